@@ -0,0 +1,34 @@
+package pgx
+
+import (
+	"errors"
+	"io"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// ErrMD5Disabled is returned when the server requests md5 password authentication and ConnConfig.DisableMD5 is set.
+var ErrMD5Disabled = errors.New("server requested md5 password authentication which is disabled by DisableMD5")
+
+// rejectMD5 wraps buildFrontend so the frontends it builds fail with ErrMD5Disabled when the server requests md5
+// password authentication.
+func rejectMD5(buildFrontend pgconn.BuildFrontendFunc) pgconn.BuildFrontendFunc {
+	return func(r io.Reader, w io.Writer) pgconn.Frontend {
+		return &md5RejectFrontend{Frontend: buildFrontend(r, w)}
+	}
+}
+
+// md5RejectFrontend is a pgconn.Frontend that fails the connection when the server requests md5 password
+// authentication, before pgconn computes the md5 digest of the password.
+type md5RejectFrontend struct {
+	pgconn.Frontend
+}
+
+func (f *md5RejectFrontend) Receive() (pgproto3.BackendMessage, error) {
+	msg, err := f.Frontend.Receive()
+	if _, ok := msg.(*pgproto3.AuthenticationMD5Password); ok && err == nil {
+		return nil, ErrMD5Disabled
+	}
+	return msg, err
+}
@@ -0,0 +1,223 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgconn"
+)
+
+// maxQueryParams is the maximum number of parameters PostgreSQL allows in a single statement.
+const maxQueryParams = 65535
+
+// SplitOptions controls how InsertRows and ExecBatch split work into multiple statements or batches.
+type SplitOptions struct {
+	// MaxParams is the maximum number of parameters in a single statement. It defaults to and may not exceed 65535,
+	// the maximum allowed by PostgreSQL.
+	MaxParams int
+
+	// MaxPayloadBytes is the approximate maximum size of the SQL and arguments sent at once. Sizes of values other than
+	// strings and byte slices are estimated. 0 means no limit.
+	MaxPayloadBytes int
+
+	// Atomic requires all statements to be executed in a single transaction when the work is split. If the connection
+	// is not already in a transaction one is started and committed when all statements succeed. Without Atomic each
+	// statement or batch commits independently and work completed before an error is not rolled back.
+	Atomic bool
+}
+
+func (so *SplitOptions) maxParams() int {
+	if so == nil || so.MaxParams <= 0 || so.MaxParams > maxQueryParams {
+		return maxQueryParams
+	}
+	return so.MaxParams
+}
+
+func (so *SplitOptions) maxPayloadBytes() int {
+	if so == nil {
+		return 0
+	}
+	return so.MaxPayloadBytes
+}
+
+// estimateArgSize estimates the number of bytes sent for arg.
+func estimateArgSize(arg interface{}) int {
+	const lengthPrefix = 4
+	switch arg := arg.(type) {
+	case nil:
+		return lengthPrefix
+	case string:
+		return lengthPrefix + len(arg)
+	case []byte:
+		return lengthPrefix + len(arg)
+	default:
+		return lengthPrefix + 8
+	}
+}
+
+func estimateArgsSize(args []interface{}) int {
+	n := 0
+	for _, arg := range args {
+		n += estimateArgSize(arg)
+	}
+	return n
+}
+
+// splitQuerier is implemented by *Conn and Tx.
+type splitQuerier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *Batch) BatchResults
+}
+
+// runSplit calls f with c or a transaction on c when atomic work is split into multiple parts.
+func (c *Conn) runSplit(ctx context.Context, split bool, opts *SplitOptions, f func(q splitQuerier) error) error {
+	if !split || opts == nil || !opts.Atomic || c.pgConn.TxStatus() != 'I' {
+		return f(c)
+	}
+
+	return c.BeginFunc(ctx, func(tx Tx) error {
+		return f(tx)
+	})
+}
+
+// InsertRows inserts rows into tableName with multi-row insert statements. Rows are split into as many statements as
+// needed to stay within the parameter limit and payload size of opts. opts may be nil to use the defaults. It returns
+// the number of rows inserted.
+func (c *Conn) InsertRows(ctx context.Context, tableName Identifier, columnNames []string, rows [][]interface{}, opts *SplitOptions) (int64, error) {
+	if len(columnNames) == 0 {
+		return 0, fmt.Errorf("no columns")
+	}
+	if len(columnNames) > opts.maxParams() {
+		return 0, fmt.Errorf("%d columns exceeds the maximum of %d parameters", len(columnNames), opts.maxParams())
+	}
+
+	for i, row := range rows {
+		if len(row) != len(columnNames) {
+			return 0, fmt.Errorf("row %d: expected %d values, got %d values", i, len(columnNames), len(row))
+		}
+	}
+
+	quotedColumnNames := make([]string, len(columnNames))
+	for i, cn := range columnNames {
+		quotedColumnNames[i] = quoteIdentifier(cn)
+	}
+	prefix := "insert into " + tableName.Sanitize() + " (" + strings.Join(quotedColumnNames, ", ") + ") values "
+
+	// Split rows into chunks that each fit in one statement.
+	maxRows := opts.maxParams() / len(columnNames)
+	maxPayload := opts.maxPayloadBytes()
+	var chunks [][][]interface{}
+	for start := 0; start < len(rows); {
+		end := start
+		payload := len(prefix)
+		for end < len(rows) && end-start < maxRows {
+			rowSize := estimateArgsSize(rows[end]) + len(columnNames)*6 // allow for placeholders such as ", $123"
+			if maxPayload > 0 && end > start && payload+rowSize > maxPayload {
+				break
+			}
+			payload += rowSize
+			end++
+		}
+		chunks = append(chunks, rows[start:end])
+		start = end
+	}
+
+	var inserted int64
+	err := c.runSplit(ctx, len(chunks) > 1, opts, func(q splitQuerier) error {
+		for _, chunk := range chunks {
+			sql, args := buildInsertRows(prefix, len(columnNames), chunk)
+			commandTag, err := q.Exec(ctx, sql, args...)
+			if err != nil {
+				return err
+			}
+			inserted += commandTag.RowsAffected()
+		}
+		return nil
+	})
+	if err != nil && opts != nil && opts.Atomic && len(chunks) > 1 {
+		inserted = 0
+	}
+
+	return inserted, err
+}
+
+func buildInsertRows(prefix string, columnCount int, rows [][]interface{}) (string, []interface{}) {
+	sb := &strings.Builder{}
+	sb.WriteString(prefix)
+	args := make([]interface{}, 0, len(rows)*columnCount)
+	for i, row := range rows {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteByte('(')
+		for j, value := range row {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			args = append(args, value)
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(len(args)))
+		}
+		sb.WriteByte(')')
+	}
+	return sb.String(), args
+}
+
+// ExecBatch executes all queries in b as if each was sent with Exec and returns their command tags. b is split into as
+// many batches as needed to stay within the payload size of opts. This avoids the large memory use and stalls that
+// can occur when a very large batch is sent at once. opts may be nil to send b as a single batch.
+//
+// A single batch is executed in an implicit transaction. When b is split, the batches only execute in a single
+// transaction if opts.Atomic is set. If an error occurs the command tags of the batches that completed and were not
+// rolled back are returned with the error. No tags are returned for any query in the batch that failed.
+func (c *Conn) ExecBatch(ctx context.Context, b *Batch, opts *SplitOptions) ([]pgconn.CommandTag, error) {
+	maxPayload := opts.maxPayloadBytes()
+
+	var batches []*Batch
+	current := &Batch{}
+	payload := 0
+	for _, bi := range b.items {
+		size := len(bi.query) + estimateArgsSize(bi.arguments)
+		if maxPayload > 0 && current.Len() > 0 && payload+size > maxPayload {
+			batches = append(batches, current)
+			current = &Batch{}
+			payload = 0
+		}
+		current.items = append(current.items, bi)
+		payload += size
+	}
+	if current.Len() > 0 {
+		batches = append(batches, current)
+	}
+
+	commandTags := make([]pgconn.CommandTag, 0, b.Len())
+	err := c.runSplit(ctx, len(batches) > 1, opts, func(q splitQuerier) error {
+		for _, batch := range batches {
+			// An error rolls back the whole batch so the tags of its earlier queries must be discarded.
+			batchStart := len(commandTags)
+			br := q.SendBatch(ctx, batch)
+			for range batch.items {
+				commandTag, err := br.Exec()
+				if err != nil {
+					br.Close()
+					commandTags = commandTags[:batchStart]
+					return err
+				}
+				commandTags = append(commandTags, commandTag)
+			}
+			err := br.Close()
+			if err != nil {
+				commandTags = commandTags[:batchStart]
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && opts != nil && opts.Atomic && len(batches) > 1 {
+		commandTags = nil
+	}
+
+	return commandTags, err
+}
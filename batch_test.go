@@ -3,6 +3,7 @@ package pgx_test
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/jackc/pgconn"
@@ -832,3 +833,99 @@ func TestSendBatchSimpleProtocol(t *testing.T) {
 	assert.EqualValues(t, 3, values[0])
 	assert.False(t, rows.Next())
 }
+
+func TestConnInsertRows(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(a int4 not null, b text)`)
+
+	rows := make([][]interface{}, 25)
+	for i := range rows {
+		rows[i] = []interface{}{int32(i), "x"}
+	}
+
+	// 10 parameters allows 5 rows per statement.
+	n, err := conn.InsertRows(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b"}, rows, &pgx.SplitOptions{MaxParams: 10})
+	require.NoError(t, err)
+	assert.EqualValues(t, 25, n)
+
+	var count int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&count)
+	require.NoError(t, err)
+	assert.EqualValues(t, 25, count)
+
+	mustExec(t, conn, "truncate foo")
+	rows[12][0] = nil
+
+	n, err = conn.InsertRows(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b"}, rows, &pgx.SplitOptions{MaxParams: 10, Atomic: true})
+	require.Error(t, err)
+	assert.EqualValues(t, 0, n)
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&count)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+
+	n, err = conn.InsertRows(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b"}, rows, &pgx.SplitOptions{MaxParams: 10})
+	require.Error(t, err)
+	assert.EqualValues(t, 10, n)
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&count)
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, count)
+
+	_, err = conn.InsertRows(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b"}, [][]interface{}{{int32(1)}}, nil)
+	require.EqualError(t, err, "row 0: expected 2 values, got 1 values")
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnExecBatchSplits(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(a int4 not null, b text)`)
+
+	batch := &pgx.Batch{}
+	for i := 0; i < 20; i++ {
+		batch.Queue("insert into foo(a, b) values($1, $2)", int32(i), strings.Repeat("x", 100))
+	}
+
+	commandTags, err := conn.ExecBatch(context.Background(), batch, &pgx.SplitOptions{MaxPayloadBytes: 1000})
+	require.NoError(t, err)
+	require.Len(t, commandTags, 20)
+	for _, ct := range commandTags {
+		assert.EqualValues(t, 1, ct.RowsAffected())
+	}
+
+	batch = &pgx.Batch{}
+	for i := 0; i < 20; i++ {
+		var a interface{} = int32(i)
+		if i == 15 {
+			a = nil
+		}
+		batch.Queue("insert into foo(a, b) values($1, $2)", a, strings.Repeat("x", 100))
+	}
+
+	commandTags, err = conn.ExecBatch(context.Background(), batch, &pgx.SplitOptions{MaxPayloadBytes: 1000, Atomic: true})
+	require.Error(t, err)
+	assert.Nil(t, commandTags)
+
+	var count int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&count)
+	require.NoError(t, err)
+	assert.EqualValues(t, 20, count)
+
+	// Without Atomic only the tags of the batches before the failed one are returned.
+	commandTags, err = conn.ExecBatch(context.Background(), batch, &pgx.SplitOptions{MaxPayloadBytes: 1000})
+	require.Error(t, err)
+
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&count)
+	require.NoError(t, err)
+	assert.EqualValues(t, 20+len(commandTags), count)
+	assert.Less(t, len(commandTags), 15)
+
+	ensureConnValid(t, conn)
+}
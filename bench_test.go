@@ -113,6 +113,35 @@ func BenchmarkMinimalPreparedSelect(b *testing.B) {
 	}
 }
 
+func BenchmarkMinimalPreparedSelectMultipleColumns(b *testing.B) {
+	conn := mustConnect(b, mustParseConfig(b, os.Getenv("PGX_TEST_DATABASE")))
+	defer closeConn(b, conn)
+
+	_, err := conn.Prepare(context.Background(), "ps1", "select $1::int8, 'foo'::text, 1.5::float8, true, now()")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var n int64
+	var s string
+	var f float64
+	var t bool
+	var ts time.Time
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = conn.QueryRow(context.Background(), "ps1", i).Scan(&n, &s, &f, &t, &ts)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if n != int64(i) {
+			b.Fatalf("expected %d, got %d", i, n)
+		}
+	}
+}
+
 func BenchmarkMinimalPgConnPreparedSelect(b *testing.B) {
 	conn := mustConnect(b, mustParseConfig(b, os.Getenv("PGX_TEST_DATABASE")))
 	defer closeConn(b, conn)
@@ -1012,6 +1041,47 @@ func BenchmarkSelectRowsScanSimple(b *testing.B) {
 	}
 }
 
+type BenchRowWide struct {
+	IDs    [4]int
+	Counts [4]int64
+	Values [4]float64
+	Labels [4]string
+}
+
+func BenchmarkSelectRowsScanWide(b *testing.B) {
+	conn := mustConnectString(b, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(b, conn)
+
+	rowCounts := getSelectRowsCounts(b)
+
+	for _, rowCount := range rowCounts {
+		b.Run(fmt.Sprintf("%d rows", rowCount), func(b *testing.B) {
+			br := &BenchRowWide{}
+			for i := 0; i < b.N; i++ {
+				rows, err := conn.Query(context.Background(), `select n, n + 1, n + 2, n + 3,
+	n::int8 * 1000, n::int8 * 2000, n::int8 * 3000, n::int8 * 4000,
+	n * 1.5::float8, n * 2.5::float8, n * 3.5::float4, n * 4.5::float4,
+	'label ' || n, 'a', 'bb', 'ccc'
+from generate_series(100001, 100000 + $1) n`, rowCount)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				for rows.Next() {
+					rows.Scan(&br.IDs[0], &br.IDs[1], &br.IDs[2], &br.IDs[3],
+						&br.Counts[0], &br.Counts[1], &br.Counts[2], &br.Counts[3],
+						&br.Values[0], &br.Values[1], &br.Values[2], &br.Values[3],
+						&br.Labels[0], &br.Labels[1], &br.Labels[2], &br.Labels[3])
+				}
+
+				if rows.Err() != nil {
+					b.Fatal(rows.Err())
+				}
+			}
+		})
+	}
+}
+
 type BenchRowStringBytes struct {
 	ID         int32
 	FirstName  []byte
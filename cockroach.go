@@ -0,0 +1,112 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultTxRetryMaxAttempts = 10
+	defaultTxRetryBackoff     = 10 * time.Millisecond
+	maxTxRetryBackoff         = time.Second
+)
+
+// ErrNotSupportedByCockroachDB is returned by features that CockroachDB does not support such as WaitForNotification
+// and large objects when the connection is to CockroachDB.
+var ErrNotSupportedByCockroachDB = errors.New("not supported by CockroachDB")
+
+// IsCockroachDB reports whether c is connected to CockroachDB. This is true when ConnConfig.CockroachDB is set or when
+// the server reports a crdb_version parameter.
+func (c *Conn) IsCockroachDB() bool {
+	return c.config.CockroachDB || c.pgConn.ParameterStatus("crdb_version") != ""
+}
+
+// RetryTxFunc starts a transaction with txOptions and calls f like BeginTxFunc, but retries f when the transaction
+// fails with a serialization_failure (40001) error. f may be called more than once so it must not have side effects
+// outside the transaction. Retries continue until f succeeds, fails with a different error, ctx is done, or f has been
+// called ConnConfig.TxRetryMaxAttempts times. The last serialization failure is returned when no attempt succeeds.
+// Each retry waits a random duration between half and all of ConnConfig.TxRetryBackoff, doubled for each further retry
+// up to one second, so that conflicting transactions do not retry in lockstep.
+//
+// With CockroachDB the retry savepoint protocol is used. f is run after SAVEPOINT cockroach_restart and is retried
+// after ROLLBACK TO SAVEPOINT cockroach_restart in the same transaction. This lets CockroachDB give the transaction
+// priority on each retry. With PostgreSQL the transaction is rolled back and a new transaction is started for each
+// retry.
+//
+// If c is already in a transaction, the retry restarts only the pseudo nested transaction f runs in. A serialization
+// failure aborts the outer transaction in PostgreSQL so in most cases RetryTxFunc should not be called in a transaction.
+func (c *Conn) RetryTxFunc(ctx context.Context, txOptions TxOptions, f func(Tx) error) error {
+	if c.IsCockroachDB() && c.pgConn.TxStatus() == 'I' {
+		return c.BeginTxFunc(ctx, txOptions, func(tx Tx) error {
+			return c.cockroachRetry(ctx, tx, f)
+		})
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := c.BeginTxFunc(ctx, txOptions, f)
+		if !c.shouldRetryTx(ctx, attempt, err) {
+			return err
+		}
+	}
+}
+
+// cockroachRetry calls f in tx using the CockroachDB retry savepoint protocol.
+func (c *Conn) cockroachRetry(ctx context.Context, tx Tx, f func(Tx) error) error {
+	_, err := tx.Exec(ctx, "savepoint cockroach_restart")
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = f(tx)
+		if err == nil {
+			_, err = tx.Exec(ctx, "release savepoint cockroach_restart")
+			if err == nil {
+				return nil
+			}
+		}
+		if !c.shouldRetryTx(ctx, attempt, err) {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "rollback to savepoint cockroach_restart")
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// shouldRetryTx reports whether RetryTxFunc should make another attempt after attempt failed with err. If so it waits
+// out the backoff first. It returns false if ctx is done before the backoff ends.
+func (c *Conn) shouldRetryTx(ctx context.Context, attempt int, err error) bool {
+	maxAttempts := c.config.TxRetryMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultTxRetryMaxAttempts
+	}
+	if !IsSerializationFailure(err) || ctx.Err() != nil || attempt >= maxAttempts {
+		return false
+	}
+
+	d := c.config.TxRetryBackoff
+	if d <= 0 {
+		d = defaultTxRetryBackoff
+	}
+	for i := 1; i < attempt && d < maxTxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxTxRetryBackoff {
+		d = maxTxRetryBackoff
+	}
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
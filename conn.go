@@ -2,9 +2,14 @@ package pgx
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/nappspt/schemapgx/v4/sanitize"
+	"net"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -25,7 +30,8 @@ type ConnConfig struct {
 	connString string
 
 	// BuildStatementCache creates the stmtcache.Cache implementation for connections created with this config. Set
-	// to nil to disable automatic prepared statements.
+	// to nil to disable automatic prepared statements. ParseConfig uses NewStatementCache which names statements with
+	// StatementCacheName.
 	BuildStatementCache BuildStatementCacheFunc
 
 	// PreferSimpleProtocol disables implicit prepared statement usage. By default pgx automatically uses the extended
@@ -34,14 +40,222 @@ type ConnConfig struct {
 	// and may be incompatible proxies such as PGBouncer. Setting PreferSimpleProtocol causes the simple protocol to be
 	// used by default. The same functionality can be controlled on a per query basis by setting
 	// QueryExOptions.SimpleProtocol.
+	//
+	// Deprecated: The simple protocol interpolates arguments into the SQL text on the client. PreferExecParams also
+	// uses a single round trip without prepared statements but sends arguments separately from the SQL.
 	PreferSimpleProtocol bool
 
+	// PreferExecParams causes queries that are not executed by a prepared statement name to be sent with the unnamed
+	// statement in a single round trip instead of being prepared first. Arguments are sent separately from the SQL in
+	// the text format with an unspecified type so the server infers their types as it would for literals. Results are
+	// received in the text format unless QueryResultFormats is used. It takes precedence over PreferSimpleProtocol. The
+	// same functionality can be controlled on a per query basis with QueryExecParams.
+	PreferExecParams bool
+
+	// StrictQueryRow causes Row.Scan to return a *NotSingleRowError when the query returns more than one row instead of
+	// using the first row.
+	StrictQueryRow bool
+
+	// StrictUnknownTypes causes Scan to return an *UnknownTypeError when a column whose type is not registered in the
+	// ConnInfo is scanned into a string or []byte. Without it the value is returned as the text or raw bytes sent by the
+	// server. This detects columns whose type has changed to one pgx does not know, such as an extension type, instead
+	// of passing through data the application may not expect.
+	StrictUnknownTypes bool
+
+	// StrictTimePrecision causes a time.Time argument to be rejected when its parameter type cannot hold all of it
+	// instead of being truncated: anything finer than a microsecond, and the time of day for date. With the simple
+	// protocol the parameter type is not known so only sub-microsecond precision is rejected.
+	StrictTimePrecision bool
+
+	// MaxResultBytes limits the total size of the column values a single query may return. When a row would take the
+	// query past the limit the query fails with a *ResultSizeError and the remaining rows are discarded as they are
+	// read. This keeps a mistaken query such as a select * on a table of large values from exhausting memory when the
+	// rows are collected. It can be overridden per query with QueryMaxResultBytes. 0 means no limit.
+	MaxResultBytes int64
+
+	// ScanConversions are the conversions Scan performs beyond those of pgtype when a column and its destination have
+	// different types, such as int4 into a float64. The zero value performs none. See ScanConversionPolicy.
+	ScanConversions ScanConversionPolicy
+
+	// SQLCommentTags, if set, is called for every query and the returned tags are appended to the SQL as an sqlcommenter
+	// style comment (e.g. /*traceparent='...'*/). This allows correlating entries in pg_stat_activity and the server
+	// logs with application traces. Comments are only added where the SQL text is sent for each execution: queries using
+	// the simple protocol, statement_cache_mode=describe, or an unnamed statement. Queries executed by a named prepared
+	// statement are sent by name and are never commented. Queries that already contain a comment are not modified.
+	SQLCommentTags SQLCommentTagsFunc
+
+	// MaxBufferedNotifications is the maximum number of notifications buffered while waiting to be read by
+	// WaitForNotification. When the buffer is full NotificationOverflowPolicy determines which notification is dropped.
+	// Set to 0 for an unlimited buffer.
+	MaxBufferedNotifications int
+
+	// NotificationOverflowPolicy determines what happens when a notification is received and the buffer is full. The
+	// default is NotificationOverflowDropOldest.
+	NotificationOverflowPolicy NotificationOverflowPolicy
+
+	// OnNotificationDropped, if set, is called with every notification dropped because the buffer was full.
+	OnNotificationDropped func(*Conn, *pgconn.Notification)
+
+	// OnConnect, if set, is called after a connection is established and initialized.
+	OnConnect func(*Conn)
+
+	// OnClose, if set, is called after a connection is closed by Close.
+	OnClose func(*Conn)
+
+	// OnDeath, if set, is called when a connection is closed by anything other than Close, such as a network error, a
+	// canceled query, or a failed rollback. err is the first error that broke the connection. OnDeath is called from a
+	// separate goroutine so it must not use the connection.
+	OnDeath func(c *Conn, err error)
+
+	// ContextLogFields, if set, is called with the context of every log message and query stat. The returned fields
+	// are added to the log data and to QueryStat.Fields. This can be used to include values the application stores in
+	// the context such as a request ID or user ID. Fields set by pgx are not overwritten.
+	ContextLogFields func(ctx context.Context) map[string]interface{}
+
+	// Stats, if set, counts driver level events such as connections opened and bytes read. It may be shared between
+	// connections.
+	Stats *DriverStats
+
+	// OnQueryStat, if set, is called after every Query, QueryRow, Exec, and CopyFrom with its duration, row count, and
+	// error. It is called synchronously so it should be fast. It is intended for recording metrics such as query
+	// duration histograms.
+	OnQueryStat func(QueryStat)
+
+	// VerifyPeerCertificate, if set, is called during the TLS handshake of every connection attempt after any
+	// verification required by sslmode. It can be used to implement custom certificate verification such as checking a
+	// SPIFFE ID or an internal PKI. See tls.Config.VerifyPeerCertificate. Verified chains are only available when sslmode
+	// is verify-full.
+	VerifyPeerCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
+
+	// GetTLSConfig, if set, is called before every connection attempt. The returned TLS configuration replaces the
+	// TLSConfig of the primary host and of each fallback that uses TLS. sslmode still determines whether TLS is used, but
+	// verification is entirely determined by the returned configuration. The ServerName is set to the host if it is
+	// empty. This allows short-lived client certificates to be picked up by new connections without rebuilding the
+	// ConnConfig.
+	GetTLSConfig func() (*tls.Config, error)
+
+	// GetPassword, if set, is called before every connection attempt. The returned password replaces Password for that
+	// attempt. It is passed a copy of the config that will be used to connect. This allows credentials that expire
+	// such as AWS RDS IAM authentication tokens or OAuth access tokens to be generated for each new connection. See the
+	// rdsiam package. The same password is used for the primary host and all fallbacks. There is no limit on the length
+	// of the password.
+	//
+	// If the server rejects the password with invalid_password (28P01), GetPassword is called once more and the
+	// connection is retried. IsPasswordRefresh reports true for the context of that call so a provider that caches
+	// tokens can fetch a new one.
+	GetPassword func(ctx context.Context, config *ConnConfig) (string, error)
+
+	// PromptPassword, if set, is called when the server requests a password and none is configured, neither in the
+	// connection string, a password file, nor by GetPassword. Without it an empty password is sent and authentication
+	// fails. It is intended for interactive tools to ask the user for the password. It is called at most once per
+	// connect, including when several hosts are tried, and not at all if the server does not require a password. It is
+	// passed a copy of the config. An error returned by it fails the connect.
+	PromptPassword func(ctx context.Context, config *ConnConfig) (string, error)
+
+	// DisableMD5 fails a connection attempt with ErrMD5Disabled if the server requests md5 password authentication
+	// instead of sending the md5 digest of the password. This is required in FIPS-constrained environments where md5 is
+	// not an approved algorithm and ensures the password is never sent with the weaker md5 method when the server is
+	// expected to use SCRAM. Other hosts are still tried. It can be set in the connection string with disable_md5=true.
+	DisableMD5 bool
+
+	// NewGSS, if set, enables GSSAPI and SSPI authentication. This allows Kerberos and Windows integrated (Active
+	// Directory) authentication without a stored password. It is called to create a security context each time the
+	// server requests either. pgx does not include an implementation as it depends on the Kerberos libraries or the
+	// Windows SSPI of the platform. An adapter over a package providing them, such as the negotiate package of
+	// github.com/alexbrainman/sspi on Windows, implements GSS.
+	NewGSS func() (GSS, error)
+
+	// KerberosServiceName is the Kerberos service name of the server. The service principal name passed to GSS is
+	// "<service>/<host>". The default is "postgres". It can be set in the connection string with krbsrvname.
+	KerberosServiceName string
+
+	// KerberosSPN, if set, is the service principal name passed to GSS instead of one built from KerberosServiceName and
+	// the host. It can be set in the connection string with krbspn.
+	KerberosSPN string
+
+	// UnixSocketName, if set, is the file name of the unix domain socket in the socket directory given as the host
+	// instead of .s.PGSQL.<port>. It can be set in the connection string with unix_socket_name. A host that begins with
+	// @ is a socket in the abstract namespace of Linux such as one created by unix_socket_directories = '@pg'.
+	UnixSocketName string
+
+	// RequirePeer, if set, is the operating system user name the server process must run as when connecting with a unix
+	// domain socket. The connection fails if the server runs as any other user. This prevents a process of another user
+	// that has taken over the socket path from receiving the credentials. It is only supported on Linux. It can be set
+	// in the connection string with requirepeer.
+	RequirePeer string
+
+	// Dial, if set, is used to open connections instead of DialFunc. It is passed the host from the config that the
+	// address was resolved from, the database, and the config in addition to the address. This allows connectors for
+	// managed databases and proxies to be used per connection. Set LookupFunc to SkipLookup if the dialer resolves hosts
+	// itself so Addr contains the host instead of an IP address. Dial is also used to send cancel requests.
+	Dial func(ctx context.Context, info DialInfo) (net.Conn, error)
+
+	// MessageReadTimeout is the maximum time to wait for data from the server while a message is expected. If it is
+	// exceeded the connection is closed and a *MessageReadTimeoutError is returned. This detects a server or network
+	// that stops responding in the middle of a query much sooner than TCP would. It must be longer than the longest
+	// expected gap between messages, which includes the time a query runs before returning its first row. It does not
+	// apply while waiting with WaitForNotification or while copying data with CopyFrom. Set to 0 to disable.
+	MessageReadTimeout time.Duration
+
+	// StartupTimeout is the maximum time for the startup of each connection attempt once the server has been dialed. It
+	// covers the TLS handshake, authentication, and waiting for the server to be ready for queries, so a server that
+	// accepts connections but never completes startup does not block the caller even when connect_timeout is not set or
+	// the dialer has its own timeout. Unlike connect_timeout it applies to each address separately so the next host is
+	// still tried. It can be set in the connection string with startup_timeout. Set to 0 to disable.
+	StartupTimeout time.Duration
+
+	// MaxPreparedStatements is the maximum number of statements created with Prepare that are kept prepared on the
+	// server. When the limit is exceeded the least recently used statement is closed. It is prepared again
+	// transparently the next time it is used. Set to 0 for no limit. Automatically prepared statements are limited
+	// separately by statement_cache_capacity.
+	MaxPreparedStatements int
+
+	// PassThroughTypes is a list of type names that are registered at connect time to be sent and received in the
+	// binary format as raw bytes. This allows types from extensions that pgx does not understand to be used with []byte
+	// values. For example, with "geometry" and "geography" PostGIS values can be bound and scanned as EWKB. Names are
+	// resolved with the search_path of the connection and may be schema-qualified. Names that do not exist in the
+	// database are ignored so the same config can be used with databases where the extension is not installed.
+	PassThroughTypes []string
+
+	// CockroachDB enables CockroachDB compatibility. Features CockroachDB does not support return
+	// ErrNotSupportedByCockroachDB instead of failing on the server and RetryTxFunc uses the CockroachDB retry savepoint
+	// protocol. It is enabled automatically when the server reports a crdb_version parameter. It can be set in the
+	// connection string with cockroachdb=true.
+	CockroachDB bool
+
+	// TxRetryMaxAttempts is the maximum number of times RetryTxFunc calls its function before returning the last
+	// serialization failure. The default is 10. TxRetryBackoff is the delay before the first retry. It is doubled for
+	// each further retry up to one second and randomized so that conflicting transactions do not retry at the same
+	// moment. The default is 10ms.
+	TxRetryMaxAttempts int
+	TxRetryBackoff     time.Duration
+
+	// TextOnly restricts the connection to features supported by PostgreSQL-compatible systems such as Redshift and
+	// older versions of Greenplum that do not support the extended protocol. All queries are sent with the simple
+	// protocol regardless of QuerySimpleProtocol so all parameters and results use the text format. Prepare, CopyFrom,
+	// and CopyFromWithProgress return ErrTextOnly. It can be set in the connection string with text_only=true.
+	TextOnly bool
+
+	// RequireUTF8 requests client_encoding=UTF8 in the startup message unless client_encoding is already set in
+	// RuntimeParams and fails the connection with a *ClientEncodingError if the server reports any other client encoding.
+	// Go strings are assumed to be UTF-8 so text in other encodings would be silently corrupted. It can be set in the
+	// connection string with require_utf8=true.
+	RequireUTF8 bool
+
+	// DefaultTxIsoLevel and DefaultTxAccessMode, if set, are sent in the startup message as default_transaction_isolation
+	// and default_transaction_read_only. They apply to every transaction on the connection, including statements run
+	// outside an explicit transaction, that does not set its own. Setting DefaultTxAccessMode to ReadOnly lets a pool of
+	// replica connections reject writes on the server even if application code attempts them. The same settings can be
+	// given in the connection string as default_transaction_isolation and default_transaction_read_only. Values set in
+	// RuntimeParams take precedence.
+	DefaultTxIsoLevel   TxIsoLevel
+	DefaultTxAccessMode TxAccessMode
+
 	createdByParseConfig bool // Used to enforce created by ParseConfig rule.
 }
 
-// Copy returns a deep copy of the config that is safe to use and modify.
-// The only exception is the tls.Config:
-// according to the tls.Config docs it must not be modified after creation.
+// Copy returns a deep copy of the config that is safe to use and modify. The tls.Config of the config and of each
+// fallback is cloned.
 func (cc *ConnConfig) Copy() *ConnConfig {
 	newConfig := new(ConnConfig)
 	*newConfig = *cc
@@ -49,9 +263,68 @@ func (cc *ConnConfig) Copy() *ConnConfig {
 	return newConfig
 }
 
+// Clone returns a deep copy of the config. It is the same as Copy. It is provided for symmetry with tls.Config.Clone
+// for code that derives per-host configs.
+func (cc *ConnConfig) Clone() *ConnConfig {
+	return cc.Copy()
+}
+
 // ConnString returns the connection string as parsed by pgx.ParseConfig into pgx.ConnConfig.
 func (cc *ConnConfig) ConnString() string { return cc.connString }
 
+// String returns a connection string in keyword/value format built from the current config with the password masked.
+// It is intended for logs and error messages.
+func (cc *ConnConfig) String() string {
+	// Fallbacks have an entry for each TLS mode tried on a host so the same host may appear more than once.
+	var hosts, ports []string
+	seen := make(map[string]struct{})
+	addHost := func(host string, port uint16) {
+		key := host + ":" + strconv.FormatUint(uint64(port), 10)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		hosts = append(hosts, host)
+		ports = append(ports, strconv.FormatUint(uint64(port), 10))
+	}
+	addHost(cc.Host, cc.Port)
+	for _, fb := range cc.Fallbacks {
+		addHost(fb.Host, fb.Port)
+	}
+
+	parts := []string{
+		"host=" + quoteConnStringValue(strings.Join(hosts, ",")),
+		"port=" + quoteConnStringValue(strings.Join(ports, ",")),
+	}
+	if cc.User != "" {
+		parts = append(parts, "user="+quoteConnStringValue(cc.User))
+	}
+	if cc.Password != "" {
+		parts = append(parts, "password=xxxxx")
+	}
+	if cc.Database != "" {
+		parts = append(parts, "dbname="+quoteConnStringValue(cc.Database))
+	}
+
+	keys := make([]string, 0, len(cc.RuntimeParams))
+	for k := range cc.RuntimeParams {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, k+"="+quoteConnStringValue(cc.RuntimeParams[k]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func quoteConnStringValue(s string) string {
+	if s != "" && !strings.ContainsAny(s, ` '\`) {
+		return s
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
 // BuildStatementCacheFunc is a function that can be used to create a stmtcache.Cache implementation for connection.
 type BuildStatementCacheFunc func(conn *pgconn.PgConn) stmtcache.Cache
 
@@ -60,12 +333,20 @@ type BuildStatementCacheFunc func(conn *pgconn.PgConn) stmtcache.Cache
 type Conn struct {
 	pgConn             *pgconn.PgConn
 	config             *ConnConfig // config used when establishing this connection
-	preparedStatements map[string]*pgconn.StatementDescription
+	preparedStatements *preparedStatementCache
 	stmtcache          stmtcache.Cache
 	logger             Logger
 	logLevel           LogLevel
 
-	notifications []*pgconn.Notification
+	readTimeoutConn *messageReadTimeoutConn
+	statsConn       *statsConn
+	lifecycle       connLifecycle
+	poolWait        *PoolWait // set by SetPoolWait and reported with the next query
+	serverParams    serverParams
+
+	notifications           []*pgconn.Notification
+	droppedNotifications    int64
+	notificationsOverflowed bool
 
 	doneChan   chan struct{}
 	closedChan chan error
@@ -74,6 +355,7 @@ type Conn struct {
 
 	wbuf             []byte
 	preallocatedRows []connRows
+	scanPlans        []pgtype.ScanPlan // reused by each Rows as only one can be active at a time
 	eqb              extendedQueryBuilder
 }
 
@@ -97,6 +379,48 @@ var ErrNoRows = errors.New("no rows in result set")
 // ErrInvalidLogLevel occurs on attempt to set an invalid log level.
 var ErrInvalidLogLevel = errors.New("invalid log level")
 
+// ErrConnBusy occurs when a query or command is started while the connection is still busy with a previous one. This
+// is usually caused by not closing the Rows of a previous query or by using a connection from multiple goroutines.
+// The previous query is not affected.
+var ErrConnBusy = errors.New("conn busy")
+
+// ErrTextOnly occurs when a feature that requires the extended protocol or the binary format is used with a connection
+// with ConnConfig.TextOnly set.
+var ErrTextOnly = errors.New("not supported with TextOnly")
+
+// ClientEncodingError occurs when ConnConfig.RequireUTF8 is set and the server reports a client_encoding other than
+// UTF8.
+type ClientEncodingError struct {
+	ClientEncoding string
+	ServerEncoding string
+}
+
+func (e *ClientEncodingError) Error() string {
+	return fmt.Sprintf("client_encoding is %s, not UTF8 (server_encoding is %s)", e.ClientEncoding, e.ServerEncoding)
+}
+
+// ArgumentCountError occurs when the number of arguments passed to a query does not match the number of parameters
+// referenced by the SQL. It is detected before the query is sent to the server.
+type ArgumentCountError struct {
+	Expected int // number of parameters referenced by the statement
+	Actual   int // number of arguments supplied
+}
+
+func (e *ArgumentCountError) Error() string {
+	if e.Actual < e.Expected {
+		return fmt.Sprintf("expected %d arguments, got %d: missing %s", e.Expected, e.Actual, placeholderRange(e.Actual+1, e.Expected))
+	}
+	return fmt.Sprintf("expected %d arguments, got %d: extra arguments for %s", e.Expected, e.Actual, placeholderRange(e.Expected+1, e.Actual))
+}
+
+// placeholderRange formats the placeholders $first through $last.
+func placeholderRange(first, last int) string {
+	if first == last {
+		return "$" + strconv.Itoa(first)
+	}
+	return "$" + strconv.Itoa(first) + " through $" + strconv.Itoa(last)
+}
+
 // Connect establishes a connection with a PostgreSQL server with a connection string. See
 // pgconn.Connect for details.
 func Connect(ctx context.Context, connString string) (*Conn, error) {
@@ -127,6 +451,40 @@ func ConnectConfig(ctx context.Context, connConfig *ConnConfig) (*Conn, error) {
 //
 //	prefer_simple_protocol
 //		Possible values: "true" and "false". Use the simple protocol instead of extended protocol. Default: false
+//
+//	prefer_exec_params
+//		Possible values: "true" and "false". Send queries with the unnamed statement in a single round trip instead of
+//		preparing them first. Default: false
+//
+//	strict_time_precision
+//		Possible values: "true" and "false". See ConnConfig.StrictTimePrecision. Default: false
+//
+//	disable_md5
+//		Possible values: "true" and "false". See ConnConfig.DisableMD5. Default: false
+//
+//	krbsrvname
+//		The Kerberos service name of the server. See ConnConfig.KerberosServiceName. Default: "postgres"
+//
+//	krbspn
+//		The service principal name of the server. See ConnConfig.KerberosSPN.
+//
+//	unix_socket_name
+//		The file name of the unix domain socket in the socket directory. See ConnConfig.UnixSocketName. Default:
+//		".s.PGSQL.<port>"
+//
+//	requirepeer
+//		The operating system user the server must run as for unix domain socket connections. See ConnConfig.RequirePeer.
+//
+//	startup_timeout
+//		A duration string such as "10s". See ConnConfig.StartupTimeout. Default: no timeout
+//
+//	gssencmode
+//		Possible values: "disable", "prefer", and "require". The PGGSSENCMODE environment variable is used if it is not
+//		set. GSSAPI encryption is not supported so "prefer" connects without it and "require" is an error. This is the
+//		same as libpq built without GSSAPI support. Default: "prefer"
+//
+// The options keyword (e.g. options='-c statement_timeout=5s -c search_path=app') is sent to the server in the startup
+// packet. The PGOPTIONS environment variable is used if it is not set.
 func ParseConfig(connString string) (*ConnConfig, error) {
 	config, err := pgconn.ParseConfig(connString)
 	if err != nil {
@@ -159,7 +517,7 @@ func ParseConfig(connString string) (*ConnConfig, error) {
 
 	if statementCacheCapacity > 0 {
 		buildStatementCache = func(conn *pgconn.PgConn) stmtcache.Cache {
-			return stmtcache.New(conn, statementCacheMode, statementCacheCapacity)
+			return NewStatementCache(conn, statementCacheMode, statementCacheCapacity)
 		}
 	}
 
@@ -173,12 +531,129 @@ func ParseConfig(connString string) (*ConnConfig, error) {
 		}
 	}
 
+	preferExecParams := false
+	if s, ok := config.RuntimeParams["prefer_exec_params"]; ok {
+		delete(config.RuntimeParams, "prefer_exec_params")
+		if b, err := strconv.ParseBool(s); err == nil {
+			preferExecParams = b
+		} else {
+			return nil, fmt.Errorf("invalid prefer_exec_params: %v", err)
+		}
+	}
+
+	cockroachDB := false
+	if s, ok := config.RuntimeParams["cockroachdb"]; ok {
+		delete(config.RuntimeParams, "cockroachdb")
+		if b, err := strconv.ParseBool(s); err == nil {
+			cockroachDB = b
+		} else {
+			return nil, fmt.Errorf("invalid cockroachdb: %v", err)
+		}
+	}
+
+	textOnly := false
+	if s, ok := config.RuntimeParams["text_only"]; ok {
+		delete(config.RuntimeParams, "text_only")
+		if b, err := strconv.ParseBool(s); err == nil {
+			textOnly = b
+		} else {
+			return nil, fmt.Errorf("invalid text_only: %v", err)
+		}
+	}
+
+	strictTimePrecision := false
+	if s, ok := config.RuntimeParams["strict_time_precision"]; ok {
+		delete(config.RuntimeParams, "strict_time_precision")
+		if b, err := strconv.ParseBool(s); err == nil {
+			strictTimePrecision = b
+		} else {
+			return nil, fmt.Errorf("invalid strict_time_precision: %v", err)
+		}
+	}
+
+	requireUTF8 := false
+	if s, ok := config.RuntimeParams["require_utf8"]; ok {
+		delete(config.RuntimeParams, "require_utf8")
+		if b, err := strconv.ParseBool(s); err == nil {
+			requireUTF8 = b
+		} else {
+			return nil, fmt.Errorf("invalid require_utf8: %v", err)
+		}
+	}
+
+	disableMD5 := false
+	if s, ok := config.RuntimeParams["disable_md5"]; ok {
+		delete(config.RuntimeParams, "disable_md5")
+		if b, err := strconv.ParseBool(s); err == nil {
+			disableMD5 = b
+		} else {
+			return nil, fmt.Errorf("invalid disable_md5: %v", err)
+		}
+	}
+
+	krbSrvName := config.RuntimeParams["krbsrvname"]
+	delete(config.RuntimeParams, "krbsrvname")
+	krbSPN := config.RuntimeParams["krbspn"]
+	delete(config.RuntimeParams, "krbspn")
+
+	unixSocketName := config.RuntimeParams["unix_socket_name"]
+	delete(config.RuntimeParams, "unix_socket_name")
+	requirePeer := config.RuntimeParams["requirepeer"]
+	delete(config.RuntimeParams, "requirepeer")
+	disableSocketTLS(config)
+
+	var startupTimeout time.Duration
+	if s, ok := config.RuntimeParams["startup_timeout"]; ok {
+		delete(config.RuntimeParams, "startup_timeout")
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid startup_timeout: %w", err)
+		}
+		startupTimeout = d
+	}
+
+	if _, ok := config.RuntimeParams["options"]; !ok {
+		if s := os.Getenv("PGOPTIONS"); s != "" {
+			config.RuntimeParams["options"] = s
+		}
+	}
+
+	setTLSServerName(config.TLSConfig, config.Host)
+	for _, fb := range config.Fallbacks {
+		setTLSServerName(fb.TLSConfig, fb.Host)
+	}
+
+	gssEncMode, ok := config.RuntimeParams["gssencmode"]
+	if ok {
+		delete(config.RuntimeParams, "gssencmode")
+	} else {
+		gssEncMode = os.Getenv("PGGSSENCMODE")
+	}
+	switch gssEncMode {
+	case "", "disable", "prefer":
+	case "require":
+		return nil, errors.New("gssencmode=require is not supported: GSSAPI encryption is not available")
+	default:
+		return nil, fmt.Errorf("invalid gssencmode: %s", gssEncMode)
+	}
+
 	connConfig := &ConnConfig{
 		Config:               *config,
 		createdByParseConfig: true,
 		LogLevel:             LogLevelInfo,
 		BuildStatementCache:  buildStatementCache,
 		PreferSimpleProtocol: preferSimpleProtocol,
+		PreferExecParams:     preferExecParams,
+		CockroachDB:          cockroachDB,
+		TextOnly:             textOnly,
+		RequireUTF8:          requireUTF8,
+		StrictTimePrecision:  strictTimePrecision,
+		DisableMD5:           disableMD5,
+		KerberosServiceName:  krbSrvName,
+		KerberosSPN:          krbSPN,
+		UnixSocketName:       unixSocketName,
+		RequirePeer:          requirePeer,
+		StartupTimeout:       startupTimeout,
 		connString:           connString,
 	}
 
@@ -205,6 +680,7 @@ func connect(ctx context.Context, config *ConnConfig) (c *Conn, err error) {
 		connInfo: pgtype.NewConnInfo(),
 		logLevel: config.LogLevel,
 		logger:   config.Logger,
+		eqb:      extendedQueryBuilder{strictTimePrecision: config.StrictTimePrecision},
 	}
 
 	// Only install pgx notification system if no other callback handler is present.
@@ -216,18 +692,169 @@ func connect(ctx context.Context, config *ConnConfig) (c *Conn, err error) {
 		}
 	}
 
+	if config.GetTLSConfig != nil {
+		tlsConfig, err := config.GetTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get TLS config: %w", err)
+		}
+		if tlsConfig == nil {
+			return nil, errors.New("GetTLSConfig returned nil TLS config")
+		}
+
+		config.Config = *config.Config.Copy()
+		config.Config.TLSConfig = replaceTLSConfig(config.Config.TLSConfig, tlsConfig, config.Config.Host)
+		for _, fb := range config.Config.Fallbacks {
+			fb.TLSConfig = replaceTLSConfig(fb.TLSConfig, tlsConfig, fb.Host)
+		}
+	}
+
+	if config.VerifyPeerCertificate != nil {
+		config.Config = *config.Config.Copy()
+		addVerifyPeerCertificate(config.Config.TLSConfig, config.VerifyPeerCertificate)
+		for _, fb := range config.Config.Fallbacks {
+			addVerifyPeerCertificate(fb.TLSConfig, config.VerifyPeerCertificate)
+		}
+	}
+
+	if config.GetPassword != nil {
+		password, err := config.GetPassword(ctx, config.Copy())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get password: %w", err)
+		}
+		config.Config.Password = password
+	}
+
+	if config.RequireUTF8 {
+		if _, ok := config.Config.RuntimeParams["client_encoding"]; !ok {
+			config.Config = *config.Config.Copy()
+			config.Config.RuntimeParams["client_encoding"] = "UTF8"
+		}
+	}
+
+	if config.DefaultTxIsoLevel != "" || config.DefaultTxAccessMode != "" {
+		config.Config = *config.Config.Copy()
+		setDefaultRuntimeParam(&config.Config, "default_transaction_isolation", string(config.DefaultTxIsoLevel))
+		switch config.DefaultTxAccessMode {
+		case ReadOnly:
+			setDefaultRuntimeParam(&config.Config, "default_transaction_read_only", "on")
+		case ReadWrite:
+			setDefaultRuntimeParam(&config.Config, "default_transaction_read_only", "off")
+		case "":
+		default:
+			return nil, fmt.Errorf("invalid DefaultTxAccessMode: %s", config.DefaultTxAccessMode)
+		}
+	}
+
+	if config.Dial != nil {
+		dh := &dialHosts{hosts: make(map[string]string)}
+		config.Config.LookupFunc = dh.lookup(config.Config.LookupFunc)
+		config.Config.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return config.Dial(ctx, dh.dialInfo(originalConfig, network, addr))
+		}
+	}
+
+	if config.RequirePeer != "" {
+		config.Config.DialFunc = requirePeerDial(config.Config.DialFunc, config.RequirePeer)
+	}
+
+	if config.Stats != nil {
+		if onNotification := config.Config.OnNotification; onNotification != nil {
+			config.Config.OnNotification = func(pgConn *pgconn.PgConn, n *pgconn.Notification) {
+				config.Stats.notificationReceived()
+				onNotification(pgConn, n)
+			}
+		}
+
+		dial := config.Config.DialFunc
+		config.Config.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			c.statsConn = &statsConn{Conn: conn, stats: config.Stats}
+			return c.statsConn, nil
+		}
+	}
+
+	if config.MessageReadTimeout > 0 {
+		dial := config.Config.DialFunc
+		config.Config.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			c.readTimeoutConn = newMessageReadTimeoutConn(conn, config.MessageReadTimeout)
+			return c.readTimeoutConn, nil
+		}
+	}
+
+	if config.OnDeath != nil {
+		dial := config.Config.DialFunc
+		config.Config.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &lifecycleConn{Conn: conn, lifecycle: &c.lifecycle}, nil
+		}
+	}
+
+	if config.Config.BuildFrontend != nil {
+		if config.DisableMD5 {
+			config.Config.BuildFrontend = rejectMD5(config.Config.BuildFrontend)
+		}
+		config.Config.BuildFrontend = c.serverParams.buildFrontend(config.Config.BuildFrontend)
+	}
+
+	opts := connectOptions{startupTimeout: config.StartupTimeout, socketName: config.UnixSocketName}
+	if config.PromptPassword != nil {
+		opts.prompt = &passwordPrompt{prompt: func(ctx context.Context) (string, error) {
+			return config.PromptPassword(ctx, config.Copy())
+		}}
+	}
+	if config.NewGSS != nil {
+		opts.newGSS = config.NewGSS
+		opts.gssSPN = func(host string) string { return gssSPN(config, host) }
+	}
+
 	if c.shouldLog(LogLevelInfo) {
 		c.log(ctx, LogLevelInfo, "Dialing PostgreSQL server", map[string]interface{}{"host": config.Config.Host})
 	}
-	c.pgConn, err = pgconn.ConnectConfig(ctx, &config.Config)
+	c.pgConn, err = connectAttempts(ctx, &config.Config, opts)
+	if err != nil && config.GetPassword != nil && pgErrorCode(err) == "28P01" {
+		config.Stats.authFailed()
+		if c.shouldLog(LogLevelInfo) {
+			c.log(ctx, LogLevelInfo, "password rejected, refreshing password", map[string]interface{}{"err": err})
+		}
+
+		password, passwordErr := config.GetPassword(context.WithValue(ctx, passwordRefreshKey{}, true), config.Copy())
+		if passwordErr != nil {
+			return nil, fmt.Errorf("failed to refresh password: %w", passwordErr)
+		}
+		config.Config.Password = password
+		c.pgConn, err = connectAttempts(ctx, &config.Config, opts)
+	}
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "28") {
+			config.Stats.authFailed()
+		}
 		if c.shouldLog(LogLevelError) {
 			c.log(ctx, LogLevelError, "connect failed", map[string]interface{}{"err": err})
 		}
 		return nil, err
 	}
+	if c.statsConn != nil {
+		c.statsConn.markEstablished()
+	}
+
+	if config.RequireUTF8 && c.ClientEncoding() != "UTF8" {
+		err = &ClientEncodingError{ClientEncoding: c.ClientEncoding(), ServerEncoding: c.ServerEncoding()}
+		c.pgConn.Close(ctx)
+		return nil, err
+	}
 
-	c.preparedStatements = make(map[string]*pgconn.StatementDescription)
+	c.preparedStatements = newPreparedStatementCache()
 	c.doneChan = make(chan struct{})
 	c.closedChan = make(chan error)
 	c.wbuf = make([]byte, 0, 1024)
@@ -239,12 +866,118 @@ func connect(ctx context.Context, config *ConnConfig) (c *Conn, err error) {
 	// Replication connections can't execute the queries to
 	// populate the c.PgTypes and c.pgsqlAfInet
 	if _, ok := config.Config.RuntimeParams["replication"]; ok {
+		c.established()
 		return c, nil
 	}
 
+	if len(config.PassThroughTypes) > 0 {
+		err = c.registerPassThroughTypes(ctx, config.PassThroughTypes)
+		if err != nil {
+			c.pgConn.Close(ctx)
+			return nil, err
+		}
+	}
+
+	c.established()
 	return c, nil
 }
 
+// established fires OnConnect and starts watching for OnDeath once connect has succeeded.
+func (c *Conn) established() {
+	if c.config.OnDeath != nil {
+		// Errors from failed attempts to connect to other hosts are not the cause of a later death.
+		c.lifecycle.mux.Lock()
+		c.lifecycle.cause = nil
+		c.lifecycle.mux.Unlock()
+		go c.watchDeath()
+	}
+	if c.config.OnConnect != nil {
+		c.config.OnConnect(c)
+	}
+}
+
+// setDefaultRuntimeParam sets the run-time parameter name to value unless value is empty or the parameter is already
+// set.
+func setDefaultRuntimeParam(config *pgconn.Config, name, value string) {
+	if value == "" {
+		return
+	}
+	if config.RuntimeParams == nil {
+		config.RuntimeParams = make(map[string]string)
+	}
+	if _, ok := config.RuntimeParams[name]; !ok {
+		config.RuntimeParams[name] = value
+	}
+}
+
+// registerPassThroughTypes registers each of names that exists in the database as pgtype.GenericBinary.
+func (c *Conn) registerPassThroughTypes(ctx context.Context, names []string) error {
+	nameOIDs, err := connInfoFromRows(c.Query(ctx,
+		"select to_regtype(name)::oid, name from unnest($1::text[]) name where to_regtype(name) is not null",
+		names,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to load pass-through types: %w", err)
+	}
+
+	for name, oid := range nameOIDs {
+		c.connInfo.RegisterDataType(pgtype.DataType{Value: &pgtype.GenericBinary{}, Name: name, OID: oid})
+	}
+
+	return nil
+}
+
+type passwordRefreshKey struct{}
+
+// IsPasswordRefresh reports whether ConnConfig.GetPassword is being called with ctx because the server rejected the
+// previous password.
+func IsPasswordRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(passwordRefreshKey{}).(bool)
+	return refresh
+}
+
+// replaceTLSConfig returns a copy of tlsConfig for host if current is not nil. It returns nil if current is nil as TLS
+// is not used for that connection attempt.
+func replaceTLSConfig(current, tlsConfig *tls.Config, host string) *tls.Config {
+	if current == nil {
+		return nil
+	}
+
+	replacement := tlsConfig.Clone()
+	setTLSServerName(replacement, host)
+	return replacement
+}
+
+// addVerifyPeerCertificate adds verify to the verification performed by tlsConfig.
+func addVerifyPeerCertificate(tlsConfig *tls.Config, verify func([][]byte, [][]*x509.Certificate) error) {
+	if tlsConfig == nil {
+		return
+	}
+
+	sslmodeVerify := tlsConfig.VerifyPeerCertificate
+	if sslmodeVerify == nil {
+		tlsConfig.VerifyPeerCertificate = verify
+		return
+	}
+
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		err := sslmodeVerify(rawCerts, verifiedChains)
+		if err != nil {
+			return err
+		}
+		return verify(rawCerts, verifiedChains)
+	}
+}
+
+// setTLSServerName sets the ServerName of tlsConfig to host so the server name indication is sent for every sslmode
+// and not only for verify-full. It is not set for unix domain sockets.
+func setTLSServerName(tlsConfig *tls.Config, host string) {
+	if tlsConfig == nil || tlsConfig.ServerName != "" || strings.HasPrefix(host, "/") {
+		return
+	}
+	tlsConfig.ServerName = host
+}
+
 // Close closes a connection. It is safe to call Close on a already closed
 // connection.
 func (c *Conn) Close(ctx context.Context) error {
@@ -252,10 +985,14 @@ func (c *Conn) Close(ctx context.Context) error {
 		return nil
 	}
 
+	c.lifecycle.startClosing()
 	err := c.pgConn.Close(ctx)
 	if c.shouldLog(LogLevelInfo) {
 		c.log(ctx, LogLevelInfo, "closed connection", nil)
 	}
+	if c.config.OnClose != nil {
+		c.config.OnClose(c)
+	}
 	return err
 }
 
@@ -264,13 +1001,42 @@ func (c *Conn) Close(ctx context.Context) error {
 //
 // Prepare is idempotent; i.e. it is safe to call Prepare multiple times with the same
 // name and sql arguments. This allows a code path to Prepare and Query/Exec without
-// concern for if the statement has already been prepared.
+// concern for if the statement has already been prepared. Preparing a name that is
+// already in use with different sql is an error.
 func (c *Conn) Prepare(ctx context.Context, name, sql string) (sd *pgconn.StatementDescription, err error) {
+	return c.prepare(ctx, name, sql, nil)
+}
+
+// PrepareWithParamOIDs is like Prepare but sends paramOIDs as the types of the parameters instead of letting the
+// server infer them. This is needed to prepare statements where the type of a parameter is ambiguous such as
+// "select $1" or a call to a polymorphic or overloaded function. An OID of 0 leaves that parameter to be inferred.
+// Preparing an existing statement name with the same sql but different parameter types is an error.
+func (c *Conn) PrepareWithParamOIDs(ctx context.Context, name, sql string, paramOIDs []uint32) (sd *pgconn.StatementDescription, err error) {
+	return c.prepare(ctx, name, sql, paramOIDs)
+}
+
+func (c *Conn) prepare(ctx context.Context, name, sql string, paramOIDs []uint32) (sd *pgconn.StatementDescription, err error) {
+	if c.pgConn.IsBusy() {
+		return nil, ErrConnBusy
+	}
+
+	if c.config.TextOnly {
+		return nil, ErrTextOnly
+	}
+
 	if name != "" {
-		var ok bool
-		if sd, ok = c.preparedStatements[name]; ok && sd.SQL == sql {
+		if sd = c.preparedStatements.get(name); sd != nil {
+			if sd.SQL != sql {
+				return nil, fmt.Errorf("prepared statement %q already exists with different sql: %s", name, sd.SQL)
+			}
+			if !paramOIDsMatch(paramOIDs, sd.ParamOIDs) {
+				return nil, fmt.Errorf("prepared statement %q already exists with different parameter types: %v", name, sd.ParamOIDs)
+			}
 			return sd, nil
 		}
+		if stale, ok := c.preparedStatements.stale[name]; ok && stale.sd.SQL != sql {
+			return nil, fmt.Errorf("prepared statement %q already exists with different sql: %s", name, stale.sd.SQL)
+		}
 	}
 
 	if c.shouldLog(LogLevelError) {
@@ -281,34 +1047,123 @@ func (c *Conn) Prepare(ctx context.Context, name, sql string) (sd *pgconn.Statem
 		}()
 	}
 
-	sd, err = c.pgConn.Prepare(ctx, name, sql, nil)
+	if stale, ok := c.preparedStatements.stale[name]; ok && stale.open {
+		err = c.closeStatement(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		delete(c.preparedStatements.stale, name)
+	}
+
+	sd, err = c.pgConn.Prepare(ctx, name, sql, paramOIDs)
 	if err != nil {
 		return nil, err
 	}
+	c.config.Stats.statementPrepared()
 
 	if name != "" {
-		c.preparedStatements[name] = sd
+		c.preparedStatements.put(sd, paramOIDs)
+		err = c.evictPreparedStatements(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return sd, nil
 }
 
+// paramOIDsMatch reports whether the parameter types requested for a statement agree with the types it was prepared
+// with. A requested OID of 0 matches any type.
+func paramOIDsMatch(requested, prepared []uint32) bool {
+	if len(requested) == 0 {
+		return true
+	}
+	if len(requested) > len(prepared) {
+		return false
+	}
+	for i, oid := range requested {
+		if oid != 0 && oid != prepared[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // Deallocate released a prepared statement
 func (c *Conn) Deallocate(ctx context.Context, name string) error {
-	delete(c.preparedStatements, name)
+	if c.pgConn.IsBusy() {
+		return ErrConnBusy
+	}
+
+	if stale, ok := c.preparedStatements.stale[name]; ok && !stale.open {
+		delete(c.preparedStatements.stale, name)
+		return nil
+	}
+	c.preparedStatements.remove(name)
 	_, err := c.pgConn.Exec(ctx, "deallocate "+quoteIdentifier(name)).ReadAll()
 	return err
 }
 
+// DeallocateAll releases all prepared statements including those created automatically by the statement cache. It
+// must be used instead of executing DEALLOCATE ALL or DISCARD ALL directly so pgx does not attempt to use statements
+// that no longer exist.
+func (c *Conn) DeallocateAll(ctx context.Context) error {
+	if c.pgConn.IsBusy() {
+		return ErrConnBusy
+	}
+
+	c.preparedStatements = newPreparedStatementCache()
+	if c.config.BuildStatementCache != nil {
+		c.stmtcache = c.config.BuildStatementCache(c.pgConn)
+	}
+	_, err := c.pgConn.Exec(ctx, "deallocate all").ReadAll()
+	return err
+}
+
 func (c *Conn) bufferNotifications(_ *pgconn.PgConn, n *pgconn.Notification) {
-	c.notifications = append(c.notifications, n)
+	max := c.config.MaxBufferedNotifications
+	if max <= 0 || len(c.notifications) < max {
+		c.notifications = append(c.notifications, n)
+		return
+	}
+
+	dropped := n
+	switch c.config.NotificationOverflowPolicy {
+	case NotificationOverflowDropNewest:
+	case NotificationOverflowError:
+		c.notificationsOverflowed = true
+	default:
+		dropped = c.notifications[0]
+		c.notifications = append(c.notifications[1:], n)
+	}
+
+	c.droppedNotifications++
+	if c.config.OnNotificationDropped != nil {
+		c.config.OnNotificationDropped(c, dropped)
+	}
+}
+
+// DroppedNotifications returns the number of notifications dropped because the buffer was full. See
+// ConnConfig.MaxBufferedNotifications.
+func (c *Conn) DroppedNotifications() int64 {
+	return c.droppedNotifications
 }
 
 // WaitForNotification waits for a PostgreSQL notification. It wraps the underlying pgconn notification system in a
 // slightly more convenient form.
+//
+// If the notification buffer overflowed with NotificationOverflowError, ErrNotificationOverflow is returned once after
+// the buffered notifications have been read.
+//
+// CockroachDB does not support LISTEN/NOTIFY. ErrNotSupportedByCockroachDB is returned if c is connected to
+// CockroachDB.
 func (c *Conn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
 	var n *pgconn.Notification
 
+	if c.IsCockroachDB() {
+		return nil, ErrNotSupportedByCockroachDB
+	}
+
 	// Return already received notification immediately
 	if len(c.notifications) > 0 {
 		n = c.notifications[0]
@@ -316,6 +1171,20 @@ func (c *Conn) WaitForNotification(ctx context.Context) (*pgconn.Notification, e
 		return n, nil
 	}
 
+	if c.notificationsOverflowed {
+		c.notificationsOverflowed = false
+		return nil, ErrNotificationOverflow
+	}
+
+	if c.pgConn.IsBusy() {
+		return nil, ErrConnBusy
+	}
+
+	if c.readTimeoutConn != nil {
+		c.readTimeoutConn.disable()
+		defer c.readTimeoutConn.enable()
+	}
+
 	err := c.pgConn.WaitForNotification(ctx)
 	if len(c.notifications) > 0 {
 		n = c.notifications[0]
@@ -333,6 +1202,7 @@ func (c *Conn) die(err error) {
 	if c.IsClosed() {
 		return
 	}
+	c.lifecycle.setCause(err)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // force immediate hard cancel
@@ -350,6 +1220,13 @@ func (c *Conn) log(ctx context.Context, lvl LogLevel, msg string, data map[strin
 	if c.pgConn != nil && c.pgConn.PID() != 0 {
 		data["pid"] = c.pgConn.PID()
 	}
+	if c.config.ContextLogFields != nil {
+		for k, v := range c.config.ContextLogFields(ctx) {
+			if _, ok := data[k]; !ok {
+				data[k] = v
+			}
+		}
+	}
 
 	c.logger.Log(ctx, lvl, msg, data)
 }
@@ -396,6 +1273,20 @@ func connInfoFromRows(rows Rows, err error) (map[string]uint32, error) {
 // is used and the connection must be returned to the same state before any *pgx.Conn methods are again used.
 func (c *Conn) PgConn() *pgconn.PgConn { return c.pgConn }
 
+// ClientEncoding returns the client_encoding reported by the server. Text sent and received by pgx is assumed to be in
+// this encoding and is only handled correctly when it is UTF8.
+func (c *Conn) ClientEncoding() string { return c.ServerParameter("client_encoding") }
+
+// ServerEncoding returns the server_encoding reported by the server. It is the encoding of the database.
+func (c *Conn) ServerEncoding() string { return c.ServerParameter("server_encoding") }
+
+// RemoteAddr returns the address of the server the connection was established with. When the config has multiple
+// hosts or fallbacks it reports which one was used.
+func (c *Conn) RemoteAddr() net.Addr { return c.pgConn.Conn().RemoteAddr() }
+
+// LocalAddr returns the local address of the connection.
+func (c *Conn) LocalAddr() net.Addr { return c.pgConn.Conn().LocalAddr() }
+
 // StatementCache returns the statement cache used for this connection.
 func (c *Conn) StatementCache() stmtcache.Cache { return c.stmtcache }
 
@@ -409,39 +1300,128 @@ func (c *Conn) Config() *ConnConfig { return c.config.Copy() }
 // positionally from the sql string as $1, $2, etc.
 func (c *Conn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
 	startTime := time.Now()
+	poolWait := c.takePoolWait()
 
 	commandTag, err := c.exec(ctx, sql, arguments...)
+	if c.config.OnQueryStat != nil {
+		c.reportQueryStat(ctx, "Exec", sql, startTime, commandTag.RowsAffected(), err, poolWait)
+	}
 	if err != nil {
 		if c.shouldLog(LogLevelError) {
-			c.log(ctx, LogLevelError, "Exec", map[string]interface{}{"sql": sql, "args": logQueryArgs(arguments), "err": err})
+			c.log(ctx, LogLevelError, "Exec", addPoolWaitLogFields(map[string]interface{}{"sql": sql, "args": logQueryArgs(arguments), "err": err}, poolWait))
 		}
 		return commandTag, err
 	}
 
 	if c.shouldLog(LogLevelInfo) {
 		endTime := time.Now()
-		c.log(ctx, LogLevelInfo, "Exec", map[string]interface{}{"sql": sql, "args": logQueryArgs(arguments), "time": endTime.Sub(startTime), "commandTag": commandTag})
+		c.log(ctx, LogLevelInfo, "Exec", addPoolWaitLogFields(map[string]interface{}{"sql": sql, "args": logQueryArgs(arguments), "time": endTime.Sub(startTime), "commandTag": commandTag}, poolWait))
 	}
 
 	return commandTag, err
 }
 
+// ScriptError occurs when a statement of a script executed by ExecScript fails.
+type ScriptError struct {
+	StatementIndex int // 0-based index of the statement that failed
+	Err            error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("statement %d: %v", e.StatementIndex, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// ExecScript executes sql which may contain multiple statements separated by semicolons such as a migration file. It
+// returns the command tag of each statement. Execution stops at the first statement that fails. The command tags of
+// the statements that were executed before it are returned with a *ScriptError that includes the index of the failed
+// statement. As with Exec, the script runs in an implicit transaction unless it contains transaction control
+// statements so the statements before the failure are usually rolled back. The simple protocol is always used so sql
+// cannot have arguments.
+func (c *Conn) ExecScript(ctx context.Context, sql string) ([]pgconn.CommandTag, error) {
+	startTime := time.Now()
+
+	if c.pgConn.IsBusy() {
+		return nil, ErrConnBusy
+	}
+
+	var commandTags []pgconn.CommandTag
+	var err error
+	mrr := c.pgConn.Exec(ctx, c.commentSQL(ctx, sql))
+	for mrr.NextResult() {
+		commandTag, rrErr := mrr.ResultReader().Close()
+		if rrErr != nil {
+			err = rrErr
+			break
+		}
+		commandTags = append(commandTags, commandTag)
+	}
+	if closeErr := mrr.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		err = &ScriptError{StatementIndex: len(commandTags), Err: err}
+		if c.shouldLog(LogLevelError) {
+			c.log(ctx, LogLevelError, "ExecScript", map[string]interface{}{"sql": sql, "err": err})
+		}
+		return commandTags, err
+	}
+
+	if c.shouldLog(LogLevelInfo) {
+		c.log(ctx, LogLevelInfo, "ExecScript", map[string]interface{}{"sql": sql, "time": time.Since(startTime), "statements": len(commandTags)})
+	}
+
+	return commandTags, nil
+}
+
 func (c *Conn) exec(ctx context.Context, sql string, arguments ...interface{}) (commandTag pgconn.CommandTag, err error) {
-	simpleProtocol := c.config.PreferSimpleProtocol
+	execParams := c.config.PreferExecParams
+	simpleProtocol := c.config.PreferSimpleProtocol && !execParams
 
 optionLoop:
 	for len(arguments) > 0 {
 		switch arg := arguments[0].(type) {
 		case QuerySimpleProtocol:
 			simpleProtocol = bool(arg)
+			execParams = execParams && !simpleProtocol
+			arguments = arguments[1:]
+		case QueryExecParams:
+			execParams = bool(arg)
+			simpleProtocol = simpleProtocol && !execParams
+			arguments = arguments[1:]
+		case QueryParamFormats:
+			c.eqb.paramFormatOverrides = arg
+			defer func() { c.eqb.paramFormatOverrides = nil }()
 			arguments = arguments[1:]
 		default:
 			break optionLoop
 		}
 	}
+	simpleProtocol = simpleProtocol || c.config.TextOnly
+	execParams = execParams && !c.config.TextOnly
+
+	if c.pgConn.IsBusy() {
+		return nil, ErrConnBusy
+	}
 
-	if sd, ok := c.preparedStatements[sql]; ok {
-		return c.execPrepared(ctx, sd, arguments)
+	if sd, err := c.preparedStatement(ctx, sql); err != nil {
+		return nil, err
+	} else if sd != nil {
+		commandTag, err = c.execPrepared(ctx, sd, arguments)
+		if err != nil {
+			c.preparedStatementErrored(sd.Name, err)
+			if c.canRetryInvalidCachedPlan(err) {
+				sd, err = c.preparedStatement(ctx, sql)
+				if err != nil {
+					return nil, err
+				}
+				commandTag, err = c.execPrepared(ctx, sd, arguments)
+			}
+		}
+		return commandTag, err
 	}
 
 	if simpleProtocol {
@@ -452,6 +1432,10 @@ optionLoop:
 		return c.execSimpleProtocol(ctx, sql, arguments)
 	}
 
+	if execParams {
+		return c.execUnnamed(ctx, sql, arguments)
+	}
+
 	if c.stmtcache != nil {
 		sd, err := c.stmtcache.Get(ctx, sql)
 		if err != nil {
@@ -459,18 +1443,65 @@ optionLoop:
 		}
 
 		if c.stmtcache.Mode() == stmtcache.ModeDescribe {
-			return c.execParams(ctx, sd, arguments)
+			commandTag, err = c.execParams(ctx, sd, arguments)
+		} else {
+			commandTag, err = c.execPrepared(ctx, sd, arguments)
 		}
-		return c.execPrepared(ctx, sd, arguments)
+		if err != nil {
+			c.stmtcache.StatementErrored(sql, err)
+			if c.stmtcache.Mode() == stmtcache.ModePrepare && c.canRetryInvalidCachedPlan(err) {
+				sd, err = c.stmtcache.Get(ctx, sql)
+				if err != nil {
+					return nil, err
+				}
+				commandTag, err = c.execPrepared(ctx, sd, arguments)
+			}
+		}
+		return commandTag, err
 	}
 
-	sd, err := c.Prepare(ctx, "", sql)
+	sd, err := c.Prepare(ctx, "", c.commentSQL(ctx, sql))
 	if err != nil {
 		return nil, err
 	}
 	return c.execPrepared(ctx, sd, arguments)
 }
 
+// execUnnamed executes sql with arguments using the unnamed statement in a single round trip.
+func (c *Conn) execUnnamed(ctx context.Context, sql string, arguments []interface{}) (pgconn.CommandTag, error) {
+	if err := c.buildUnnamedParams(sql, arguments); err != nil {
+		return nil, err
+	}
+
+	return c.pgConn.ExecParams(ctx, c.commentSQL(ctx, sql), c.eqb.paramValues, nil, c.eqb.paramFormats, nil).Close()
+}
+
+// buildUnnamedParams resets c.eqb and appends args as parameters of an unspecified type in the text format. sql is
+// only used to check the number of arguments.
+func (c *Conn) buildUnnamedParams(sql string, args []interface{}) error {
+	query, err := sanitize.NewQuery(sql)
+	if err != nil {
+		return err
+	}
+	if paramCount := maxPlaceholder(query); paramCount != len(args) {
+		return &ArgumentCountError{Expected: paramCount, Actual: len(args)}
+	}
+
+	args, err = convertDriverValuers(args)
+	if err != nil {
+		return err
+	}
+
+	c.eqb.Reset()
+	for _, arg := range args {
+		if err := c.eqb.AppendUnknownParam(c.connInfo, arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (c *Conn) execSimpleProtocol(ctx context.Context, sql string, arguments []interface{}) (commandTag pgconn.CommandTag, err error) {
 	if len(arguments) > 0 {
 		sql, err = c.sanitizeForSimpleQuery(sql, arguments...)
@@ -479,7 +1510,7 @@ func (c *Conn) execSimpleProtocol(ctx context.Context, sql string, arguments []i
 		}
 	}
 
-	mrr := c.pgConn.Exec(ctx, sql)
+	mrr := c.pgConn.Exec(ctx, c.commentSQL(ctx, sql))
 	for mrr.NextResult() {
 		commandTag, err = mrr.ResultReader().Close()
 	}
@@ -489,7 +1520,7 @@ func (c *Conn) execSimpleProtocol(ctx context.Context, sql string, arguments []i
 
 func (c *Conn) execParamsAndPreparedPrefix(sd *pgconn.StatementDescription, arguments []interface{}) error {
 	if len(sd.ParamOIDs) != len(arguments) {
-		return fmt.Errorf("expected %d arguments, got %d", len(sd.ParamOIDs), len(arguments))
+		return &ArgumentCountError{Expected: len(sd.ParamOIDs), Actual: len(arguments)}
 	}
 
 	c.eqb.Reset()
@@ -519,7 +1550,7 @@ func (c *Conn) execParams(ctx context.Context, sd *pgconn.StatementDescription,
 		return nil, err
 	}
 
-	result := c.pgConn.ExecParams(ctx, sd.SQL, c.eqb.paramValues, sd.ParamOIDs, c.eqb.paramFormats, c.eqb.resultFormats).Read()
+	result := c.pgConn.ExecParams(ctx, c.commentSQL(ctx, sd.SQL), c.eqb.paramValues, sd.ParamOIDs, c.eqb.paramFormats, c.eqb.resultFormats).Read()
 	c.eqb.Reset() // Allow c.eqb internal memory to be GC'ed as soon as possible.
 	return result.CommandTag, result.Err
 }
@@ -550,6 +1581,7 @@ func (c *Conn) getRows(ctx context.Context, sql string, args []interface{}) *con
 	r.sql = sql
 	r.args = args
 	r.conn = c
+	r.maxResultBytes = c.config.MaxResultBytes
 
 	return r
 }
@@ -557,12 +1589,25 @@ func (c *Conn) getRows(ctx context.Context, sql string, args []interface{}) *con
 // QuerySimpleProtocol controls whether the simple or extended protocol is used to send the query.
 type QuerySimpleProtocol bool
 
+// QueryExecParams controls whether the query is sent with the unnamed statement in a single round trip instead of
+// being prepared first. See ConnConfig.PreferExecParams.
+type QueryExecParams bool
+
 // QueryResultFormats controls the result format (text=0, binary=1) of a query by result column position.
 type QueryResultFormats []int16
 
+// QueryParamFormats forces the format (text=0, binary=1) of the arguments at the given zero-based positions instead of
+// choosing it from the parameter type. A []byte argument with a forced format is sent as is, which allows passing a
+// value that is already encoded, such as the binary representation of a custom type. It is ignored by the simple
+// protocol.
+type QueryParamFormats map[int]int16
+
 // QueryResultFormatsByOID controls the result format (text=0, binary=1) of a query by the result column OID.
 type QueryResultFormatsByOID map[uint32]int16
 
+// QueryMaxResultBytes overrides ConnConfig.MaxResultBytes for a query. 0 means no limit.
+type QueryMaxResultBytes int64
+
 // Query executes sql with args. It is safe to attempt to read from the returned Rows even if an error is returned. The
 // error will be the available in rows.Err() after rows are closed. So it is allowed to ignore the error returned from
 // Query and handle it in Rows.
@@ -570,13 +1615,17 @@ type QueryResultFormatsByOID map[uint32]int16
 // Err() on the returned Rows must be checked after the Rows is closed to determine if the query executed successfully
 // as some errors can only be detected by reading the entire response. e.g. A divide by zero error on the last row.
 //
-// For extra control over how the query is executed, the types QuerySimpleProtocol, QueryResultFormats, and
-// QueryResultFormatsByOID may be used as the first args to control exactly how the query is executed. This is rarely
+// For extra control over how the query is executed, the types QuerySimpleProtocol, QueryExecParams, QueryResultFormats,
+// QueryResultFormatsByOID, QueryParamFormats and QueryMaxResultBytes may be used as the first args to control exactly how the query is executed. This is rarely
 // needed. See the documentation for those types for details.
 func (c *Conn) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	querySQL, queryArgs := sql, args
+
 	var resultFormats QueryResultFormats
 	var resultFormatsByOID QueryResultFormatsByOID
-	simpleProtocol := c.config.PreferSimpleProtocol
+	maxResultBytes := c.config.MaxResultBytes
+	execParams := c.config.PreferExecParams
+	simpleProtocol := c.config.PreferSimpleProtocol && !execParams
 
 optionLoop:
 	for len(args) > 0 {
@@ -589,16 +1638,44 @@ optionLoop:
 			args = args[1:]
 		case QuerySimpleProtocol:
 			simpleProtocol = bool(arg)
+			execParams = execParams && !simpleProtocol
+			args = args[1:]
+		case QueryExecParams:
+			execParams = bool(arg)
+			simpleProtocol = simpleProtocol && !execParams
+			args = args[1:]
+		case QueryParamFormats:
+			c.eqb.paramFormatOverrides = arg
+			defer func() { c.eqb.paramFormatOverrides = nil }()
+			args = args[1:]
+		case QueryMaxResultBytes:
+			maxResultBytes = int64(arg)
 			args = args[1:]
 		default:
 			break optionLoop
 		}
 	}
+	simpleProtocol = simpleProtocol || c.config.TextOnly
+	execParams = execParams && !c.config.TextOnly
 
 	rows := c.getRows(ctx, sql, args)
+	rows.poolWait = c.takePoolWait()
+	rows.maxResultBytes = maxResultBytes
 
-	var err error
-	sd, ok := c.preparedStatements[sql]
+	if c.pgConn.IsBusy() {
+		rows.fatal(ErrConnBusy)
+		return rows, ErrConnBusy
+	}
+
+	sd, err := c.preparedStatement(ctx, sql)
+	if err != nil {
+		rows.fatal(err)
+		return rows, err
+	}
+	ok := sd != nil
+	if ok {
+		rows.preparedName = sd.Name
+	}
 
 	if simpleProtocol && !ok {
 		sql, err = c.sanitizeForSimpleQuery(sql, args...)
@@ -607,7 +1684,7 @@ optionLoop:
 			return rows, err
 		}
 
-		mrr := c.pgConn.Exec(ctx, sql)
+		mrr := c.pgConn.Exec(ctx, c.commentSQL(ctx, sql))
 		if mrr.NextResult() {
 			rows.resultReader = mrr.ResultReader()
 			rows.multiResultReader = mrr
@@ -620,6 +1697,18 @@ optionLoop:
 		return rows, nil
 	}
 
+	if execParams && !ok {
+		err = c.buildUnnamedParams(sql, args)
+		if err != nil {
+			rows.fatal(err)
+			return rows, err
+		}
+
+		// The result types are not known before the query is executed so binary results cannot be requested by OID.
+		rows.resultReader = c.pgConn.ExecParams(ctx, c.commentSQL(ctx, sql), c.eqb.paramValues, nil, c.eqb.paramFormats, resultFormats)
+		return rows, nil
+	}
+
 	c.eqb.Reset()
 
 	if !ok {
@@ -630,7 +1719,7 @@ optionLoop:
 				return rows, rows.err
 			}
 		} else {
-			sd, err = c.pgConn.Prepare(ctx, "", sql, nil)
+			sd, err = c.pgConn.Prepare(ctx, "", c.commentSQL(ctx, sql), nil)
 			if err != nil {
 				rows.fatal(err)
 				return rows, rows.err
@@ -638,7 +1727,7 @@ optionLoop:
 		}
 	}
 	if len(sd.ParamOIDs) != len(args) {
-		rows.fatal(fmt.Errorf("expected %d arguments, got %d", len(sd.ParamOIDs), len(args)))
+		rows.fatal(&ArgumentCountError{Expected: len(sd.ParamOIDs), Actual: len(args)})
 		return rows, rows.err
 	}
 
@@ -674,9 +1763,10 @@ optionLoop:
 	}
 
 	if c.stmtcache != nil && c.stmtcache.Mode() == stmtcache.ModeDescribe {
-		rows.resultReader = c.pgConn.ExecParams(ctx, sql, c.eqb.paramValues, sd.ParamOIDs, c.eqb.paramFormats, resultFormats)
+		rows.resultReader = c.pgConn.ExecParams(ctx, c.commentSQL(ctx, sql), c.eqb.paramValues, sd.ParamOIDs, c.eqb.paramFormats, resultFormats)
 	} else {
 		rows.resultReader = c.pgConn.ExecPrepared(ctx, sd.Name, c.eqb.paramValues, c.eqb.paramFormats, resultFormats)
+		rows.retrySQL, rows.retryArgs, rows.retryable = querySQL, queryArgs, true
 	}
 
 	c.eqb.Reset() // Allow c.eqb internal memory to be GC'ed as soon as possible.
@@ -705,6 +1795,27 @@ type QueryFuncRow interface {
 	RawValues() [][]byte
 }
 
+// QueryCount executes sql with args and returns the number of rows it returned. The rows are read but not decoded so it
+// is cheaper than scanning them. The command tag of the query is available in CommandTag of Rows when more than a count
+// is needed.
+func (c *Conn) QueryCount(ctx context.Context, sql string, args ...interface{}) (int64, error) {
+	rows, err := c.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var n int64
+	for rows.Next() {
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
 // QueryFunc executes sql with args. For each row returned by the query the values will scanned into the elements of
 // scans and f will be called. If any row fails to scan or f returns an error the query will be aborted and the error
 // will be returned.
@@ -738,7 +1849,11 @@ func (c *Conn) QueryFunc(ctx context.Context, sql string, args []interface{}, sc
 // explicit transaction control statements are executed. The returned BatchResults must be closed before the connection
 // is used again.
 func (c *Conn) SendBatch(ctx context.Context, b *Batch) BatchResults {
-	simpleProtocol := c.config.PreferSimpleProtocol
+	if c.pgConn.IsBusy() {
+		return &batchResults{ctx: ctx, conn: c, err: ErrConnBusy}
+	}
+
+	simpleProtocol := c.config.PreferSimpleProtocol || c.config.TextOnly
 	var sb strings.Builder
 	if simpleProtocol {
 		for i, bi := range b.items {
@@ -751,7 +1866,7 @@ func (c *Conn) SendBatch(ctx context.Context, b *Batch) BatchResults {
 			}
 			sb.WriteString(sql)
 		}
-		mrr := c.pgConn.Exec(ctx, sb.String())
+		mrr := c.pgConn.Exec(ctx, c.commentSQL(ctx, sb.String()))
 		return &batchResults{
 			ctx:  ctx,
 			conn: c,
@@ -764,7 +1879,11 @@ func (c *Conn) SendBatch(ctx context.Context, b *Batch) BatchResults {
 	distinctUnpreparedQueries := map[string]struct{}{}
 
 	for _, bi := range b.items {
-		if _, ok := c.preparedStatements[bi.query]; ok {
+		sd, err := c.preparedStatement(ctx, bi.query)
+		if err != nil {
+			return &batchResults{ctx: ctx, conn: c, err: err}
+		}
+		if sd != nil {
 			continue
 		}
 		distinctUnpreparedQueries[bi.query] = struct{}{}
@@ -791,9 +1910,11 @@ func (c *Conn) SendBatch(ctx context.Context, b *Batch) BatchResults {
 	for _, bi := range b.items {
 		c.eqb.Reset()
 
-		sd := c.preparedStatements[bi.query]
+		sd, err := c.preparedStatement(ctx, bi.query)
+		if err != nil {
+			return &batchResults{ctx: ctx, conn: c, err: err}
+		}
 		if sd == nil {
-			var err error
 			sd, err = stmtCache.Get(ctx, bi.query)
 			if err != nil {
 				// the stmtCache was prefilled from distinctUnpreparedQueries above so we are guaranteed no errors
@@ -802,7 +1923,7 @@ func (c *Conn) SendBatch(ctx context.Context, b *Batch) BatchResults {
 		}
 
 		if len(sd.ParamOIDs) != len(bi.arguments) {
-			return &batchResults{ctx: ctx, conn: c, err: fmt.Errorf("mismatched param and argument count")}
+			return &batchResults{ctx: ctx, conn: c, err: &ArgumentCountError{Expected: len(sd.ParamOIDs), Actual: len(bi.arguments)}}
 		}
 
 		args, err := convertDriverValuers(bi.arguments)
@@ -822,7 +1943,7 @@ func (c *Conn) SendBatch(ctx context.Context, b *Batch) BatchResults {
 		}
 
 		if sd.Name == "" {
-			batch.ExecParams(bi.query, c.eqb.paramValues, sd.ParamOIDs, c.eqb.paramFormats, c.eqb.resultFormats)
+			batch.ExecParams(c.commentSQL(ctx, bi.query), c.eqb.paramValues, sd.ParamOIDs, c.eqb.paramFormats, c.eqb.resultFormats)
 		} else {
 			batch.ExecPrepared(sd.Name, c.eqb.paramValues, c.eqb.paramFormats, c.eqb.resultFormats)
 		}
@@ -842,22 +1963,45 @@ func (c *Conn) SendBatch(ctx context.Context, b *Batch) BatchResults {
 }
 
 func (c *Conn) sanitizeForSimpleQuery(sql string, args ...interface{}) (string, error) {
-	if c.pgConn.ParameterStatus("standard_conforming_strings") != "on" {
-		return "", errors.New("simple protocol queries must be run with standard_conforming_strings=on")
-	}
-
 	if c.pgConn.ParameterStatus("client_encoding") != "UTF8" {
 		return "", errors.New("simple protocol queries must be run with client_encoding=UTF8")
 	}
 
-	var err error
+	// The server reports standard_conforming_strings whenever it changes so this reflects the current setting.
+	query, err := sanitize.NewQueryForServer(sql, sanitize.ServerSettings{
+		StandardConformingStrings: c.pgConn.ParameterStatus("standard_conforming_strings") == "on",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if paramCount := maxPlaceholder(query); paramCount != len(args) {
+		return "", &ArgumentCountError{Expected: paramCount, Actual: len(args)}
+	}
+
 	valueArgs := make([]interface{}, len(args))
 	for i, a := range args {
+		if c.config.StrictTimePrecision {
+			if err := checkTimePrecision(a); err != nil {
+				return "", err
+			}
+		}
 		valueArgs[i], err = convertSimpleArgument(c.connInfo, a)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	return sanitize.SanitizeSQL(sql, valueArgs...)
+	return query.Sanitize(valueArgs...)
+}
+
+// maxPlaceholder returns the highest placeholder number referenced by query.
+func maxPlaceholder(query *sanitize.Query) int {
+	n := 0
+	for _, part := range query.Parts {
+		if i, ok := part.(int); ok && i > n {
+			n = i
+		}
+	}
+	return n
 }
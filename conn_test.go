@@ -2,7 +2,17 @@ package pgx_test
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
 	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -10,6 +20,7 @@ import (
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgconn/stmtcache"
+	"github.com/jackc/pgproto3/v2"
 	"github.com/jackc/pgtype"
 	"github.com/nappspt/schemapgx/v4"
 	"github.com/stretchr/testify/assert"
@@ -137,6 +148,41 @@ func TestConfigCopyCanBeUsedToConnect(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestConfigCloneCopiesTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	original, err := pgx.ParseConfig("host=a.example.com,b.example.com sslmode=require")
+	require.NoError(t, err)
+	require.NotNil(t, original.TLSConfig)
+	require.NotEmpty(t, original.Fallbacks)
+
+	cloned := original.Clone()
+	require.NotNil(t, cloned.TLSConfig)
+	assert.NotSame(t, original.TLSConfig, cloned.TLSConfig)
+	for i := range original.Fallbacks {
+		assert.NotSame(t, original.Fallbacks[i], cloned.Fallbacks[i])
+		assert.NotSame(t, original.Fallbacks[i].TLSConfig, cloned.Fallbacks[i].TLSConfig)
+	}
+
+	cloned.TLSConfig.ServerName = "changed"
+	assert.NotEqual(t, "changed", original.TLSConfig.ServerName)
+}
+
+func TestConfigString(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgx.ParseConfig("host=a.example.com,b.example.com port=5433,5432 user=jack password=secret dbname=mydb sslmode=disable application_name='my app' search_path=public")
+	require.NoError(t, err)
+
+	s := config.String()
+	assert.Equal(t, "host=a.example.com,b.example.com port=5433,5432 user=jack password=xxxxx dbname=mydb application_name='my app' search_path=public", s)
+	assert.NotContains(t, s, "secret")
+
+	config.Password = ""
+	config.Database = "it's"
+	assert.Equal(t, `host=a.example.com,b.example.com port=5433,5432 user=jack dbname='it\'s' application_name='my app' search_path=public`, config.String())
+}
+
 func TestParseConfigExtractsStatementCacheOptions(t *testing.T) {
 	t.Parallel()
 
@@ -189,6 +235,813 @@ func TestParseConfigExtractsPreferSimpleProtocol(t *testing.T) {
 	}
 }
 
+func TestParseConfigExtractsCockroachDB(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgx.ParseConfig("cockroachdb=true")
+	require.NoError(t, err)
+	require.True(t, config.CockroachDB)
+	require.Empty(t, config.RuntimeParams["cockroachdb"])
+
+	_, err = pgx.ParseConfig("cockroachdb=maybe")
+	require.Error(t, err)
+}
+
+func TestConnectRequireUTF8(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgx.ParseConfig(os.Getenv("PGX_TEST_DATABASE") + " require_utf8=true")
+	require.NoError(t, err)
+	require.True(t, config.RequireUTF8)
+	require.Empty(t, config.RuntimeParams["require_utf8"])
+
+	_, err = pgx.ParseConfig("require_utf8=maybe")
+	require.Error(t, err)
+
+	conn := mustConnect(t, config)
+	assert.Equal(t, "UTF8", conn.ClientEncoding())
+	assert.NotEmpty(t, conn.ServerEncoding())
+	closeConn(t, conn)
+
+	config.RuntimeParams["client_encoding"] = "LATIN1"
+	_, err = pgx.ConnectConfig(context.Background(), config)
+	var encodingErr *pgx.ClientEncodingError
+	require.ErrorAs(t, err, &encodingErr)
+	assert.Equal(t, "LATIN1", encodingErr.ClientEncoding)
+}
+
+func TestConnServerParameters(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	var versionNum int
+	var superuser, timeZone string
+	err := conn.QueryRow(context.Background(), "select current_setting('server_version_num')::int, current_setting('is_superuser'), current_setting('TimeZone')").Scan(&versionNum, &superuser, &timeZone)
+	require.NoError(t, err)
+
+	assert.Equal(t, versionNum, conn.ServerVersion())
+	assert.Equal(t, superuser == "on", conn.IsSuperuser())
+	assert.Equal(t, timeZone, conn.ServerParameter("TimeZone"))
+
+	_, err = conn.Exec(context.Background(), "set application_name = 'pgx server parameter test'")
+	require.NoError(t, err)
+	assert.Equal(t, "pgx server parameter test", conn.ServerParameter("application_name"))
+
+	_, err = conn.Exec(context.Background(), "set time zone 'America/Chicago'")
+	require.NoError(t, err)
+	loc, err := conn.TimeZone()
+	require.NoError(t, err)
+	assert.Equal(t, "America/Chicago", loc.String())
+
+	// ServerParameter may be called while the connection is in use.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			conn.ServerParameter("application_name")
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		_, err = conn.Exec(context.Background(), "set application_name = 'concurrent'")
+		require.NoError(t, err)
+	}
+	<-done
+}
+
+func TestConnectDefaultTxOptions(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.DefaultTxIsoLevel = pgx.RepeatableRead
+	config.DefaultTxAccessMode = pgx.ReadOnly
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+	skipCockroachDB(t, conn, "Server does not support default_transaction_isolation in the startup message")
+
+	var isoLevel, readOnly string
+	err := conn.QueryRow(context.Background(), "select current_setting('transaction_isolation'), current_setting('transaction_read_only')").Scan(&isoLevel, &readOnly)
+	require.NoError(t, err)
+	assert.Equal(t, "repeatable read", isoLevel)
+	assert.Equal(t, "on", readOnly)
+
+	_, err = conn.Exec(context.Background(), "create temporary table foo(id int)")
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, "25006", pgErr.Code)
+
+	config = mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.DefaultTxAccessMode = "sometimes"
+	_, err = pgx.ConnectConfig(context.Background(), config)
+	require.Error(t, err)
+}
+
+func TestConnLifecycleHooks(t *testing.T) {
+	t.Parallel()
+
+	var connected, closed int
+	deathChan := make(chan error, 1)
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.OnConnect = func(*pgx.Conn) { connected++ }
+	config.OnClose = func(*pgx.Conn) { closed++ }
+	config.OnDeath = func(c *pgx.Conn, err error) { deathChan <- err }
+
+	conn := mustConnect(t, config)
+	assert.Equal(t, 1, connected)
+	closeConn(t, conn)
+	assert.Equal(t, 1, closed)
+
+	conn = mustConnect(t, config)
+	assert.Equal(t, 2, connected)
+
+	// Break the connection underneath pgx.
+	require.NoError(t, conn.PgConn().Conn().Close())
+	_, err := conn.Exec(context.Background(), "select 1")
+	require.Error(t, err)
+
+	select {
+	case err := <-deathChan:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnDeath was not called")
+	}
+
+	require.NoError(t, conn.Close(context.Background()))
+	assert.Equal(t, 1, closed)
+}
+
+func TestConnOnDeathIgnoresTimeouts(t *testing.T) {
+	t.Parallel()
+
+	// The server completes startup and closes the connection when told to. Later connections, such as the cancel
+	// request sent when the connection dies, are closed immediately.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	closeServerConn := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+		defer conn.Close()
+
+		backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+		if _, err := backend.ReceiveStartupMessage(); err != nil {
+			return
+		}
+		backend.Send(&pgproto3.AuthenticationOk{})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		<-closeServerConn
+	}()
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	deathChan := make(chan error, 1)
+	config, err := pgx.ParseConfig("host=" + host + " port=" + port + " sslmode=disable user=pgx_test")
+	require.NoError(t, err)
+	config.OnDeath = func(c *pgx.Conn, err error) { deathChan <- err }
+	conn, err := pgx.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	// Waiting for a notification times out without breaking the connection.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	_, err = conn.WaitForNotification(ctx)
+	cancel()
+	require.Error(t, err)
+	require.False(t, conn.IsClosed())
+
+	close(closeServerConn)
+	_, err = conn.Exec(context.Background(), "select 1")
+	require.Error(t, err)
+
+	select {
+	case err := <-deathChan:
+		var netErr net.Error
+		assert.Falsef(t, errors.As(err, &netErr) && netErr.Timeout(), "OnDeath reported the earlier timeout: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnDeath was not called")
+	}
+}
+
+func TestConnTextOnly(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgx.ParseConfig(os.Getenv("PGX_TEST_DATABASE") + " text_only=true")
+	require.NoError(t, err)
+	require.True(t, config.TextOnly)
+	require.Empty(t, config.RuntimeParams["text_only"])
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var n int64
+	var s string
+	err = conn.QueryRow(context.Background(), "select $1::int8, $2::text", pgx.QuerySimpleProtocol(false), 42, "foo").Scan(&n, &s)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+	assert.Equal(t, "foo", s)
+
+	rows, err := conn.Query(context.Background(), "select 1::int4")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+	for _, fd := range rows.FieldDescriptions() {
+		assert.EqualValues(t, pgx.TextFormatCode, fd.Format)
+	}
+
+	_, err = conn.Prepare(context.Background(), "ps", "select 1")
+	require.ErrorIs(t, err, pgx.ErrTextOnly)
+
+	_, err = conn.CopyFrom(context.Background(), pgx.Identifier{"foo"}, []string{"a"}, pgx.CopyFromRows(nil))
+	require.ErrorIs(t, err, pgx.ErrTextOnly)
+
+	ensureConnValid(t, conn)
+}
+
+func TestParseConfigSetsTLSServerName(t *testing.T) {
+	t.Parallel()
+
+	for _, sslmode := range []string{"prefer", "require", "verify-ca", "verify-full"} {
+		config, err := pgx.ParseConfig("host=a.example.com,b.example.com sslmode=" + sslmode)
+		require.NoError(t, err, sslmode)
+
+		require.NotNil(t, config.TLSConfig, sslmode)
+		assert.Equal(t, "a.example.com", config.TLSConfig.ServerName, sslmode)
+		for _, fb := range config.Fallbacks {
+			if fb.TLSConfig != nil {
+				assert.Equal(t, fb.Host, fb.TLSConfig.ServerName, sslmode)
+			}
+		}
+	}
+
+}
+
+func TestConnectGetTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgx.ParseConfig("host=localhost sslmode=require")
+	require.NoError(t, err)
+
+	calls := 0
+	config.GetTLSConfig = func() (*tls.Config, error) {
+		calls++
+		return nil, errors.New("certificate not ready")
+	}
+
+	for i := 1; i <= 2; i++ {
+		_, err = pgx.ConnectConfig(context.Background(), config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "certificate not ready")
+		assert.Equal(t, i, calls)
+	}
+
+	config.GetTLSConfig = func() (*tls.Config, error) { return nil, nil }
+	_, err = pgx.ConnectConfig(context.Background(), config)
+	require.EqualError(t, err, "GetTLSConfig returned nil TLS config")
+}
+
+func TestConnectGetPassword(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	password := config.Password
+	config.Password = "wrong"
+	var passedHost string
+	config.GetPassword = func(ctx context.Context, cc *pgx.ConnConfig) (string, error) {
+		passedHost = cc.Host
+		return password, nil
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+	assert.Equal(t, config.Host, passedHost)
+	assert.Equal(t, "wrong", config.Password)
+
+	getPasswordErr := errors.New("no credentials")
+	config.GetPassword = func(ctx context.Context, cc *pgx.ConnConfig) (string, error) {
+		return "", getPasswordErr
+	}
+	_, err := pgx.ConnectConfig(context.Background(), config)
+	require.ErrorIs(t, err, getPasswordErr)
+}
+
+func TestConnectGetPasswordRefresh(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	password := config.Password
+	var refreshes []bool
+	config.GetPassword = func(ctx context.Context, cc *pgx.ConnConfig) (string, error) {
+		refresh := pgx.IsPasswordRefresh(ctx)
+		refreshes = append(refreshes, refresh)
+		if !refresh {
+			return "expired-" + strings.Repeat("x", 4096), nil
+		}
+		return password, nil
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	// The first password is only rejected when the server requires password authentication.
+	require.NotEmpty(t, refreshes)
+	assert.False(t, refreshes[0])
+	if len(refreshes) > 1 {
+		assert.Equal(t, []bool{false, true}, refreshes)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnectDial(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.LookupFunc = pgx.SkipLookup
+	var infos []pgx.DialInfo
+	config.Dial = func(ctx context.Context, info pgx.DialInfo) (net.Conn, error) {
+		infos = append(infos, info)
+		var d net.Dialer
+		return d.DialContext(ctx, info.Network, info.Addr)
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	require.Len(t, infos, 1)
+	assert.Equal(t, config.Host, infos[0].Host)
+	assert.Equal(t, config.Port, infos[0].Port)
+	assert.Equal(t, config.Database, infos[0].Database)
+	assert.Equal(t, config.User, infos[0].User)
+	assert.Same(t, config, infos[0].Config)
+	if infos[0].Network == "tcp" {
+		assert.Equal(t, net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port))), infos[0].Addr)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnectErrorListsAttempts(t *testing.T) {
+	t.Parallel()
+
+	// The server refuses TLS so the attempt fails after dialing.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 8)
+			if _, err := conn.Read(buf); err == nil {
+				conn.Write([]byte{'N'})
+			}
+			conn.Close()
+		}
+	}()
+	_, tlsPort, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	config, err := pgx.ParseConfig("host=bad.example,127.0.0.1,tlshost.example port=1,1," + tlsPort + " sslmode=require user=pgx_test connect_timeout=5")
+	require.NoError(t, err)
+	config.LookupFunc = func(ctx context.Context, host string) ([]string, error) {
+		switch host {
+		case "bad.example":
+			return nil, errors.New("no such host")
+		case "tlshost.example":
+			return []string{"127.0.0.1"}, nil
+		}
+		return []string{host}, nil
+	}
+
+	_, err = pgx.ConnectConfig(context.Background(), config)
+	var connectErr *pgx.ConnectError
+	require.ErrorAs(t, err, &connectErr)
+	require.Len(t, connectErr.Attempts, 3)
+
+	assert.Equal(t, "bad.example", connectErr.Attempts[0].Host)
+	assert.Equal(t, pgx.ConnectPhaseLookup, connectErr.Attempts[0].Phase)
+
+	assert.Equal(t, "127.0.0.1:1", connectErr.Attempts[1].Addr)
+	assert.Equal(t, pgx.ConnectPhaseDial, connectErr.Attempts[1].Phase)
+
+	assert.Equal(t, "tlshost.example", connectErr.Attempts[2].Host)
+	assert.Equal(t, net.JoinHostPort("127.0.0.1", tlsPort), connectErr.Attempts[2].Addr)
+	assert.Equal(t, pgx.ConnectPhaseTLS, connectErr.Attempts[2].Phase)
+
+	assert.Contains(t, err.Error(), "failed to connect to any of 3 addresses: bad.example (lookup): no such host; 127.0.0.1:1 (dial): ")
+}
+
+func TestConnectPromptPassword(t *testing.T) {
+	t.Parallel()
+
+	// The server requires a cleartext password and accepts any.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	passwords := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+		if _, err := backend.ReceiveStartupMessage(); err != nil {
+			return
+		}
+		if err := backend.Send(&pgproto3.AuthenticationCleartextPassword{}); err != nil {
+			return
+		}
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		passwords <- msg.(*pgproto3.PasswordMessage).Password
+		for _, msg := range []pgproto3.BackendMessage{
+			&pgproto3.AuthenticationOk{},
+			&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1},
+			&pgproto3.ReadyForQuery{TxStatus: 'I'},
+		} {
+			if err := backend.Send(msg); err != nil {
+				return
+			}
+		}
+		backend.Receive()
+	}()
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	config, err := pgx.ParseConfig("host=" + host + " port=" + port + " sslmode=disable user=pgx_test password='' passfile=/nonexistent")
+	require.NoError(t, err)
+	require.Empty(t, config.Password)
+	prompts := 0
+	config.PromptPassword = func(ctx context.Context, config *pgx.ConnConfig) (string, error) {
+		prompts++
+		assert.Equal(t, "pgx_test", config.User)
+		return "secret", nil
+	}
+
+	conn, err := pgx.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+	assert.Equal(t, 1, prompts)
+	assert.Equal(t, "secret", <-passwords)
+}
+
+func TestConnectDisableMD5(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgx.ParseConfig("disable_md5=true")
+	require.NoError(t, err)
+	assert.True(t, config.DisableMD5)
+	assert.NotContains(t, config.RuntimeParams, "disable_md5")
+
+	// The server requires md5 password authentication.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	received := make(chan pgproto3.FrontendMessage, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+		if _, err := backend.ReceiveStartupMessage(); err != nil {
+			return
+		}
+		if err := backend.Send(&pgproto3.AuthenticationMD5Password{Salt: [4]byte{1, 2, 3, 4}}); err != nil {
+			return
+		}
+		msg, _ := backend.Receive()
+		received <- msg
+	}()
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	config, err = pgx.ParseConfig("host=" + host + " port=" + port + " sslmode=disable user=pgx_test password=secret disable_md5=true")
+	require.NoError(t, err)
+
+	_, err = pgx.ConnectConfig(context.Background(), config)
+	require.ErrorIs(t, err, pgx.ErrMD5Disabled)
+	_, isPassword := (<-received).(*pgproto3.PasswordMessage)
+	assert.False(t, isPassword)
+}
+
+type testGSS struct {
+	spn string
+}
+
+func (g *testGSS) InitToken(spn string) ([]byte, error) {
+	g.spn = spn
+	return []byte("init"), nil
+}
+
+func (g *testGSS) Continue(inToken []byte) (bool, []byte, error) {
+	return true, append([]byte("reply to "), inToken...), nil
+}
+
+func TestConnectGSS(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgx.ParseConfig("krbsrvname=pg krbspn=pg/db.example.com@EXAMPLE.COM")
+	require.NoError(t, err)
+	assert.Equal(t, "pg", config.KerberosServiceName)
+	assert.Equal(t, "pg/db.example.com@EXAMPLE.COM", config.KerberosSPN)
+	assert.NotContains(t, config.RuntimeParams, "krbsrvname")
+	assert.NotContains(t, config.RuntimeParams, "krbspn")
+
+	// The server requests SSPI authentication and completes it after one continuation.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	tokens := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		readMessage := func(header int) []byte {
+			buf := make([]byte, header+4)
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				return nil
+			}
+			body := make([]byte, int(binary.BigEndian.Uint32(buf[header:]))-4)
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return nil
+			}
+			return body
+		}
+		authRequest := func(authType uint32, data []byte) []byte {
+			buf := []byte{'R', 0, 0, 0, 0, 0, 0, 0, byte(authType)}
+			binary.BigEndian.PutUint32(buf[1:], uint32(8+len(data)))
+			return append(buf, data...)
+		}
+
+		readMessage(0) // startup message
+		conn.Write(authRequest(9, nil))
+		tokens <- string(readMessage(1))
+		conn.Write(authRequest(8, []byte("challenge")))
+		tokens <- string(readMessage(1))
+
+		var buf []byte
+		for _, msg := range []pgproto3.BackendMessage{
+			&pgproto3.AuthenticationOk{},
+			&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1},
+			&pgproto3.ReadyForQuery{TxStatus: 'I'},
+		} {
+			buf = msg.Encode(buf)
+		}
+		conn.Write(buf)
+		readMessage(1) // terminate
+	}()
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	config, err = pgx.ParseConfig("host=" + host + " port=" + port + " sslmode=disable user=pgx_test")
+	require.NoError(t, err)
+	gss := &testGSS{}
+	config.NewGSS = func() (pgx.GSS, error) { return gss, nil }
+
+	conn, err := pgx.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+	assert.Equal(t, "postgres/"+host, gss.spn)
+	assert.Equal(t, "init", <-tokens)
+	assert.Equal(t, "reply to challenge", <-tokens)
+}
+
+// serveTrustStartup accepts connections on ln and completes their startup without authentication.
+func serveTrustStartup(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+
+			backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+			if _, err := backend.ReceiveStartupMessage(); err != nil {
+				return
+			}
+			var buf []byte
+			for _, msg := range []pgproto3.BackendMessage{
+				&pgproto3.AuthenticationOk{},
+				&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1},
+				&pgproto3.ReadyForQuery{TxStatus: 'I'},
+			} {
+				buf = msg.Encode(buf)
+			}
+			if _, err := conn.Write(buf); err != nil {
+				return
+			}
+			backend.Receive()
+		}()
+	}
+}
+
+func TestConnectUnixSocket(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract namespace sockets and requirepeer are only supported on Linux")
+	}
+
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	ln, err := net.Listen("unix", filepath.Join(dir, "custom.sock"))
+	require.NoError(t, err)
+	defer ln.Close()
+	go serveTrustStartup(ln)
+
+	abstractName := "@pgx_test_" + strconv.Itoa(os.Getpid())
+	abstractLn, err := net.Listen("unix", abstractName+"/.s.PGSQL.5432")
+	require.NoError(t, err)
+	defer abstractLn.Close()
+	go serveTrustStartup(abstractLn)
+
+	config, err := pgx.ParseConfig("host=" + dir + " user=pgx_test unix_socket_name=custom.sock requirepeer=" + currentUser.Username)
+	require.NoError(t, err)
+	assert.Equal(t, "custom.sock", config.UnixSocketName)
+	assert.Equal(t, currentUser.Username, config.RequirePeer)
+	assert.NotContains(t, config.RuntimeParams, "unix_socket_name")
+	assert.NotContains(t, config.RuntimeParams, "requirepeer")
+
+	conn, err := pgx.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "custom.sock"), conn.RemoteAddr().String())
+	require.NoError(t, conn.Close(context.Background()))
+
+	config, err = pgx.ParseConfig("host=" + dir + " user=pgx_test unix_socket_name=custom.sock requirepeer=pgx_no_such_user")
+	require.NoError(t, err)
+	_, err = pgx.ConnectConfig(context.Background(), config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `requirepeer specifies "pgx_no_such_user"`)
+
+	config, err = pgx.ParseConfig("host=" + abstractName + " port=5432 user=pgx_test sslmode=prefer")
+	require.NoError(t, err)
+	assert.Nil(t, config.TLSConfig)
+	assert.Empty(t, config.Fallbacks)
+
+	conn, err = pgx.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close(context.Background()))
+}
+
+func TestConnectStartupTimeout(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgx.ParseConfig("startup_timeout=250ms")
+	require.NoError(t, err)
+	assert.Equal(t, 250*time.Millisecond, config.StartupTimeout)
+	assert.NotContains(t, config.RuntimeParams, "startup_timeout")
+
+	_, err = pgx.ParseConfig("startup_timeout=forever")
+	require.Error(t, err)
+
+	// The server accepts connections but never responds to the startup message.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	config, err = pgx.ParseConfig("host=" + host + " port=" + port + " sslmode=disable user=pgx_test startup_timeout=100ms")
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = pgx.ConnectConfig(context.Background(), config)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Contains(t, err.Error(), "startup did not complete within 100ms")
+	var connectErr *pgx.ConnectError
+	require.ErrorAs(t, err, &connectErr)
+	assert.Equal(t, pgx.ConnectPhaseAuth, connectErr.Attempts[0].Phase)
+}
+
+func TestConnectRemoteAddr(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	require.NotNil(t, conn.RemoteAddr())
+	require.NotNil(t, conn.LocalAddr())
+	assert.Equal(t, conn.PgConn().Conn().RemoteAddr().String(), conn.RemoteAddr().String())
+	assert.NotEqual(t, conn.RemoteAddr().String(), conn.LocalAddr().String())
+}
+
+func TestConnectPassThroughTypes(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.PassThroughTypes = []string{"point", "pg_catalog.circle", "no_such_type"}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	dt, ok := conn.ConnInfo().DataTypeForName("point")
+	require.True(t, ok)
+	assert.IsType(t, &pgtype.GenericBinary{}, dt.Value)
+	_, ok = conn.ConnInfo().DataTypeForName("no_such_type")
+	assert.False(t, ok)
+
+	var raw []byte
+	err := conn.QueryRow(context.Background(), "select point(1, 2)").Scan(&raw)
+	require.NoError(t, err)
+	require.Len(t, raw, 16)
+
+	var s string
+	err = conn.QueryRow(context.Background(), "select $1::point::text", raw).Scan(&s)
+	require.NoError(t, err)
+	assert.Equal(t, "(1,2)", s)
+
+	ensureConnValid(t, conn)
+}
+
+func TestParseConfigGSSEncMode(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []string{"disable", "prefer"} {
+		config, err := pgx.ParseConfig("host=localhost gssencmode=" + mode)
+		require.NoError(t, err, mode)
+		assert.NotContains(t, config.RuntimeParams, "gssencmode")
+	}
+
+	_, err := pgx.ParseConfig("host=localhost gssencmode=require")
+	require.EqualError(t, err, "gssencmode=require is not supported: GSSAPI encryption is not available")
+
+	_, err = pgx.ParseConfig("host=localhost gssencmode=bogus")
+	require.EqualError(t, err, "invalid gssencmode: bogus")
+}
+
+func TestParseConfigOptions(t *testing.T) {
+	config, err := pgx.ParseConfig("host=localhost options='-c search_path=app'")
+	require.NoError(t, err)
+	assert.Equal(t, "-c search_path=app", config.RuntimeParams["options"])
+
+	os.Setenv("PGOPTIONS", "-c statement_timeout=5s")
+	defer os.Unsetenv("PGOPTIONS")
+
+	config, err = pgx.ParseConfig("host=localhost")
+	require.NoError(t, err)
+	assert.Equal(t, "-c statement_timeout=5s", config.RuntimeParams["options"])
+
+	config, err = pgx.ParseConfig("host=localhost options='-c search_path=app'")
+	require.NoError(t, err)
+	assert.Equal(t, "-c search_path=app", config.RuntimeParams["options"])
+}
+
+func TestConnectWithOptions(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.RuntimeParams["options"] = "-c statement_timeout=4321"
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var s string
+	err := conn.QueryRow(context.Background(), "show statement_timeout").Scan(&s)
+	require.NoError(t, err)
+	assert.Equal(t, "4321ms", s)
+}
+
 func TestExec(t *testing.T) {
 	t.Parallel()
 
@@ -223,6 +1076,36 @@ func TestExec(t *testing.T) {
 	})
 }
 
+func TestExecScript(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	commandTags, err := conn.ExecScript(context.Background(), `create temporary table foo(id int4);
+insert into foo values (1), (2);
+select * from foo;
+update foo set id = id + 1 where id = 2`)
+	require.NoError(t, err)
+	require.Len(t, commandTags, 4)
+	assert.Equal(t, "CREATE TABLE", string(commandTags[0]))
+	assert.Equal(t, "INSERT 0 2", string(commandTags[1]))
+	assert.Equal(t, "SELECT 2", string(commandTags[2]))
+	assert.Equal(t, "UPDATE 1", string(commandTags[3]))
+
+	commandTags, err = conn.ExecScript(context.Background(), "insert into foo values (3); select 1 / 0; insert into foo values (4)")
+	var scriptErr *pgx.ScriptError
+	require.ErrorAs(t, err, &scriptErr)
+	assert.Equal(t, 1, scriptErr.StatementIndex)
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, "22012", pgErr.Code)
+	require.Len(t, commandTags, 1)
+	assert.Equal(t, "INSERT 0 1", string(commandTags[0]))
+
+	ensureConnValid(t, conn)
+}
+
 func TestExecFailure(t *testing.T) {
 	t.Parallel()
 
@@ -347,58 +1230,256 @@ func TestExecStatementCacheModes(t *testing.T) {
 		},
 	}
 
-	for _, tt := range tests {
-		func() {
-			config.BuildStatementCache = tt.buildStatementCache
-			conn := mustConnect(t, config)
-			defer closeConn(t, conn)
+	for _, tt := range tests {
+		func() {
+			config.BuildStatementCache = tt.buildStatementCache
+			conn := mustConnect(t, config)
+			defer closeConn(t, conn)
+
+			commandTag, err := conn.Exec(context.Background(), "select 1")
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, "SELECT 1", string(commandTag), tt.name)
+
+			commandTag, err = conn.Exec(context.Background(), "select 1 union all select 1")
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, "SELECT 2", string(commandTag), tt.name)
+
+			commandTag, err = conn.Exec(context.Background(), "select 1")
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, "SELECT 1", string(commandTag), tt.name)
+
+			ensureConnValid(t, conn)
+		}()
+	}
+}
+
+func TestExecPerQuerySimpleProtocol(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	commandTag, err := conn.Exec(ctx, "create temporary table foo(name varchar primary key);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(commandTag) != "CREATE TABLE" {
+		t.Fatalf("Unexpected results from Exec: %v", commandTag)
+	}
+
+	commandTag, err = conn.Exec(ctx,
+		"insert into foo(name) values($1);",
+		pgx.QuerySimpleProtocol(true),
+		"bar'; drop table foo;--",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(commandTag) != "INSERT 0 1" {
+		t.Fatalf("Unexpected results from Exec: %v", commandTag)
+	}
+
+}
+
+func TestConnMessageReadTimeout(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.MessageReadTimeout = 250 * time.Millisecond
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	// Waiting for a notification is not limited by the timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	_, err := conn.WaitForNotification(ctx)
+	cancel()
+	require.True(t, pgconn.Timeout(err), err)
+	require.False(t, conn.IsClosed())
+	ensureConnValid(t, conn)
+
+	_, err = conn.Exec(context.Background(), "select pg_sleep(1)")
+	var timeoutErr *pgx.MessageReadTimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, 250*time.Millisecond, timeoutErr.Timeout)
+	assert.True(t, conn.IsClosed())
+}
+
+func TestConnOnQueryStat(t *testing.T) {
+	t.Parallel()
+
+	var stats []pgx.QueryStat
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.OnQueryStat = func(stat pgx.QueryStat) {
+		stats = append(stats, stat)
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	_, err := conn.Exec(context.Background(), "select generate_series(1, 3)")
+	require.NoError(t, err)
+
+	var n int64
+	err = conn.QueryRow(context.Background(), "select 42 -- answer").Scan(&n)
+	require.NoError(t, err)
+
+	_, err = conn.Exec(context.Background(), "select 1/0")
+	require.Error(t, err)
+
+	rows, err := conn.Query(context.Background(), "select  generate_series(1, $1)", 5)
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	rows.Close()
+
+	require.Len(t, stats, 4)
+
+	assert.Equal(t, "Exec", stats[0].Operation)
+	assert.Equal(t, "select generate_series(1, 3)", stats[0].SQL)
+	assert.Equal(t, "select generate_series($1, $2)", stats[0].Fingerprint)
+	assert.EqualValues(t, 3, stats[0].Rows)
+	assert.NoError(t, stats[0].Err)
+	assert.Equal(t, "", stats[0].ErrorClass)
+	assert.True(t, stats[0].Duration > 0)
+
+	assert.Equal(t, "Query", stats[1].Operation)
+	assert.Equal(t, "select $1", stats[1].Fingerprint)
+	assert.EqualValues(t, 1, stats[1].Rows)
+
+	assert.Equal(t, "Exec", stats[2].Operation)
+	assert.Error(t, stats[2].Err)
+	assert.Equal(t, "22", stats[2].ErrorClass)
+
+	assert.Equal(t, "Query", stats[3].Operation)
+	assert.Equal(t, "select generate_series($2, $1)", stats[3].Fingerprint)
+	assert.EqualValues(t, 5, stats[3].Rows)
+}
+
+func TestConnDriverStats(t *testing.T) {
+	t.Parallel()
+
+	stats := &pgx.DriverStats{}
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.Stats = stats
+
+	conn := mustConnect(t, config)
+
+	_, err := conn.Prepare(context.Background(), "ps1", "select 1")
+	require.NoError(t, err)
+
+	_, err = conn.Exec(context.Background(), "listen driver_stats")
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), "notify driver_stats")
+	require.NoError(t, err)
+
+	snapshot := stats.Snapshot()
+	assert.EqualValues(t, 1, snapshot.ConnectionsOpened)
+	assert.EqualValues(t, 0, snapshot.ConnectionsClosed)
+	assert.EqualValues(t, 1, snapshot.StatementsPrepared)
+	assert.EqualValues(t, 1, snapshot.NotificationsReceived)
+	assert.True(t, snapshot.BytesRead > 0)
+	assert.True(t, snapshot.BytesWritten > 0)
+
+	closeConn(t, conn)
+	assert.EqualValues(t, 1, stats.Snapshot().ConnectionsClosed)
+
+	config.Config.Password = "wrong password"
+	config.Config.User = "pgx_stats_no_such_user"
+	_, err = pgx.ConnectConfig(context.Background(), config)
+	require.Error(t, err)
+	assert.EqualValues(t, 1, stats.Snapshot().AuthFailures)
+	assert.EqualValues(t, 1, stats.Snapshot().ConnectionsOpened)
+}
+
+func TestConnPrepareWithoutDriverStats(t *testing.T) {
+	t.Parallel()
 
-			commandTag, err := conn.Exec(context.Background(), "select 1")
-			assert.NoError(t, err, tt.name)
-			assert.Equal(t, "SELECT 1", string(commandTag), tt.name)
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	require.Nil(t, config.Stats)
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
 
-			commandTag, err = conn.Exec(context.Background(), "select 1 union all select 1")
-			assert.NoError(t, err, tt.name)
-			assert.Equal(t, "SELECT 2", string(commandTag), tt.name)
+	_, err := conn.Prepare(context.Background(), "ps1", "select 1")
+	require.NoError(t, err)
+	_, err = conn.PrepareWithParamOIDs(context.Background(), "ps2", "select $1", []uint32{pgtype.Int4OID})
+	require.NoError(t, err)
 
-			commandTag, err = conn.Exec(context.Background(), "select 1")
-			assert.NoError(t, err, tt.name)
-			assert.Equal(t, "SELECT 1", string(commandTag), tt.name)
+	ensureConnValid(t, conn)
+}
 
-			ensureConnValid(t, conn)
-		}()
-	}
+func TestConnectAuthFailureWithoutDriverStats(t *testing.T) {
+	t.Parallel()
+
+	// The server rejects the password.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+		if _, err := backend.ReceiveStartupMessage(); err != nil {
+			return
+		}
+		backend.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: "28P01", Message: "password authentication failed"})
+	}()
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	config, err := pgx.ParseConfig("host=" + host + " port=" + port + " sslmode=disable user=pgx_test password=secret")
+	require.NoError(t, err)
+	require.Nil(t, config.Stats)
+
+	_, err = pgx.ConnectConfig(context.Background(), config)
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, "28P01", pgErr.Code)
 }
 
-func TestExecPerQuerySimpleProtocol(t *testing.T) {
+func TestConnBusy(t *testing.T) {
 	t.Parallel()
 
 	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
 	defer closeConn(t, conn)
 
-	ctx, cancelFunc := context.WithCancel(context.Background())
-	defer cancelFunc()
+	rows, err := conn.Query(context.Background(), "select generate_series(1, 3)")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
 
-	commandTag, err := conn.Exec(ctx, "create temporary table foo(name varchar primary key);")
-	if err != nil {
-		t.Fatal(err)
-	}
-	if string(commandTag) != "CREATE TABLE" {
-		t.Fatalf("Unexpected results from Exec: %v", commandTag)
-	}
+	_, err = conn.Exec(context.Background(), "select 1")
+	assert.Equal(t, pgx.ErrConnBusy, err)
 
-	commandTag, err = conn.Exec(ctx,
-		"insert into foo(name) values($1);",
-		pgx.QuerySimpleProtocol(true),
-		"bar'; drop table foo;--",
-	)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if string(commandTag) != "INSERT 0 1" {
-		t.Fatalf("Unexpected results from Exec: %v", commandTag)
+	_, err = conn.Query(context.Background(), "select 1")
+	assert.Equal(t, pgx.ErrConnBusy, err)
+
+	var n int32
+	err = conn.QueryRow(context.Background(), "select 1").Scan(&n)
+	assert.Equal(t, pgx.ErrConnBusy, err)
+
+	_, err = conn.Prepare(context.Background(), "ps1", "select 1")
+	assert.Equal(t, pgx.ErrConnBusy, err)
+
+	br := conn.SendBatch(context.Background(), &pgx.Batch{})
+	assert.Equal(t, pgx.ErrConnBusy, br.Close())
+
+	// The original query is not affected.
+	var values []int32
+	for ok := true; ok; ok = rows.Next() {
+		require.NoError(t, rows.Scan(&n))
+		values = append(values, n)
 	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []int32{1, 2, 3}, values)
 
+	ensureConnValid(t, conn)
 }
 
 func TestPrepare(t *testing.T) {
@@ -453,6 +1534,92 @@ func TestPrepare(t *testing.T) {
 	}
 }
 
+func TestCloseStatementAndPortal(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	_, err := conn.Prepare(context.Background(), "ps", "select $1::varchar")
+	require.NoError(t, err)
+
+	err = conn.CloseStatement(context.Background(), "ps")
+	require.NoError(t, err)
+
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from pg_prepared_statements where name = 'ps'").Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+
+	// The name can be reused for a different statement.
+	_, err = conn.Prepare(context.Background(), "ps", "select $1::int4")
+	require.NoError(t, err)
+	var i int32
+	err = conn.QueryRow(context.Background(), "ps", 7).Scan(&i)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, i)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), "declare c cursor for select generate_series(1, 10)")
+	require.NoError(t, err)
+
+	err = conn.ClosePortal(context.Background(), "c")
+	require.NoError(t, err)
+
+	err = tx.QueryRow(context.Background(), "select count(*) from pg_cursors where name = 'c'").Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+
+	require.NoError(t, conn.ClosePortal(context.Background(), "missing"))
+	require.NoError(t, conn.CloseStatement(context.Background(), "missing"))
+
+	require.NoError(t, tx.Rollback(context.Background()))
+	ensureConnValid(t, conn)
+}
+
+func TestCloseStatementClosesConnOnTimeout(t *testing.T) {
+	t.Parallel()
+
+	// The server completes startup but never replies to the Close.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+		if _, err := backend.ReceiveStartupMessage(); err != nil {
+			return
+		}
+		backend.Send(&pgproto3.AuthenticationOk{})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		io.Copy(ioutil.Discard, conn)
+	}()
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	config, err := pgx.ParseConfig("host=" + host + " port=" + port + " sslmode=disable user=pgx_test")
+	require.NoError(t, err)
+	conn, err := pgx.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = conn.CloseStatement(ctx, "ps")
+	require.Error(t, err)
+
+	// The replies to the Close and Sync may still arrive so the connection must not be used again.
+	assert.True(t, conn.IsClosed())
+}
+
 func TestPrepareBadSQLFailure(t *testing.T) {
 	t.Parallel()
 
@@ -466,6 +1633,34 @@ func TestPrepareBadSQLFailure(t *testing.T) {
 	ensureConnValid(t, conn)
 }
 
+func TestPrepareWithParamOIDs(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	sd, err := conn.PrepareWithParamOIDs(context.Background(), "ps_param_oids", "select $1, $2::text", []uint32{pgtype.Int8OID})
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{pgtype.Int8OID, pgtype.TextOID}, sd.ParamOIDs)
+	require.Len(t, sd.Fields, 2)
+	assert.Equal(t, uint32(pgtype.Int8OID), sd.Fields[0].DataTypeOID)
+
+	var n int64
+	var s string
+	err = conn.QueryRow(context.Background(), "ps_param_oids", 42, "foo").Scan(&n, &s)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+	assert.Equal(t, "foo", s)
+
+	_, err = conn.PrepareWithParamOIDs(context.Background(), "ps_param_oids", "select $1, $2::text", []uint32{pgtype.Int8OID, 0})
+	require.NoError(t, err)
+
+	_, err = conn.PrepareWithParamOIDs(context.Background(), "ps_param_oids", "select $1, $2::text", []uint32{pgtype.Int4OID})
+	require.Error(t, err)
+
+	ensureConnValid(t, conn)
+}
+
 func TestPrepareIdempotency(t *testing.T) {
 	t.Parallel()
 
@@ -494,6 +1689,157 @@ func TestPrepareIdempotency(t *testing.T) {
 		t.Fatalf("Prepare statement with same name but different SQL should have failed but it didn't")
 		return
 	}
+	require.EqualError(t, err, `prepared statement "test" already exists with different sql: select 42::integer`)
+}
+
+func TestConnPreparedStatements(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	ctx := context.Background()
+
+	assert.Empty(t, conn.PreparedStatements())
+
+	_, err := conn.Prepare(ctx, "ps1", "select $1::int4, 'foo'::text")
+	require.NoError(t, err)
+
+	statements := conn.PreparedStatements()
+	require.Len(t, statements, 1)
+	sd := statements["ps1"]
+	require.NotNil(t, sd)
+	assert.Equal(t, "select $1::int4, 'foo'::text", sd.SQL)
+	assert.Equal(t, []uint32{pgtype.Int4OID}, sd.ParamOIDs)
+	require.Len(t, sd.Fields, 2)
+	assert.EqualValues(t, pgtype.Int4OID, sd.Fields[0].DataTypeOID)
+	assert.EqualValues(t, pgtype.TextOID, sd.Fields[1].DataTypeOID)
+
+	require.NoError(t, conn.Deallocate(ctx, "ps1"))
+	assert.Empty(t, conn.PreparedStatements())
+}
+
+func TestPrepareMaxPreparedStatements(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.MaxPreparedStatements = 2
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	ctx := context.Background()
+
+	preparedNames := func() []string {
+		rows, err := conn.Query(ctx, "select name from pg_prepared_statements order by name", pgx.QuerySimpleProtocol(true))
+		require.NoError(t, err)
+		var names []string
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		require.NoError(t, rows.Err())
+		return names
+	}
+
+	for _, name := range []string{"ps1", "ps2", "ps3"} {
+		_, err := conn.Prepare(ctx, name, "select $1::text || '"+name+"'")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"ps2", "ps3"}, preparedNames())
+
+	// ps1 was evicted but is prepared again when used. ps2 is now the least recently used.
+	var s string
+	err := conn.QueryRow(ctx, "ps1", "a").Scan(&s)
+	require.NoError(t, err)
+	assert.Equal(t, "aps1", s)
+	assert.Equal(t, []string{"ps1", "ps3"}, preparedNames())
+
+	_, err = conn.Exec(ctx, "ps2", "b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ps1", "ps2"}, preparedNames())
+
+	require.NoError(t, conn.Deallocate(ctx, "ps3"))
+	require.NoError(t, conn.Deallocate(ctx, "ps2"))
+	assert.Equal(t, []string{"ps1"}, preparedNames())
+
+	ensureConnValid(t, conn)
+}
+
+func TestPrepareWithParamOIDsEvictedKeepsParamOIDs(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.MaxPreparedStatements = 1
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	ctx := context.Background()
+
+	_, err := conn.PrepareWithParamOIDs(ctx, "typed", "select $1", []uint32{pgtype.Int8OID})
+	require.NoError(t, err)
+	_, err = conn.Prepare(ctx, "other", "select 1")
+	require.NoError(t, err)
+
+	// typed was evicted. It must be prepared again with the parameter type it was given rather than unknown.
+	var v interface{}
+	err = conn.QueryRow(ctx, "typed", int64(42)).Scan(&v)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+
+	var paramTypes string
+	err = conn.QueryRow(ctx, "select parameter_types::text from pg_prepared_statements where name = 'typed'", pgx.QuerySimpleProtocol(true)).Scan(&paramTypes)
+	require.NoError(t, err)
+	assert.Equal(t, "{bigint}", paramTypes)
+
+	ensureConnValid(t, conn)
+}
+
+func TestPreparedStatementInvalidation(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	ctx := context.Background()
+
+	_, err := conn.Exec(ctx, "create temporary table prepared_invalidation (id int primary key, f1 int, f2 int)")
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, "insert into prepared_invalidation (id, f1, f2) values (1, 2, 3)")
+	require.NoError(t, err)
+
+	_, err = conn.Prepare(ctx, "get", "select * from prepared_invalidation where id = $1")
+	require.NoError(t, err)
+
+	_, err = conn.Exec(ctx, "alter table prepared_invalidation drop column f1")
+	require.NoError(t, err)
+
+	// Inside a transaction the error is returned as the transaction is aborted.
+	tx, err := conn.Begin(ctx)
+	require.NoError(t, err)
+	var id, f2 int32
+	err = tx.QueryRow(ctx, "get", 1).Scan(&id, &f2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cached plan must not change result type")
+	require.NoError(t, tx.Rollback(ctx))
+
+	// The statement was invalidated and is prepared again.
+	err = conn.QueryRow(ctx, "get", 1).Scan(&id, &f2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, id)
+	assert.EqualValues(t, 3, f2)
+
+	// Outside of a transaction the statement is prepared again and retried without an error.
+	_, err = conn.Exec(ctx, "alter table prepared_invalidation add column f3 int")
+	require.NoError(t, err)
+	var f3 *int32
+	err = conn.QueryRow(ctx, "get", 1).Scan(&id, &f2, &f3)
+	require.NoError(t, err)
+	assert.Nil(t, f3)
+
+	ensureConnValid(t, conn)
 }
 
 func TestListenNotify(t *testing.T) {
@@ -793,6 +2139,43 @@ func TestLogPassesContext(t *testing.T) {
 	}
 }
 
+func TestContextLogFields(t *testing.T) {
+	t.Parallel()
+
+	type requestIDKey struct{}
+
+	l1 := &testLogger{}
+	var stats []pgx.QueryStat
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.Logger = l1
+	config.ContextLogFields = func(ctx context.Context) map[string]interface{} {
+		if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return map[string]interface{}{"request_id": requestID, "sql": "not overwritten"}
+		}
+		return nil
+	}
+	config.OnQueryStat = func(stat pgx.QueryStat) {
+		stats = append(stats, stat)
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	l1.logs = l1.logs[0:0] // Clear logs written when establishing connection
+	stats = stats[0:0]
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "abc123")
+	_, err := conn.Exec(ctx, ";")
+	require.NoError(t, err)
+
+	require.Len(t, l1.logs, 1)
+	assert.Equal(t, "abc123", l1.logs[0].data["request_id"])
+	assert.Equal(t, ";", l1.logs[0].data["sql"])
+
+	require.Len(t, stats, 1)
+	assert.Equal(t, "abc123", stats[0].Fields["request_id"])
+}
+
 func TestIdentifierSanitize(t *testing.T) {
 	t.Parallel()
 
@@ -956,33 +2339,22 @@ func TestStmtCacheInvalidationConn(t *testing.T) {
 	_, err = conn.Exec(ctx, "ALTER TABLE drop_cols DROP COLUMN f1")
 	require.NoError(t, err)
 
-	// We must get an error the first time we try to re-execute a bad statement.
-	// It is up to the application to determine if it wants to try again. We punt to
-	// the application because there is no clear recovery path in the case of failed transactions
-	// or batch operations and because automatic retry is tricky and we don't want to get
-	// it wrong at such an importaint layer of the stack.
+	// Outside of a transaction the statement is prepared again and the query is retried transparently.
+	var id, f2 int32
 	rows, err = conn.Query(ctx, getSQL, 1)
 	require.NoError(t, err)
-	rows.Next()
-	nextErr := rows.Err()
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&id, &f2))
 	rows.Close()
-	for _, err := range []error{nextErr, rows.Err()} {
-		if err == nil {
-			t.Fatal("expected InvalidCachedStatementPlanError: no error")
-		}
-		if !strings.Contains(err.Error(), "cached plan must not change result type") {
-			t.Fatalf("expected InvalidCachedStatementPlanError, got: %s", err.Error())
-		}
-	}
+	require.NoError(t, rows.Err())
+	assert.EqualValues(t, 1, id)
+	assert.EqualValues(t, 2, f2)
 
-	// On retry, the statement should have been flushed from the cache.
-	rows, err = conn.Query(ctx, getSQL, 1)
+	// Exec is retried in the same way.
+	_, err = conn.Exec(ctx, "ALTER TABLE drop_cols ADD COLUMN f3 int")
 	require.NoError(t, err)
-	rows.Next()
-	err = rows.Err()
+	_, err = conn.Exec(ctx, getSQL, 1)
 	require.NoError(t, err)
-	rows.Close()
-	require.NoError(t, rows.Err())
 
 	ensureConnValid(t, conn)
 }
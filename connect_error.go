@@ -0,0 +1,201 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+// ConnectPhase is how far a connection attempt got before it failed.
+type ConnectPhase int
+
+const (
+	ConnectPhaseLookup ConnectPhase = iota // resolving the host
+	ConnectPhaseDial                       // opening the network connection
+	ConnectPhaseTLS                        // negotiating TLS
+	ConnectPhaseAuth                       // sending the startup message and authenticating
+)
+
+func (p ConnectPhase) String() string {
+	switch p {
+	case ConnectPhaseLookup:
+		return "lookup"
+	case ConnectPhaseDial:
+		return "dial"
+	case ConnectPhaseTLS:
+		return "tls"
+	case ConnectPhaseAuth:
+		return "auth"
+	default:
+		return fmt.Sprintf("ConnectPhase(%d)", int(p))
+	}
+}
+
+// ConnectAttempt is a failed attempt to connect to one address.
+type ConnectAttempt struct {
+	Host  string // host from the config or fallback
+	Addr  string // address that was dialed, or Host if the lookup failed
+	Phase ConnectPhase
+	Err   error
+}
+
+// ConnectError occurs when a connection could not be established with any of the hosts and fallbacks of a config. It
+// lists every address that was attempted in order. Unwrap returns the error of the last attempt.
+type ConnectError struct {
+	Attempts []ConnectAttempt
+}
+
+func (e *ConnectError) Error() string {
+	if len(e.Attempts) == 1 {
+		return e.Attempts[0].Err.Error()
+	}
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "failed to connect to any of %d addresses", len(e.Attempts))
+	for i, a := range e.Attempts {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString("; ")
+		}
+		fmt.Fprintf(sb, "%s (%v): %v", a.Addr, a.Phase, a.Err)
+	}
+	return sb.String()
+}
+
+func (e *ConnectError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// connectOptions are the settings of a ConnConfig used by connectAttempts.
+type connectOptions struct {
+	// startupTimeout, if not 0, is the time the startup of each attempt has after it is dialed.
+	startupTimeout time.Duration
+
+	// prompt, if not nil, provides the password when the server requests one and config has none.
+	prompt *passwordPrompt
+
+	// newGSS, if not nil, creates the context for GSSAPI and SSPI authentication with the service principal name
+	// returned by gssSPN for the host.
+	newGSS func() (GSS, error)
+	gssSPN func(host string) string
+
+	// socketName, if not empty, replaces the .s.PGSQL.<port> file name of unix domain sockets.
+	socketName string
+}
+
+// connectAttempts connects to the host and fallbacks of config in order like pgconn.ConnectConfig, but connects to each
+// address separately so the failure of each attempt can be reported in a *ConnectError.
+func connectAttempts(ctx context.Context, config *pgconn.Config, opts connectOptions) (*pgconn.PgConn, error) {
+	startupTimeout, prompt := opts.startupTimeout, opts.prompt
+
+	if config.ConnectTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.ConnectTimeout)
+		defer cancel()
+	}
+
+	fallbacks := append([]*pgconn.FallbackConfig{{Host: config.Host, Port: config.Port, TLSConfig: config.TLSConfig}}, config.Fallbacks...)
+
+	var attempts []ConnectAttempt
+	for _, fb := range fallbacks {
+		addrs := []string{fb.Host}
+		// Unix sockets are not resolved.
+		if !isSocketHost(fb.Host) {
+			var err error
+			addrs, err = config.LookupFunc(ctx, fb.Host)
+			if err == nil && len(addrs) == 0 {
+				err = errors.New("ip addr wasn't found")
+			}
+			if err != nil {
+				attempts = append(attempts, ConnectAttempt{Host: fb.Host, Addr: fb.Host, Phase: ConnectPhaseLookup, Err: err})
+				if ctx.Err() != nil {
+					break
+				}
+				continue
+			}
+		}
+
+		for _, addr := range addrs {
+			socketNetwork, address := socketAddress(addr, fb.Port, opts.socketName)
+			attempt := ConnectAttempt{Host: fb.Host, Addr: address, Phase: ConnectPhaseDial}
+			var startupDeadline time.Time
+
+			attemptConfig := *config
+			attemptConfig.Host = addr
+			attemptConfig.Port = fb.Port
+			attemptConfig.TLSConfig = fb.TLSConfig
+			attemptConfig.Fallbacks = nil
+			attemptConfig.ConnectTimeout = 0
+			attemptConfig.LookupFunc = SkipLookup
+			attemptConfig.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				// pgconn does not know abstract namespace sockets or socket names other than .s.PGSQL.<port>.
+				if socketNetwork == "unix" {
+					network, addr = socketNetwork, address
+				}
+				conn, err := config.DialFunc(ctx, network, addr)
+				if err == nil {
+					if startupTimeout > 0 {
+						startupDeadline = time.Now().Add(startupTimeout)
+						conn.SetDeadline(startupDeadline)
+					}
+					if fb.TLSConfig != nil {
+						attempt.Phase = ConnectPhaseTLS
+					} else {
+						attempt.Phase = ConnectPhaseAuth
+					}
+				}
+				return conn, err
+			}
+			attemptConfig.BuildFrontend = func(r io.Reader, w io.Writer) pgconn.Frontend {
+				attempt.Phase = ConnectPhaseAuth
+				buildFrontend := config.BuildFrontend
+				if opts.newGSS != nil {
+					buildFrontend = gssBuildFrontend(buildFrontend, opts.newGSS, opts.gssSPN(fb.Host))
+				}
+				frontend := buildFrontend(r, w)
+				if prompt != nil && attemptConfig.Password == "" {
+					frontend = &passwordPromptFrontend{Frontend: frontend, ctx: ctx, config: &attemptConfig, prompt: prompt}
+				}
+				return frontend
+			}
+
+			pgConn, err := pgconn.ConnectConfig(ctx, &attemptConfig)
+			if err == nil {
+				if !startupDeadline.IsZero() {
+					pgConn.Conn().SetDeadline(time.Time{})
+				}
+				return pgConn, nil
+			}
+
+			var netErr net.Error
+			if !startupDeadline.IsZero() && ctx.Err() == nil && errors.As(err, &netErr) && netErr.Timeout() && !time.Now().Before(startupDeadline) {
+				err = fmt.Errorf("startup did not complete within %v: %w", startupTimeout, err)
+			}
+
+			attempt.Err = err
+			attempts = append(attempts, attempt)
+
+			// Other hosts would fail the same way if the password could not be obtained.
+			if prompt != nil && prompt.err != nil {
+				return nil, &ConnectError{Attempts: attempts}
+			}
+
+			// As with pgconn, an invalid password or a database that does not exist is not retried on other hosts.
+			if code := pgErrorCode(err); code == "28P01" || code == "28000" || ctx.Err() != nil {
+				return nil, &ConnectError{Attempts: attempts}
+			}
+		}
+	}
+
+	return nil, &ConnectError{Attempts: attempts}
+}
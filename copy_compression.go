@@ -0,0 +1,103 @@
+package pgx
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+// CopyCompression compresses and decompresses COPY data for CopyToCompressed and CopyFromCompressed. GzipCompression is
+// provided. Other formats such as zstd can be used by implementing CopyCompression with a third party package.
+type CopyCompression interface {
+	// NewWriter returns a writer that compresses data written to it and writes it to w. It is closed when all data has
+	// been written.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+
+	// NewReader returns a reader that decompresses the data read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCompression is a CopyCompression that uses the gzip format.
+type GzipCompression struct {
+	// Level is the compression level as defined by compress/gzip. 0 uses gzip.DefaultCompression.
+	Level int
+}
+
+func (gc GzipCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := gc.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+func (gc GzipCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// CopyToCompressed executes the copy command sql and copies the results to w compressed with compression. The
+// compressed stream is complete when it returns without error.
+func (c *Conn) CopyToCompressed(ctx context.Context, w io.Writer, sql string, compression CopyCompression) (pgconn.CommandTag, error) {
+	cw, err := compression.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+
+	commandTag, err := c.CopyToWithProgress(ctx, cw, sql, nil)
+	closeErr := cw.Close()
+	if err != nil {
+		return commandTag, err
+	}
+	return commandTag, closeErr
+}
+
+// CopyFromCompressed executes the copy command sql and copies the data read from r after decompressing it with
+// compression. The data must be in the format expected by sql such as the text or csv format of COPY FROM STDIN.
+func (c *Conn) CopyFromCompressed(ctx context.Context, r io.Reader, sql string, compression CopyCompression) (pgconn.CommandTag, error) {
+	if c.pgConn.IsBusy() {
+		return nil, ErrConnBusy
+	}
+
+	poolWait := c.takePoolWait()
+
+	cr, err := compression.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer cr.Close()
+
+	startTime := time.Now()
+
+	// The server sends nothing while the data is being copied so the message read timeout must not apply.
+	if c.readTimeoutConn != nil {
+		c.readTimeoutConn.disable()
+		defer c.readTimeoutConn.enable()
+	}
+
+	commandTag, err := c.pgConn.CopyFrom(ctx, cr, sql)
+
+	rowsAffected := commandTag.RowsAffected()
+	if c.config.OnQueryStat != nil {
+		c.reportQueryStat(ctx, "CopyFrom", sql, startTime, rowsAffected, err, poolWait)
+	}
+	if err == nil {
+		if c.shouldLog(LogLevelInfo) {
+			endTime := time.Now()
+			c.log(ctx, LogLevelInfo, "CopyFromCompressed", addPoolWaitLogFields(map[string]interface{}{"sql": sql, "time": endTime.Sub(startTime), "rowCount": rowsAffected}, poolWait))
+		}
+	} else if c.shouldLog(LogLevelError) {
+		data := addPoolWaitLogFields(map[string]interface{}{"err": err, "sql": sql}, poolWait)
+		if row, column, ok := CopyFromErrorRow(err); ok {
+			data["row"] = row
+			if column != "" {
+				data["column"] = column
+			}
+		}
+		c.log(ctx, LogLevelError, "CopyFromCompressed", data)
+	}
+
+	return commandTag, err
+}
@@ -3,8 +3,12 @@ package pgx
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgconn"
@@ -86,9 +90,21 @@ type copyFrom struct {
 	columnNames   []string
 	rowSrc        CopyFromSource
 	readerErrChan chan error
+	progress      *copyProgress
+	rowIdx        int64 // index of the current row read from rowSrc
 }
 
 func (ct *copyFrom) run(ctx context.Context) (int64, error) {
+	poolWait := ct.conn.takePoolWait()
+
+	if ct.conn.pgConn.IsBusy() {
+		return 0, ErrConnBusy
+	}
+
+	if ct.conn.config.TextOnly {
+		return 0, ErrTextOnly
+	}
+
 	quotedTableName := ct.tableName.Sanitize()
 	cbuf := &bytes.Buffer{}
 	for i, cn := range ct.columnNames {
@@ -137,6 +153,12 @@ func (ct *copyFrom) run(ctx context.Context) (int64, error) {
 					w.Close()
 					return
 				}
+
+				err = ct.progress.chunk(int64(len(buf)))
+				if err != nil {
+					w.CloseWithError(err)
+					return
+				}
 			}
 
 			buf = buf[:0]
@@ -147,19 +169,37 @@ func (ct *copyFrom) run(ctx context.Context) (int64, error) {
 
 	startTime := time.Now()
 
-	commandTag, err := ct.conn.pgConn.CopyFrom(ctx, r, fmt.Sprintf("copy %s ( %s ) from stdin binary;", quotedTableName, quotedColumnNames))
+	// The server sends nothing while the data is being copied so the message read timeout must not apply.
+	if ct.conn.readTimeoutConn != nil {
+		ct.conn.readTimeoutConn.disable()
+		defer ct.conn.readTimeoutConn.enable()
+	}
+
+	copySQL := fmt.Sprintf("copy %s ( %s ) from stdin binary;", quotedTableName, quotedColumnNames)
+	commandTag, err := ct.conn.pgConn.CopyFrom(ctx, r, copySQL)
 
 	r.Close()
 	<-doneChan
 
 	rowsAffected := commandTag.RowsAffected()
+	err = ct.progress.done(rowsAffected, err)
+	if ct.conn.config.OnQueryStat != nil {
+		ct.conn.reportQueryStat(ctx, "CopyFrom", copySQL, startTime, rowsAffected, err, poolWait)
+	}
 	if err == nil {
 		if ct.conn.shouldLog(LogLevelInfo) {
 			endTime := time.Now()
-			ct.conn.log(ctx, LogLevelInfo, "CopyFrom", map[string]interface{}{"tableName": ct.tableName, "columnNames": ct.columnNames, "time": endTime.Sub(startTime), "rowCount": rowsAffected})
+			ct.conn.log(ctx, LogLevelInfo, "CopyFrom", addPoolWaitLogFields(map[string]interface{}{"tableName": ct.tableName, "columnNames": ct.columnNames, "time": endTime.Sub(startTime), "rowCount": rowsAffected}, poolWait))
 		}
 	} else if ct.conn.shouldLog(LogLevelError) {
-		ct.conn.log(ctx, LogLevelError, "CopyFrom", map[string]interface{}{"err": err, "tableName": ct.tableName, "columnNames": ct.columnNames})
+		data := addPoolWaitLogFields(map[string]interface{}{"err": err, "tableName": ct.tableName, "columnNames": ct.columnNames}, poolWait)
+		if row, column, ok := CopyFromErrorRow(err); ok {
+			data["row"] = row
+			if column != "" {
+				data["column"] = column
+			}
+		}
+		ct.conn.log(ctx, LogLevelError, "CopyFrom", data)
 	}
 
 	return rowsAffected, err
@@ -168,22 +208,28 @@ func (ct *copyFrom) run(ctx context.Context) (int64, error) {
 func (ct *copyFrom) buildCopyBuf(buf []byte, sd *pgconn.StatementDescription) (bool, []byte, error) {
 
 	for ct.rowSrc.Next() {
+		ct.rowIdx++
 		values, err := ct.rowSrc.Values()
 		if err != nil {
 			return false, nil, err
 		}
 		if len(values) != len(ct.columnNames) {
-			return false, nil, fmt.Errorf("expected %d values, got %d values", len(ct.columnNames), len(values))
+			return false, nil, fmt.Errorf("row %d: expected %d values, got %d values", ct.rowIdx-1, len(ct.columnNames), len(values))
 		}
 
 		buf = pgio.AppendInt16(buf, int16(len(ct.columnNames)))
 		for i, val := range values {
-			buf, err = encodePreparedStatementArgument(ct.conn.connInfo, buf, sd.Fields[i].DataTypeOID, val)
+			buf, err = encodePreparedStatementArgument(ct.conn.connInfo, buf, sd.Fields[i].DataTypeOID, val, ct.conn.config.StrictTimePrecision)
 			if err != nil {
-				return false, nil, err
+				return false, nil, fmt.Errorf("row %d column %s: %w", ct.rowIdx-1, ct.columnNames[i], err)
 			}
 		}
 
+		err = ct.progress.row(int64(len(buf)))
+		if err != nil {
+			return false, nil, err
+		}
+
 		if len(buf) > 65536 {
 			return true, buf, nil
 		}
@@ -192,12 +238,47 @@ func (ct *copyFrom) buildCopyBuf(buf []byte, sd *pgconn.StatementDescription) (b
 	return false, buf, nil
 }
 
+var copyWhereRegexp = regexp.MustCompile(`^COPY .*, line (\d+)(?:, column ([^:]*))?`)
+
+// CopyFromErrorRow returns the location of the row that caused a CopyFrom to fail with err. row is the zero based
+// index of the row in the CopyFromSource. column is the name of the column with the invalid value if the server
+// reported it. ok is false if err does not identify a row.
+//
+// The location is taken from the context (Where) of the *pgconn.PgError reported by the server. It is only available
+// for errors detected while the server processes a row such as invalid values and constraint violations checked per
+// row. Errors detected when the copy completes such as deferred constraints do not identify a row. The context is
+// translated according to lc_messages of the server like the message, so ok is always false unless lc_messages is
+// English or C.
+func CopyFromErrorRow(err error) (row int64, column string, ok bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return 0, "", false
+	}
+
+	for _, line := range strings.Split(pgErr.Where, "\n") {
+		match := copyWhereRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		n, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || n < 1 {
+			return 0, "", false
+		}
+		return n - 1, match[2], true
+	}
+
+	return 0, "", false
+}
+
 // CopyFrom uses the PostgreSQL copy protocol to perform bulk data insertion.
 // It returns the number of rows copied and an error.
 //
 // CopyFrom requires all values use the binary format. Almost all types
 // implemented by pgx use the binary format by default. Types implementing
 // Encoder can only be used if they encode to the binary format.
+//
+// If the server rejects a row, CopyFromErrorRow returns the index of the row in rowSrc.
 func (c *Conn) CopyFrom(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource) (int64, error) {
 	ct := &copyFrom{
 		conn:          c,
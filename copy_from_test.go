@@ -1,8 +1,13 @@
 package pgx_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
@@ -604,3 +609,224 @@ func TestConnCopyFromCopyFromSourceErrorEnd(t *testing.T) {
 
 	ensureConnValid(t, conn)
 }
+
+func TestConnCopyFromWithProgress(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(a int8)`)
+
+	inputRows := make([][]interface{}, 100)
+	for i := range inputRows {
+		inputRows[i] = []interface{}{int64(i)}
+	}
+
+	var reports []pgx.CopyProgress
+	progress := &pgx.CopyProgressReporter{
+		EveryRows: 10,
+		OnProgress: func(p pgx.CopyProgress) error {
+			reports = append(reports, p)
+			return nil
+		},
+	}
+
+	copyCount, err := conn.CopyFromWithProgress(context.Background(), pgx.Identifier{"foo"}, []string{"a"}, pgx.CopyFromRows(inputRows), progress)
+	require.NoError(t, err)
+	require.EqualValues(t, len(inputRows), copyCount)
+
+	require.Len(t, reports, 11)
+	for i, p := range reports[:10] {
+		require.EqualValues(t, (i+1)*10, p.Rows)
+		require.False(t, p.Done)
+	}
+	require.EqualValues(t, 100, reports[10].Rows)
+	require.True(t, reports[10].Done)
+	require.True(t, reports[10].Bytes > reports[0].Bytes)
+
+	errAbort := errors.New("abort")
+	progress.OnProgress = func(p pgx.CopyProgress) error {
+		if p.Rows >= 50 {
+			return errAbort
+		}
+		return nil
+	}
+
+	copyCount, err = conn.CopyFromWithProgress(context.Background(), pgx.Identifier{"foo"}, []string{"a"}, pgx.CopyFromRows(inputRows), progress)
+	require.Equal(t, errAbort, err)
+	require.EqualValues(t, 0, copyCount)
+
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&n)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, n)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyToWithProgress(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	var reports []pgx.CopyProgress
+	progress := &pgx.CopyProgressReporter{
+		EveryRows: 25,
+		OnProgress: func(p pgx.CopyProgress) error {
+			reports = append(reports, p)
+			return nil
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	commandTag, err := conn.CopyToWithProgress(context.Background(), buf, "copy (select generate_series(1, 100)) to stdout", progress)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, commandTag.RowsAffected())
+
+	require.Len(t, reports, 5)
+	require.EqualValues(t, 25, reports[0].Rows)
+	require.EqualValues(t, 100, reports[4].Rows)
+	require.EqualValues(t, buf.Len(), reports[4].Bytes)
+	require.True(t, reports[4].Done)
+}
+
+func TestConnCopyCompressed(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	buf := &bytes.Buffer{}
+	commandTag, err := conn.CopyToCompressed(context.Background(), buf, "copy (select n, 'row ' || n from generate_series(1, 100) n) to stdout", pgx.GzipCompression{})
+	require.NoError(t, err)
+	require.EqualValues(t, 100, commandTag.RowsAffected())
+
+	zr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(data, []byte("1\trow 1\n2\trow 2\n")))
+
+	mustExec(t, conn, "create temporary table foo(a int4, b text)")
+	commandTag, err = conn.CopyFromCompressed(context.Background(), buf, "copy foo from stdin", pgx.GzipCompression{})
+	require.NoError(t, err)
+	require.EqualValues(t, 100, commandTag.RowsAffected())
+
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo where b = 'row ' || a").Scan(&n)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, n)
+
+	_, err = conn.CopyFromCompressed(context.Background(), bytes.NewReader([]byte("not gzip")), "copy foo from stdin", pgx.GzipCompression{})
+	require.ErrorIs(t, err, gzip.ErrHeader)
+
+	ensureConnValid(t, conn)
+}
+
+// slowReader waits before its second read.
+type slowReader struct {
+	r     io.Reader
+	reads int
+	delay time.Duration
+}
+
+func (sr *slowReader) Read(p []byte) (int, error) {
+	sr.reads++
+	if sr.reads == 2 {
+		time.Sleep(sr.delay)
+	}
+	return sr.r.Read(p)
+}
+
+func TestConnCopyFromCompressedMessageReadTimeout(t *testing.T) {
+	t.Parallel()
+
+	var stats []pgx.QueryStat
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.MessageReadTimeout = 100 * time.Millisecond
+	config.OnQueryStat = func(stat pgx.QueryStat) {
+		stats = append(stats, stat)
+	}
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	buf := &bytes.Buffer{}
+	_, err := conn.CopyToCompressed(context.Background(), buf, "copy (select n, md5(n::text) from generate_series(1, 10000) n) to stdout", pgx.GzipCompression{})
+	require.NoError(t, err)
+
+	mustExec(t, conn, "create temporary table foo(a int4, b text)")
+	stats = nil
+
+	// The server is silent for longer than the read timeout while the data is read.
+	commandTag, err := conn.CopyFromCompressed(context.Background(), &slowReader{r: buf, delay: 300 * time.Millisecond}, "copy foo from stdin", pgx.GzipCompression{})
+	require.NoError(t, err)
+	require.EqualValues(t, 10000, commandTag.RowsAffected())
+
+	require.Len(t, stats, 1)
+	require.Equal(t, "CopyFrom", stats[0].Operation)
+	require.Equal(t, "copy foo from stdin", stats[0].SQL)
+	require.EqualValues(t, 10000, stats[0].Rows)
+
+	ensureConnValid(t, conn)
+}
+
+func TestCopyFromErrorRow(t *testing.T) {
+	t.Parallel()
+
+	for i, tt := range []struct {
+		err    error
+		row    int64
+		column string
+		ok     bool
+	}{
+		{err: &pgconn.PgError{Where: "COPY foo, line 3, column b: \"abc\""}, row: 2, column: "b", ok: true},
+		{err: fmt.Errorf("wrapped: %w", &pgconn.PgError{Where: "COPY foo, line 1"}), row: 0, ok: true},
+		{err: &pgconn.PgError{Where: "PL/pgSQL function f() line 3 at RAISE\nCOPY foo, line 7"}, row: 6, ok: true},
+		{err: &pgconn.PgError{Where: ""}},
+		{err: fmt.Errorf("client error")},
+	} {
+		row, column, ok := pgx.CopyFromErrorRow(tt.err)
+		require.Equal(t, tt.ok, ok, i)
+		require.Equal(t, tt.row, row, i)
+		require.Equal(t, tt.column, column, i)
+	}
+}
+
+func TestConnCopyFromReportsFailedRow(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4,
+		b varchar not null
+	)`)
+
+	inputRows := [][]interface{}{
+		{int32(1), "abc"},
+		{int32(2), "def"},
+		{int32(3), nil},
+		{int32(4), "ghi"},
+	}
+
+	_, err := conn.CopyFrom(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b"}, pgx.CopyFromRows(inputRows))
+	require.Error(t, err)
+
+	row, _, ok := pgx.CopyFromErrorRow(err)
+	require.True(t, ok)
+	require.EqualValues(t, 2, row)
+
+	inputRows = [][]interface{}{
+		{int32(1), "abc"},
+		{"not a number", "def"},
+	}
+
+	_, err = conn.CopyFrom(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b"}, pgx.CopyFromRows(inputRows))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "row 1 column a")
+
+	ensureConnValid(t, conn)
+}
@@ -0,0 +1,170 @@
+package pgx
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+// CopyProgress is the progress of a CopyFromWithProgress or CopyToWithProgress.
+type CopyProgress struct {
+	Rows    int64 // rows sent or received so far
+	Bytes   int64 // bytes of COPY data sent or received so far
+	Elapsed time.Duration
+	Done    bool // the copy completed successfully and this is the final report
+}
+
+// CopyProgressReporter configures progress reporting for CopyFromWithProgress and CopyToWithProgress.
+type CopyProgressReporter struct {
+	// EveryRows reports progress after every EveryRows rows. 0 disables reporting by row count.
+	EveryRows int64
+
+	// EveryBytes reports progress each time another EveryBytes bytes of COPY data have been sent or received. 0 disables
+	// reporting by byte count.
+	EveryBytes int64
+
+	// OnProgress is called with the current progress. When both EveryRows and EveryBytes are 0 it is called each time a
+	// chunk of data is sent or received. It is always called a final time with Done set when the copy succeeds.
+	// Returning an error aborts the copy and the error is returned by the copy method. It must not use the connection.
+	OnProgress func(CopyProgress) error
+}
+
+// copyProgress tracks the progress of a single copy.
+type copyProgress struct {
+	reporter  *CopyProgressReporter
+	startTime time.Time
+	rows      int64
+	bytes     int64
+	nextBytes int64
+	err       error
+}
+
+func newCopyProgress(reporter *CopyProgressReporter) *copyProgress {
+	if reporter == nil || reporter.OnProgress == nil {
+		return nil
+	}
+	return &copyProgress{reporter: reporter, startTime: time.Now(), nextBytes: reporter.EveryBytes}
+}
+
+// row records a row. pending is the number of bytes buffered but not yet counted.
+func (cp *copyProgress) row(pending int64) error {
+	if cp == nil {
+		return nil
+	}
+	cp.rows++
+
+	report := cp.reporter.EveryRows > 0 && cp.rows%cp.reporter.EveryRows == 0
+	if cp.reporter.EveryBytes > 0 {
+		bytes := cp.bytes + pending
+		if bytes >= cp.nextBytes {
+			report = true
+			cp.nextBytes = bytes - bytes%cp.reporter.EveryBytes + cp.reporter.EveryBytes
+		}
+	}
+	if !report {
+		return nil
+	}
+
+	return cp.report(pending, false)
+}
+
+// chunk records that n bytes were sent or received.
+func (cp *copyProgress) chunk(n int64) error {
+	if cp == nil {
+		return nil
+	}
+	cp.bytes += n
+
+	if cp.reporter.EveryRows > 0 || cp.reporter.EveryBytes > 0 {
+		return nil
+	}
+	return cp.report(0, false)
+}
+
+func (cp *copyProgress) report(pending int64, done bool) error {
+	err := cp.reporter.OnProgress(CopyProgress{
+		Rows:    cp.rows,
+		Bytes:   cp.bytes + pending,
+		Elapsed: time.Since(cp.startTime),
+		Done:    done,
+	})
+	if err != nil {
+		cp.err = err
+	}
+	return err
+}
+
+// done makes the final report after a copy of rows rows completed with err. It returns the error the copy method
+// should return.
+func (cp *copyProgress) done(rows int64, err error) error {
+	if cp == nil {
+		return err
+	}
+	if cp.err != nil {
+		return cp.err
+	}
+	if err != nil {
+		return err
+	}
+
+	cp.rows = rows
+	return cp.report(0, true)
+}
+
+// CopyFromWithProgress is the same as CopyFrom, but reports progress as configured by progress.
+func (c *Conn) CopyFromWithProgress(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource, progress *CopyProgressReporter) (int64, error) {
+	ct := &copyFrom{
+		conn:          c,
+		tableName:     tableName,
+		columnNames:   columnNames,
+		rowSrc:        rowSrc,
+		readerErrChan: make(chan error),
+		progress:      newCopyProgress(progress),
+	}
+
+	return ct.run(ctx)
+}
+
+// CopyToWithProgress executes the copy command sql and copies the results to w while reporting progress as configured
+// by progress. PostgreSQL sends a row at a time so rows are counted for both the text and binary formats. As with
+// pgconn.PgConn.CopyTo, the connection is closed if the copy is aborted by an error returned by OnProgress or w.
+func (c *Conn) CopyToWithProgress(ctx context.Context, w io.Writer, sql string, progress *CopyProgressReporter) (pgconn.CommandTag, error) {
+	if c.pgConn.IsBusy() {
+		return nil, ErrConnBusy
+	}
+
+	cp := newCopyProgress(progress)
+	if cp != nil {
+		w = &copyProgressWriter{w: w, progress: cp}
+	}
+
+	commandTag, err := c.pgConn.CopyTo(ctx, w, sql)
+	return commandTag, cp.done(commandTag.RowsAffected(), err)
+}
+
+type copyProgressWriter struct {
+	w        io.Writer
+	progress *copyProgress
+}
+
+func (w *copyProgressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	// Counting the bytes before the row means the row check sees the total including this row.
+	w.progress.bytes += int64(n)
+	if err := w.progress.row(0); err != nil {
+		return n, err
+	}
+	if w.progress.reporter.EveryRows == 0 && w.progress.reporter.EveryBytes == 0 {
+		if err := w.progress.report(0, false); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,88 @@
+package pgx
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DialInfo describes a connection being dialed by ConnConfig.Dial.
+type DialInfo struct {
+	Network string // "tcp" or "unix"
+	Addr    string // address to dial as returned by LookupFunc with the port added
+
+	Host     string // host in the config that Addr was resolved from
+	Port     uint16
+	Database string
+	User     string
+
+	// Config is the config of the connection. It must not be modified.
+	Config *ConnConfig
+}
+
+// SkipLookup is a LookupFunc that returns host unchanged. It is used with ConnConfig.Dial when the dialer resolves
+// hosts itself such as connectors for managed databases that dial by instance name.
+func SkipLookup(ctx context.Context, host string) ([]string, error) {
+	return []string{host}, nil
+}
+
+// dialHosts records the config host each address returned by LookupFunc was resolved from.
+type dialHosts struct {
+	mux   sync.Mutex
+	hosts map[string]string
+}
+
+func (dh *dialHosts) lookup(lookup func(context.Context, string) ([]string, error)) func(context.Context, string) ([]string, error) {
+	return func(ctx context.Context, host string) ([]string, error) {
+		addrs, err := lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		dh.mux.Lock()
+		for _, addr := range addrs {
+			dh.hosts[addr] = host
+		}
+		dh.mux.Unlock()
+
+		return addrs, nil
+	}
+}
+
+// dialInfo returns the DialInfo for dialing addr on network.
+func (dh *dialHosts) dialInfo(config *ConnConfig, network, addr string) DialInfo {
+	info := DialInfo{
+		Network:  network,
+		Addr:     addr,
+		Database: config.Database,
+		User:     config.User,
+		Config:   config,
+	}
+
+	var host, port string
+	if network == "unix" {
+		host = filepath.Dir(addr)
+		port = strings.TrimPrefix(filepath.Base(addr), ".s.PGSQL.")
+	} else {
+		var err error
+		host, port, err = net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		dh.mux.Lock()
+		if h, ok := dh.hosts[host]; ok {
+			host = h
+		}
+		dh.mux.Unlock()
+	}
+
+	info.Host = host
+	if n, err := strconv.ParseUint(port, 10, 16); err == nil {
+		info.Port = uint16(n)
+	}
+
+	return info
+}
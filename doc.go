@@ -225,6 +225,21 @@ Raw Bytes Mapping
 
 []byte passed as arguments to Query, QueryRow, and Exec are passed unmodified to PostgreSQL.
 
+Large Values
+
+Each row is received as a single message which pgconn reads into memory in full before any of its columns can be
+scanned. A row holding a bytea or text value of several gigabytes therefore needs that much memory. Values that large
+should be stored as large objects and streamed with LargeObjects, or read in pieces with substring:
+
+    var chunk []byte
+    for offset := 1; ; offset += chunkSize {
+        err := conn.QueryRow(ctx, "select substring(data from $1 for $2) from blobs where id=$3", offset, chunkSize, id).Scan(&chunk)
+        // ...
+        if len(chunk) < chunkSize {
+            break
+        }
+    }
+
 Transactions
 
 Transactions are started by calling Begin.
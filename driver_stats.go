@@ -0,0 +1,103 @@
+package pgx
+
+import (
+	"expvar"
+	"net"
+	"sync/atomic"
+)
+
+// DriverStats counts driver level events for introspection in production. A single *DriverStats may be shared by
+// many connections by setting ConnConfig.Stats. As a pgxpool.Pool shares its ConnConfig between connections, setting
+// it on the pool config aggregates the events of all connections in the pool. It is safe for concurrent use.
+type DriverStats struct {
+	connectionsOpened     int64
+	connectionsClosed     int64
+	authFailures          int64
+	bytesRead             int64
+	bytesWritten          int64
+	statementsPrepared    int64
+	notificationsReceived int64
+}
+
+// DriverStatsSnapshot is a point in time copy of the counters in DriverStats.
+type DriverStatsSnapshot struct {
+	ConnectionsOpened     int64 // connections successfully established
+	ConnectionsClosed     int64 // established connections that have been closed for any reason
+	AuthFailures          int64 // connection attempts rejected by the server with an authorization error
+	BytesRead             int64 // bytes read from the network including TLS overhead
+	BytesWritten          int64 // bytes written to the network including TLS overhead
+	StatementsPrepared    int64 // statements prepared on the server with Prepare
+	NotificationsReceived int64 // LISTEN / NOTIFY notifications received
+}
+
+// Snapshot returns the current values of the counters.
+func (s *DriverStats) Snapshot() DriverStatsSnapshot {
+	return DriverStatsSnapshot{
+		ConnectionsOpened:     atomic.LoadInt64(&s.connectionsOpened),
+		ConnectionsClosed:     atomic.LoadInt64(&s.connectionsClosed),
+		AuthFailures:          atomic.LoadInt64(&s.authFailures),
+		BytesRead:             atomic.LoadInt64(&s.bytesRead),
+		BytesWritten:          atomic.LoadInt64(&s.bytesWritten),
+		StatementsPrepared:    atomic.LoadInt64(&s.statementsPrepared),
+		NotificationsReceived: atomic.LoadInt64(&s.notificationsReceived),
+	}
+}
+
+// Publish publishes the counters with expvar under name. Like expvar.Publish it panics if name is already in use.
+func (s *DriverStats) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return s.Snapshot() }))
+}
+
+// The counting methods may be called on a nil *DriverStats. The receiver must be checked before the address of a
+// counter is taken as that dereferences it.
+
+func (s *DriverStats) authFailed() {
+	if s != nil {
+		atomic.AddInt64(&s.authFailures, 1)
+	}
+}
+
+func (s *DriverStats) statementPrepared() {
+	if s != nil {
+		atomic.AddInt64(&s.statementsPrepared, 1)
+	}
+}
+
+func (s *DriverStats) notificationReceived() {
+	if s != nil {
+		atomic.AddInt64(&s.notificationsReceived, 1)
+	}
+}
+
+// statsConn is a net.Conn that counts the bytes read and written and the closing of an established connection.
+type statsConn struct {
+	net.Conn
+	stats       *DriverStats
+	established int32
+}
+
+func (c *statsConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.stats.bytesRead, int64(n))
+	return n, err
+}
+
+func (c *statsConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.stats.bytesWritten, int64(n))
+	return n, err
+}
+
+func (c *statsConn) Close() error {
+	if atomic.CompareAndSwapInt32(&c.established, 1, 2) {
+		atomic.AddInt64(&c.stats.connectionsClosed, 1)
+	}
+	return c.Conn.Close()
+}
+
+// markEstablished records that the connection was successfully established.
+func (c *statsConn) markEstablished() {
+	if atomic.CompareAndSwapInt32(&c.established, 0, 1) {
+		atomic.AddInt64(&c.stats.connectionsOpened, 1)
+	}
+}
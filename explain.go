@@ -0,0 +1,155 @@
+package pgx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ExplainOptions are the options of an EXPLAIN run by Explain.
+type ExplainOptions struct {
+	// Analyze executes the statement and includes the actual run times and row counts. The statement is executed so
+	// data modifying statements should be explained in a transaction that is rolled back.
+	Analyze bool
+
+	// Buffers includes buffer usage. Buffer usage is only available with Analyze before PostgreSQL 13.
+	Buffers bool
+
+	// Verbose includes additional information such as output column lists and schema-qualified names.
+	Verbose bool
+
+	// Settings includes configuration parameters that differ from the default and affect planning. Requires
+	// PostgreSQL 12 or later.
+	Settings bool
+
+	// WAL includes WAL record generation. Requires Analyze and PostgreSQL 13 or later.
+	WAL bool
+}
+
+func (eo ExplainOptions) sql(sql string) string {
+	options := []string{"format json"}
+	if eo.Analyze {
+		options = append(options, "analyze")
+	}
+	if eo.Buffers {
+		options = append(options, "buffers")
+	}
+	if eo.Verbose {
+		options = append(options, "verbose")
+	}
+	if eo.Settings {
+		options = append(options, "settings")
+	}
+	if eo.WAL {
+		options = append(options, "wal")
+	}
+	return "explain (" + strings.Join(options, ", ") + ") " + sql
+}
+
+// ExplainResult is the result of Explain.
+type ExplainResult struct {
+	Plan          ExplainPlan       `json:"Plan"`
+	PlanningTime  float64           `json:"Planning Time"`  // milliseconds, only with Analyze
+	ExecutionTime float64           `json:"Execution Time"` // milliseconds, only with Analyze
+	Triggers      []ExplainTrigger  `json:"Triggers"`
+	Settings      map[string]string `json:"Settings"`
+
+	// JSON is the complete plan as returned by the server. It includes fields that are not decoded into
+	// ExplainResult.
+	JSON json.RawMessage `json:"-"`
+}
+
+// ExplainTrigger is the execution statistics of a trigger.
+type ExplainTrigger struct {
+	TriggerName string  `json:"Trigger Name"`
+	Relation    string  `json:"Relation"`
+	Time        float64 `json:"Time"`
+	Calls       int64   `json:"Calls"`
+}
+
+// ExplainPlan is a node of a query plan. Fields that do not apply to a node or were not requested are zero.
+type ExplainPlan struct {
+	NodeType           string   `json:"Node Type"`
+	ParentRelationship string   `json:"Parent Relationship"`
+	JoinType           string   `json:"Join Type"`
+	Strategy           string   `json:"Strategy"`
+	RelationName       string   `json:"Relation Name"`
+	Schema             string   `json:"Schema"`
+	Alias              string   `json:"Alias"`
+	IndexName          string   `json:"Index Name"`
+	IndexCond          string   `json:"Index Cond"`
+	Filter             string   `json:"Filter"`
+	HashCond           string   `json:"Hash Cond"`
+	MergeCond          string   `json:"Merge Cond"`
+	JoinFilter         string   `json:"Join Filter"`
+	SortKey            []string `json:"Sort Key"`
+	GroupKey           []string `json:"Group Key"`
+	Output             []string `json:"Output"`
+	ParallelAware      bool     `json:"Parallel Aware"`
+	WorkersPlanned     int64    `json:"Workers Planned"`
+	WorkersLaunched    int64    `json:"Workers Launched"`
+
+	StartupCost float64 `json:"Startup Cost"`
+	TotalCost   float64 `json:"Total Cost"`
+	PlanRows    float64 `json:"Plan Rows"`
+	PlanWidth   int64   `json:"Plan Width"`
+
+	ActualStartupTime    float64 `json:"Actual Startup Time"`
+	ActualTotalTime      float64 `json:"Actual Total Time"`
+	ActualRows           float64 `json:"Actual Rows"`
+	ActualLoops          float64 `json:"Actual Loops"`
+	RowsRemovedByFilter  float64 `json:"Rows Removed by Filter"`
+	RowsRemovedByJoin    float64 `json:"Rows Removed by Join Filter"`
+	RowsRemovedByRecheck float64 `json:"Rows Removed by Index Recheck"`
+
+	SharedHitBlocks     int64 `json:"Shared Hit Blocks"`
+	SharedReadBlocks    int64 `json:"Shared Read Blocks"`
+	SharedDirtiedBlocks int64 `json:"Shared Dirtied Blocks"`
+	SharedWrittenBlocks int64 `json:"Shared Written Blocks"`
+	LocalHitBlocks      int64 `json:"Local Hit Blocks"`
+	LocalReadBlocks     int64 `json:"Local Read Blocks"`
+	TempReadBlocks      int64 `json:"Temp Read Blocks"`
+	TempWrittenBlocks   int64 `json:"Temp Written Blocks"`
+
+	WALRecords int64 `json:"WAL Records"`
+	WALFPI     int64 `json:"WAL FPI"`
+	WALBytes   int64 `json:"WAL Bytes"`
+
+	Plans []ExplainPlan `json:"Plans"`
+}
+
+// Walk calls f for p and every node below it in depth-first order.
+func (p *ExplainPlan) Walk(f func(*ExplainPlan)) {
+	f(p)
+	for i := range p.Plans {
+		p.Plans[i].Walk(f)
+	}
+}
+
+// Explain runs EXPLAIN with options on sql and args and returns the plan. The plan is requested in the JSON format and
+// decoded into an ExplainResult.
+func (c *Conn) Explain(ctx context.Context, sql string, args []interface{}, options ExplainOptions) (*ExplainResult, error) {
+	var buf []byte
+	err := c.QueryRow(ctx, options.sql(sql), args...).Scan(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ExplainResult
+	err = json.Unmarshal(buf, &results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode plan: %w", err)
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("expected 1 plan, got %d", len(results))
+	}
+
+	result := &results[0]
+	var raw []json.RawMessage
+	if err := json.Unmarshal(buf, &raw); err == nil && len(raw) == 1 {
+		result.JSON = raw[0]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package pgx_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnExplain(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	skipCockroachDB(t, conn, "Server does not support EXPLAIN (FORMAT JSON)")
+
+	result, err := conn.Explain(context.Background(), "select n from generate_series(1, $1::int) n where n % 2 = 0", []interface{}{10}, pgx.ExplainOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Function Scan", result.Plan.NodeType)
+	assert.NotEmpty(t, result.Plan.Filter)
+	assert.Greater(t, result.Plan.TotalCost, 0.0)
+	assert.Zero(t, result.ExecutionTime)
+	assert.Contains(t, string(result.JSON), `"Node Type"`)
+
+	result, err = conn.Explain(context.Background(), "select count(*) from generate_series(1, 10) n where n % 2 = 0", nil, pgx.ExplainOptions{Analyze: true, Buffers: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Aggregate", result.Plan.NodeType)
+	var nodeTypes []string
+	result.Plan.Walk(func(p *pgx.ExplainPlan) {
+		nodeTypes = append(nodeTypes, p.NodeType)
+	})
+	assert.Equal(t, []string{"Aggregate", "Function Scan"}, nodeTypes)
+	assert.EqualValues(t, 5, result.Plan.Plans[0].ActualRows)
+	assert.EqualValues(t, 5, result.Plan.Plans[0].RowsRemovedByFilter)
+	assert.Greater(t, result.ExecutionTime, 0.0)
+
+	ensureConnValid(t, conn)
+}
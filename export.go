@@ -0,0 +1,237 @@
+package pgx
+
+import (
+	"bufio"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+)
+
+// WriteCSV writes rows to w as CSV with a header row of the column names. It reads and closes rows and returns the
+// number of data rows written. Values are formatted as PostgreSQL would in COPY ... CSV: NULL is an empty unquoted field
+// while an empty string is written as "" so the two remain distinguishable. bytea is written in hex format and
+// timestamps in RFC 3339 format.
+func WriteCSV(w io.Writer, rows Rows) (int64, error) {
+	defer rows.Close()
+
+	bw := bufio.NewWriter(w)
+	fds := rows.FieldDescriptions()
+	for i := range fds {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		writeCSVField(bw, string(fds[i].Name))
+	}
+	bw.WriteString("\r\n")
+
+	var n int64
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return n, err
+		}
+		for i, v := range values {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			if v == nil {
+				continue
+			}
+			s, err := exportText(fds[i].DataTypeOID, v)
+			if err != nil {
+				return n, fmt.Errorf("column %s: %w", fds[i].Name, err)
+			}
+			writeCSVField(bw, s)
+		}
+		bw.WriteString("\r\n")
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	return n, bw.Flush()
+}
+
+func writeCSVField(bw *bufio.Writer, s string) {
+	if s != "" && !strings.ContainsAny(s, ",\"\r\n") {
+		bw.WriteString(s)
+		return
+	}
+
+	bw.WriteByte('"')
+	bw.WriteString(strings.ReplaceAll(s, `"`, `""`))
+	bw.WriteByte('"')
+}
+
+// WriteJSON writes rows to w as a JSON array with an object per row keyed by column name in column order. It reads and
+// closes rows and returns the number of rows written. NULL is written as null, json and jsonb values are embedded as is,
+// numbers as JSON numbers except NaN and infinity which are written as strings, and bytea as base64 as encoding/json
+// does. Other values are written as strings in the same format as WriteCSV.
+func WriteJSON(w io.Writer, rows Rows) (int64, error) {
+	defer rows.Close()
+
+	fds := rows.FieldDescriptions()
+	keys := make([][]byte, len(fds))
+	for i := range fds {
+		key, err := json.Marshal(string(fds[i].Name))
+		if err != nil {
+			return 0, err
+		}
+		keys[i] = key
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteByte('[')
+
+	var n int64
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return n, err
+		}
+		if n > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteByte('{')
+		for i, v := range values {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			bw.Write(keys[i])
+			bw.WriteByte(':')
+			buf, err := exportJSON(fds[i].DataTypeOID, v)
+			if err != nil {
+				return n, fmt.Errorf("column %s: %w", fds[i].Name, err)
+			}
+			bw.Write(buf)
+		}
+		bw.WriteByte('}')
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	bw.WriteString("]\n")
+	return n, bw.Flush()
+}
+
+// exportText formats v, a non-nil value returned by Rows.Values for a column of type oid, as text.
+func exportText(oid uint32, v interface{}) (string, error) {
+	switch oid {
+	case pgtype.JSONOID, pgtype.JSONBOID:
+		buf, err := json.Marshal(v)
+		return string(buf), err
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return `\x` + hex.EncodeToString(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16]), nil
+	case pgtype.Numeric:
+		return numericText(v), nil
+	case driver.Valuer:
+		dv, err := v.Value()
+		if err != nil {
+			return "", err
+		}
+		if dv == nil {
+			return "", nil
+		}
+		return exportText(0, dv)
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+// exportJSON encodes v, a value returned by Rows.Values for a column of type oid, as JSON.
+func exportJSON(oid uint32, v interface{}) ([]byte, error) {
+	switch oid {
+	case pgtype.JSONOID, pgtype.JSONBOID:
+		return json.Marshal(v)
+	case pgtype.NumericOID:
+		if v == nil {
+			break
+		}
+		s, err := exportText(oid, v)
+		if err != nil {
+			return nil, err
+		}
+		// NaN and infinity are not valid JSON numbers.
+		if json.Valid([]byte(s)) {
+			return []byte(s), nil
+		}
+		return json.Marshal(s)
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return []byte("null"), nil
+	case bool, int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint, []byte:
+		return json.Marshal(v)
+	case float32:
+		return exportJSONFloat(float64(v))
+	case float64:
+		return exportJSONFloat(v)
+	}
+
+	s, err := exportText(oid, v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// numericText formats n in plain decimal notation as PostgreSQL does rather than the exponent notation of its text
+// encoding.
+func numericText(n pgtype.Numeric) string {
+	if n.NaN {
+		return "NaN"
+	}
+
+	digits := new(big.Int).Abs(n.Int).String()
+	var sb strings.Builder
+	if n.Int.Sign() < 0 {
+		sb.WriteByte('-')
+	}
+	if n.Exp >= 0 {
+		sb.WriteString(digits)
+		if n.Int.Sign() != 0 {
+			sb.WriteString(strings.Repeat("0", int(n.Exp)))
+		}
+		return sb.String()
+	}
+
+	scale := int(-n.Exp)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	sb.WriteString(digits[:len(digits)-scale])
+	sb.WriteByte('.')
+	sb.WriteString(digits[len(digits)-scale:])
+	return sb.String()
+}
+
+func exportJSONFloat(f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return json.Marshal(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return json.Marshal(f)
+}
@@ -0,0 +1,65 @@
+package pgx_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportTestSQL = `select n, 'a,"b"'::text as "text", ''::text as empty, null::text as "null", '\x0102'::bytea as bytes,
+  1.50::numeric as num, 'NaN'::numeric as nan, '{"k": [1]}'::jsonb as doc, true as flag
+from generate_series(1, 2) n`
+
+func TestWriteCSV(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	rows, err := conn.Query(context.Background(), exportTestSQL)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	n, err := pgx.WriteCSV(&buf, rows)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	assert.Equal(t, "n,text,empty,null,bytes,num,nan,doc,flag\r\n"+
+		`1,"a,""b""","",,\x0102,1.50,NaN,"{""k"":[1]}",true`+"\r\n"+
+		`2,"a,""b""","",,\x0102,1.50,NaN,"{""k"":[1]}",true`+"\r\n", buf.String())
+
+	ensureConnValid(t, conn)
+}
+
+func TestWriteJSON(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	rows, err := conn.Query(context.Background(), exportTestSQL)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	n, err := pgx.WriteJSON(&buf, rows)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	assert.Equal(t, `[`+
+		`{"n":1,"text":"a,\"b\"","empty":"","null":null,"bytes":"AQI=","num":1.50,"nan":"NaN","doc":{"k":[1]},"flag":true},`+
+		`{"n":2,"text":"a,\"b\"","empty":"","null":null,"bytes":"AQI=","num":1.50,"nan":"NaN","doc":{"k":[1]},"flag":true}`+
+		"]\n", buf.String())
+
+	rows, err = conn.Query(context.Background(), "select 1 where false")
+	require.NoError(t, err)
+	buf.Reset()
+	n, err = pgx.WriteJSON(&buf, rows)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+	assert.Equal(t, "[]\n", buf.String())
+
+	ensureConnValid(t, conn)
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/jackc/pgtype"
 )
@@ -13,13 +14,23 @@ type extendedQueryBuilder struct {
 	paramValueBytes []byte
 	paramFormats    []int16
 	resultFormats   []int16
+
+	// paramFormatOverrides are the formats forced by QueryParamFormats for the query being built. It is not cleared by
+	// Reset.
+	paramFormatOverrides QueryParamFormats
+
+	// strictTimePrecision is ConnConfig.StrictTimePrecision.
+	strictTimePrecision bool
 }
 
 func (eqb *extendedQueryBuilder) AppendParam(ci *pgtype.ConnInfo, oid uint32, arg interface{}) error {
-	f := chooseParameterFormatCode(ci, oid, arg)
+	f, forced := eqb.paramFormatOverrides[len(eqb.paramFormats)]
+	if !forced {
+		f = chooseParameterFormatCode(ci, oid, arg)
+	}
 	eqb.paramFormats = append(eqb.paramFormats, f)
 
-	v, err := eqb.encodeExtendedParamValue(ci, oid, f, arg)
+	v, err := eqb.encodeParamValue(ci, oid, f, forced, arg)
 	if err != nil {
 		return err
 	}
@@ -28,6 +39,35 @@ func (eqb *extendedQueryBuilder) AppendParam(ci *pgtype.ConnInfo, oid uint32, ar
 	return nil
 }
 
+// AppendUnknownParam appends arg as a parameter of an unspecified type in the text format so the server infers its
+// type.
+func (eqb *extendedQueryBuilder) AppendUnknownParam(ci *pgtype.ConnInfo, arg interface{}) error {
+	f, forced := eqb.paramFormatOverrides[len(eqb.paramFormats)]
+	if !forced {
+		f = TextFormatCode
+	}
+	eqb.paramFormats = append(eqb.paramFormats, f)
+
+	v, err := eqb.encodeParamValue(ci, 0, f, forced, arg)
+	if err != nil {
+		return err
+	}
+	eqb.paramValues = append(eqb.paramValues, v)
+
+	return nil
+}
+
+// encodeParamValue encodes arg in formatCode. A []byte whose format was forced by QueryParamFormats is already encoded
+// and is sent as is.
+func (eqb *extendedQueryBuilder) encodeParamValue(ci *pgtype.ConnInfo, oid uint32, formatCode int16, forced bool, arg interface{}) ([]byte, error) {
+	if forced {
+		if buf, ok := arg.([]byte); ok {
+			return buf, nil
+		}
+	}
+	return eqb.encodeExtendedParamValue(ci, oid, formatCode, arg)
+}
+
 func (eqb *extendedQueryBuilder) AppendResultFormat(f int16) {
 	eqb.resultFormats = append(eqb.resultFormats, f)
 }
@@ -79,6 +119,15 @@ func (eqb *extendedQueryBuilder) encodeExtendedParamValue(ci *pgtype.ConnInfo, o
 		return []byte(arg), nil
 	}
 
+	if arg, ok := arg.(time.Time); ok && formatCode == BinaryFormatCode && isTimeParamOID(oid) {
+		buf, err = encodeTimeParam(eqb.paramValueBytes, oid, arg, eqb.strictTimePrecision)
+		if err != nil {
+			return nil, err
+		}
+		eqb.paramValueBytes = buf
+		return eqb.paramValueBytes[pos:], nil
+	}
+
 	if formatCode == TextFormatCode {
 		if arg, ok := arg.(pgtype.TextEncoder); ok {
 			buf, err = arg.EncodeText(ci, eqb.paramValueBytes)
@@ -0,0 +1,234 @@
+package pgx
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nappspt/schemapgx/v4/sanitize"
+)
+
+// Fingerprint normalizes sql so statements that differ only by constant values, whitespace, or comments produce the
+// same string. String, numeric, and bit string constants are replaced with placeholders numbered after the highest
+// placeholder already in sql in the style of pg_stat_statements. Whitespace and comments are collapsed to a single
+// space. The result has a bounded number of distinct values for the statements of an application so it is suitable as
+// a metric label or cache key. It is the Fingerprint of QueryStat.
+func Fingerprint(sql string) string {
+	next := 1
+	if query, err := sanitize.NewQuery(sql); err == nil {
+		next = maxPlaceholder(query) + 1
+	}
+
+	sb := &strings.Builder{}
+	sb.Grow(len(sql))
+
+	pendingSpace := false
+	writeSpace := func() {
+		if pendingSpace && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		pendingSpace = false
+	}
+	writeConstant := func() {
+		writeSpace()
+		sb.WriteByte('$')
+		sb.WriteString(strconv.Itoa(next))
+		next++
+	}
+
+	for i := 0; i < len(sql); {
+		ch := sql[i]
+
+		switch {
+		case isSpace(ch):
+			pendingSpace = true
+			i++
+
+		case ch == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 1
+			}
+			pendingSpace = true
+
+		case ch == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			i = skipBlockComment(sql, i)
+			pendingSpace = true
+
+		case ch == '\'':
+			i = skipQuoted(sql, i, false)
+			writeConstant()
+
+		case (ch == 'e' || ch == 'E') && i+1 < len(sql) && sql[i+1] == '\'' && !precededByIdentChar(sql, i):
+			i = skipQuoted(sql, i+1, true)
+			writeConstant()
+
+		case (ch == 'b' || ch == 'B' || ch == 'x' || ch == 'X' || ch == 'n' || ch == 'N') && i+1 < len(sql) && sql[i+1] == '\'' && !precededByIdentChar(sql, i):
+			i = skipQuoted(sql, i+1, false)
+			writeConstant()
+
+		case ch == '"':
+			end := skipQuotedIdentifier(sql, i)
+			writeSpace()
+			sb.WriteString(sql[i:end])
+			i = end
+
+		case ch == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			end := i + 1
+			for end < len(sql) && isDigit(sql[end]) {
+				end++
+			}
+			writeSpace()
+			sb.WriteString(sql[i:end])
+			i = end
+
+		case ch == '$':
+			if end, ok := skipDollarQuoted(sql, i); ok {
+				i = end
+				writeConstant()
+			} else {
+				writeSpace()
+				sb.WriteByte(ch)
+				i++
+			}
+
+		case (isDigit(ch) || (ch == '.' && i+1 < len(sql) && isDigit(sql[i+1]))) && !precededByIdentChar(sql, i):
+			i = skipNumber(sql, i)
+			writeConstant()
+
+		case isIdentChar(ch):
+			end := i
+			for end < len(sql) && isIdentChar(sql[end]) {
+				end++
+			}
+			writeSpace()
+			sb.WriteString(sql[i:end])
+			i = end
+
+		default:
+			writeSpace()
+			_, size := utf8.DecodeRuneInString(sql[i:])
+			sb.WriteString(sql[i : i+size])
+			i += size
+		}
+	}
+
+	return sb.String()
+}
+
+// skipQuoted returns the position after the single quoted literal starting at start. Doubled quotes are escapes.
+// Backslash escapes are recognized when backslashEscapes is true.
+func skipQuoted(sql string, start int, backslashEscapes bool) int {
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case '\'':
+			if i+1 < len(sql) && sql[i+1] == '\'' {
+				i++
+			} else {
+				return i + 1
+			}
+		}
+	}
+	return len(sql)
+}
+
+func skipQuotedIdentifier(sql string, start int) int {
+	for i := start + 1; i < len(sql); i++ {
+		if sql[i] == '"' {
+			if i+1 < len(sql) && sql[i+1] == '"' {
+				i++
+			} else {
+				return i + 1
+			}
+		}
+	}
+	return len(sql)
+}
+
+// skipBlockComment returns the position after the possibly nested block comment starting at start.
+func skipBlockComment(sql string, start int) int {
+	depth := 0
+	for i := start; i+1 < len(sql); i++ {
+		if sql[i] == '/' && sql[i+1] == '*' {
+			depth++
+			i++
+		} else if sql[i] == '*' && sql[i+1] == '/' {
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(sql)
+}
+
+// skipDollarQuoted returns the position after the dollar quoted string starting at start. ok is false if start does not
+// begin a dollar quote tag.
+func skipDollarQuoted(sql string, start int) (end int, ok bool) {
+	tagEnd := start + 1
+	for tagEnd < len(sql) && sql[tagEnd] != '$' {
+		if !isIdentChar(sql[tagEnd]) || (tagEnd == start+1 && isDigit(sql[tagEnd])) {
+			return 0, false
+		}
+		tagEnd++
+	}
+	if tagEnd >= len(sql) {
+		return 0, false
+	}
+
+	tag := sql[start : tagEnd+1]
+	closing := strings.Index(sql[tagEnd+1:], tag)
+	if closing < 0 {
+		return len(sql), true
+	}
+	return tagEnd + 1 + closing + len(tag), true
+}
+
+func skipNumber(sql string, start int) int {
+	i := start
+	for i < len(sql) && isDigit(sql[i]) {
+		i++
+	}
+	if i < len(sql) && sql[i] == '.' {
+		i++
+		for i < len(sql) && isDigit(sql[i]) {
+			i++
+		}
+	}
+	if i < len(sql) && (sql[i] == 'e' || sql[i] == 'E') {
+		j := i + 1
+		if j < len(sql) && (sql[j] == '+' || sql[j] == '-') {
+			j++
+		}
+		if j < len(sql) && isDigit(sql[j]) {
+			i = j
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func precededByIdentChar(sql string, i int) bool {
+	return i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '$')
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f'
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func isIdentChar(ch byte) bool {
+	return ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || isDigit(ch) || ch >= 0x80
+}
@@ -0,0 +1,35 @@
+package pgx_test
+
+import (
+	"testing"
+
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sql      string
+		expected string
+	}{
+		{"select 1", "select $1"},
+		{"  select\n\t1 ,  'a'  ", "select $1 , $2"},
+		{"select * from t where id = $1 and name = 'x'", "select * from t where id = $1 and name = $2"},
+		{"select $2, 42, $1", "select $2, $3, $1"},
+		{"select 'it''s', E'a\\'b', B'101', X'ff', N'n'", "select $1, $2, $3, $4, $5"},
+		{"select $$dollar$$, $tag$quoted $$ text$tag$", "select $1, $2"},
+		{"select 1.5, .5, 1e10, 2.5E-3", "select $1, $2, $3, $4"},
+		{"select a1, t2.b3 from t2", "select a1, t2.b3 from t2"},
+		{`select "Col 1" from "t'x"`, `select "Col 1" from "t'x"`},
+		{"select 1 -- comment\n, 2 /* block /* nested */ */ from t", "select $1 , $2 from t"},
+		{"SELECT 1", "SELECT $1"},
+	}
+
+	for i, tt := range tests {
+		assert.Equalf(t, tt.expected, pgx.Fingerprint(tt.sql), "%d. %q", i, tt.sql)
+	}
+
+	assert.Equal(t, pgx.Fingerprint("select * from t where id = 1"), pgx.Fingerprint("select *\n  from t\n where id = 987654"))
+}
@@ -0,0 +1,172 @@
+package pgx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// GSS is a GSSAPI or SSPI security context used for Kerberos and Windows integrated authentication. See
+// ConnConfig.NewGSS.
+type GSS interface {
+	// InitToken returns the first token to send to the server with the service principal name spn.
+	InitToken(spn string) ([]byte, error)
+
+	// Continue processes a token received from the server. It returns the token to send in reply, which may be empty,
+	// and whether the security context is complete.
+	Continue(inToken []byte) (done bool, outToken []byte, err error)
+}
+
+// gssSPN returns the service principal name of host.
+func gssSPN(config *ConnConfig, host string) string {
+	if config.KerberosSPN != "" {
+		return config.KerberosSPN
+	}
+	service := config.KerberosServiceName
+	if service == "" {
+		service = "postgres"
+	}
+	return service + "/" + host
+}
+
+// gssBuildFrontend wraps buildFrontend so the frontends it builds perform GSSAPI and SSPI authentication with a
+// context from newGSS. pgproto3 cannot decode these authentication requests so the frontend reads the first messages
+// from the server itself and only hands them to the wrapped frontend once authentication is no longer GSS.
+func gssBuildFrontend(buildFrontend pgconn.BuildFrontendFunc, newGSS func() (GSS, error), spn string) pgconn.BuildFrontendFunc {
+	return func(r io.Reader, w io.Writer) pgconn.Frontend {
+		pr := &pushbackReader{r: r}
+		return &gssFrontend{Frontend: buildFrontend(pr, w), r: pr, w: w, newGSS: newGSS, spn: spn}
+	}
+}
+
+const (
+	authTypeGSS         = 7
+	authTypeGSSContinue = 8
+	authTypeSSPI        = 9
+)
+
+type gssFrontend struct {
+	pgconn.Frontend
+	r      *pushbackReader
+	w      io.Writer
+	newGSS func() (GSS, error)
+	spn    string
+
+	// delegated is set once messages are read by the wrapped frontend. It may buffer data beyond the message it
+	// returns so messages must not be read directly after that.
+	delegated bool
+}
+
+func (f *gssFrontend) Receive() (pgproto3.BackendMessage, error) {
+	if f.delegated {
+		return f.Frontend.Receive()
+	}
+	f.delegated = true
+
+	msg, err := readRawMessage(f.r)
+	if err != nil {
+		return nil, err
+	}
+	if authType(msg) == authTypeGSS || authType(msg) == authTypeSSPI {
+		msg, err = f.authenticate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f.r.pending = msg
+	return f.Frontend.Receive()
+}
+
+// authenticate exchanges tokens with the server until it sends a message other than a GSS continuation, which is
+// returned.
+func (f *gssFrontend) authenticate() ([]byte, error) {
+	gss, err := f.newGSS()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GSS context: %w", err)
+	}
+
+	token, err := gss.InitToken(f.spn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GSS token for %s: %w", f.spn, err)
+	}
+	if err := f.sendToken(token); err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, err := readRawMessage(f.r)
+		if err != nil {
+			return nil, err
+		}
+		if authType(msg) != authTypeGSSContinue {
+			return msg, nil
+		}
+
+		_, token, err := gss.Continue(msg[9:])
+		if err != nil {
+			return nil, fmt.Errorf("GSS authentication failed: %w", err)
+		}
+		if len(token) > 0 {
+			if err := f.sendToken(token); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// sendToken sends token in a GSSResponse message.
+func (f *gssFrontend) sendToken(token []byte) error {
+	buf := make([]byte, 5, 5+len(token))
+	buf[0] = 'p'
+	binary.BigEndian.PutUint32(buf[1:], uint32(4+len(token)))
+	buf = append(buf, token...)
+	_, err := f.w.Write(buf)
+	return err
+}
+
+// readRawMessage reads a complete backend message including its type and length.
+func readRawMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint32(header[1:]))
+	if length < 4 || length > 1<<20 {
+		return nil, &ProtocolError{MessageType: header[0], Err: errors.New("invalid message length")}
+	}
+
+	msg := make([]byte, 1+length)
+	copy(msg, header)
+	if _, err := io.ReadFull(r, msg[5:]); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// authType returns the authentication type of an authentication request message or -1 for any other message.
+func authType(msg []byte) int {
+	if msg[0] != 'R' || len(msg) < 9 {
+		return -1
+	}
+	return int(binary.BigEndian.Uint32(msg[5:]))
+}
+
+// pushbackReader is an io.Reader that returns pending before reading from r.
+type pushbackReader struct {
+	r       io.Reader
+	pending []byte
+}
+
+func (pr *pushbackReader) Read(p []byte) (int, error) {
+	if len(pr.pending) > 0 {
+		n := copy(p, pr.pending)
+		pr.pending = pr.pending[n:]
+		return n, nil
+	}
+	return pr.r.Read(p)
+}
@@ -0,0 +1,158 @@
+// Package introspect queries the system catalogs for the schemas, tables, columns, and indexes of a database.
+//
+// All functions take a Querier so they can be used with a *pgx.Conn, a pgx.Tx, or a *pgxpool.Pool.
+package introspect
+
+import (
+	"context"
+
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// Querier is the interface used to run catalog queries. It is implemented by *pgx.Conn, pgx.Tx, and *pgxpool.Pool.
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// Table is a table or table-like relation.
+type Table struct {
+	Schema string
+	Name   string
+	Kind   string // "table", "view", "materialized view", "foreign table", or "partitioned table"
+}
+
+// Column is a column of a table.
+type Column struct {
+	Name     string
+	Position int16 // 1-based position in the table
+	TypeOID  uint32
+	TypeName string  // formatted type including modifiers such as "character varying(20)"
+	Nullable bool    // false if the column has a NOT NULL constraint
+	Default  *string // default expression or nil if the column has no default
+}
+
+// Index is an index of a table.
+type Index struct {
+	Schema     string
+	Table      string
+	Name       string
+	Columns    []string // key and included columns or expressions in index order
+	Unique     bool
+	Primary    bool
+	Definition string // CREATE INDEX statement
+}
+
+// ListSchemas returns the names of the schemas in the database in order excluding system schemas and the temporary
+// schemas of other sessions.
+func ListSchemas(ctx context.Context, q Querier) ([]string, error) {
+	rows, err := q.Query(ctx, `select nspname
+from pg_catalog.pg_namespace
+where nspname not in ('pg_catalog', 'information_schema')
+  and nspname not like 'pg\_toast%'
+  and (nspname not like 'pg\_temp\_%' or oid = pg_catalog.pg_my_temp_schema())
+order by nspname`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+
+	return schemas, rows.Err()
+}
+
+// ListTables returns the tables, views, materialized views, foreign tables, and partitioned tables in schema ordered by
+// name.
+func ListTables(ctx context.Context, q Querier, schema string) ([]Table, error) {
+	rows, err := q.Query(ctx, `select n.nspname, c.relname,
+  case c.relkind
+    when 'r' then 'table'
+    when 'v' then 'view'
+    when 'm' then 'materialized view'
+    when 'f' then 'foreign table'
+    when 'p' then 'partitioned table'
+  end
+from pg_catalog.pg_class c
+  join pg_catalog.pg_namespace n on n.oid = c.relnamespace
+where n.nspname = $1 and c.relkind in ('r', 'v', 'm', 'f', 'p')
+order by c.relname`, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []Table
+	for rows.Next() {
+		var t Table
+		if err := rows.Scan(&t.Schema, &t.Name, &t.Kind); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, rows.Err()
+}
+
+// TableColumns returns the columns of table in schema in table order. Dropped and system columns are excluded. It
+// returns no columns if the table does not exist.
+func TableColumns(ctx context.Context, q Querier, schema, table string) ([]Column, error) {
+	rows, err := q.Query(ctx, `select a.attname, a.attnum, a.atttypid, pg_catalog.format_type(a.atttypid, a.atttypmod),
+  not a.attnotnull, pg_catalog.pg_get_expr(d.adbin, d.adrelid)
+from pg_catalog.pg_attribute a
+  join pg_catalog.pg_class c on c.oid = a.attrelid
+  join pg_catalog.pg_namespace n on n.oid = c.relnamespace
+  left join pg_catalog.pg_attrdef d on d.adrelid = a.attrelid and d.adnum = a.attnum
+where n.nspname = $1 and c.relname = $2 and a.attnum > 0 and not a.attisdropped
+order by a.attnum`, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var c Column
+		if err := rows.Scan(&c.Name, &c.Position, &c.TypeOID, &c.TypeName, &c.Nullable, &c.Default); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+
+	return columns, rows.Err()
+}
+
+// ListIndexes returns the indexes of the tables in schema ordered by table and index name. If table is not empty only
+// the indexes of that table are returned.
+func ListIndexes(ctx context.Context, q Querier, schema, table string) ([]Index, error) {
+	rows, err := q.Query(ctx, `select n.nspname, t.relname, i.relname,
+  array(select pg_catalog.pg_get_indexdef(x.indexrelid, k, true) from generate_series(1, x.indnatts) k order by k),
+  x.indisunique, x.indisprimary, pg_catalog.pg_get_indexdef(x.indexrelid)
+from pg_catalog.pg_index x
+  join pg_catalog.pg_class i on i.oid = x.indexrelid
+  join pg_catalog.pg_class t on t.oid = x.indrelid
+  join pg_catalog.pg_namespace n on n.oid = t.relnamespace
+where n.nspname = $1 and ($2 = '' or t.relname = $2)
+order by t.relname, i.relname`, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var idx Index
+		if err := rows.Scan(&idx.Schema, &idx.Table, &idx.Name, &idx.Columns, &idx.Unique, &idx.Primary, &idx.Definition); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, idx)
+	}
+
+	return indexes, rows.Err()
+}
@@ -0,0 +1,73 @@
+package introspect_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/nappspt/schemapgx/v4/introspect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospect(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	if conn.PgConn().ParameterStatus("crdb_version") != "" {
+		t.Skip("Server does not support all catalog queries")
+	}
+
+	_, err = conn.Exec(ctx, `create temporary table introspect_test (
+  id int8 primary key,
+  name varchar(20) not null default 'none',
+  note text
+)`)
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, `create index introspect_test_lower_name_idx on introspect_test (lower(name))`)
+	require.NoError(t, err)
+
+	var schema string
+	err = conn.QueryRow(ctx, "select nspname from pg_namespace where oid = pg_my_temp_schema()").Scan(&schema)
+	require.NoError(t, err)
+
+	schemas, err := introspect.ListSchemas(ctx, conn)
+	require.NoError(t, err)
+	assert.Contains(t, schemas, "public")
+	assert.Contains(t, schemas, schema)
+	assert.NotContains(t, schemas, "pg_catalog")
+
+	tables, err := introspect.ListTables(ctx, conn, schema)
+	require.NoError(t, err)
+	assert.Equal(t, []introspect.Table{{Schema: schema, Name: "introspect_test", Kind: "table"}}, tables)
+
+	columns, err := introspect.TableColumns(ctx, conn, schema, "introspect_test")
+	require.NoError(t, err)
+	require.Len(t, columns, 3)
+	assert.Equal(t, "id", columns[0].Name)
+	assert.EqualValues(t, 1, columns[0].Position)
+	assert.EqualValues(t, 20, columns[0].TypeOID)
+	assert.False(t, columns[0].Nullable)
+	assert.Nil(t, columns[0].Default)
+	assert.Equal(t, "character varying(20)", columns[1].TypeName)
+	require.NotNil(t, columns[1].Default)
+	assert.Equal(t, "'none'::character varying", *columns[1].Default)
+	assert.True(t, columns[2].Nullable)
+
+	indexes, err := introspect.ListIndexes(ctx, conn, schema, "introspect_test")
+	require.NoError(t, err)
+	require.Len(t, indexes, 2)
+	assert.Equal(t, "introspect_test_lower_name_idx", indexes[0].Name)
+	assert.Equal(t, []string{"lower((name)::text)"}, indexes[0].Columns)
+	assert.False(t, indexes[0].Unique)
+	assert.Equal(t, "introspect_test_pkey", indexes[1].Name)
+	assert.Equal(t, []string{"id"}, indexes[1].Columns)
+	assert.True(t, indexes[1].Primary)
+	assert.True(t, indexes[1].Unique)
+	assert.Contains(t, indexes[1].Definition, "CREATE UNIQUE INDEX")
+}
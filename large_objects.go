@@ -21,8 +21,20 @@ const (
 	LargeObjectModeRead  LargeObjectMode = 0x40000
 )
 
+// checkSupported returns ErrNotSupportedByCockroachDB if the transaction is on a CockroachDB connection.
+func (o *LargeObjects) checkSupported() error {
+	if conn := o.tx.Conn(); conn != nil && conn.IsCockroachDB() {
+		return ErrNotSupportedByCockroachDB
+	}
+	return nil
+}
+
 // Create creates a new large object. If oid is zero, the server assigns an unused OID.
 func (o *LargeObjects) Create(ctx context.Context, oid uint32) (uint32, error) {
+	if err := o.checkSupported(); err != nil {
+		return 0, err
+	}
+
 	err := o.tx.QueryRow(ctx, "select lo_create($1)", oid).Scan(&oid)
 	return oid, err
 }
@@ -30,6 +42,10 @@ func (o *LargeObjects) Create(ctx context.Context, oid uint32) (uint32, error) {
 // Open opens an existing large object with the given mode. ctx will also be used for all operations on the opened large
 // object.
 func (o *LargeObjects) Open(ctx context.Context, oid uint32, mode LargeObjectMode) (*LargeObject, error) {
+	if err := o.checkSupported(); err != nil {
+		return nil, err
+	}
+
 	var fd int32
 	err := o.tx.QueryRow(ctx, "select lo_open($1, $2)", oid, mode).Scan(&fd)
 	if err != nil {
@@ -40,6 +56,10 @@ func (o *LargeObjects) Open(ctx context.Context, oid uint32, mode LargeObjectMod
 
 // Unlink removes a large object from the database.
 func (o *LargeObjects) Unlink(ctx context.Context, oid uint32) error {
+	if err := o.checkSupported(); err != nil {
+		return err
+	}
+
 	var result int32
 	err := o.tx.QueryRow(ctx, "select lo_unlink($1)", oid).Scan(&result)
 	if err != nil {
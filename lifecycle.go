@@ -0,0 +1,83 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// errConnLost is the cause reported to OnDeath when the connection was closed without an observed error.
+var errConnLost = errors.New("connection lost")
+
+// connLifecycle tracks why a connection was closed so the OnClose and OnDeath hooks can be fired.
+type connLifecycle struct {
+	mux     sync.Mutex
+	closing bool  // Close was called
+	cause   error // first error that broke the connection
+}
+
+// setCause records err as the reason the connection died unless a reason has already been recorded.
+func (lc *connLifecycle) setCause(err error) {
+	lc.mux.Lock()
+	if lc.cause == nil {
+		lc.cause = err
+	}
+	lc.mux.Unlock()
+}
+
+// startClosing records that the connection is being closed by Close.
+func (lc *connLifecycle) startClosing() {
+	lc.mux.Lock()
+	lc.closing = true
+	lc.mux.Unlock()
+}
+
+// watchDeath fires OnDeath when the connection is closed other than by Close.
+func (c *Conn) watchDeath() {
+	<-c.pgConn.CleanupDone()
+
+	c.lifecycle.mux.Lock()
+	closing, cause := c.lifecycle.closing, c.lifecycle.cause
+	c.lifecycle.mux.Unlock()
+
+	if closing {
+		return
+	}
+	if cause == nil {
+		cause = errConnLost
+	}
+	if c.shouldLog(LogLevelError) {
+		c.log(context.Background(), LogLevelError, "connection died", map[string]interface{}{"err": cause})
+	}
+	c.config.OnDeath(c, cause)
+}
+
+// lifecycleConn records the first read or write error on the underlying network connection as the cause of death.
+// Timeouts are not recorded. They are caused by deadlines pgconn sets to interrupt a read when a context is canceled,
+// such as while waiting for a notification, and do not break the connection.
+type lifecycleConn struct {
+	net.Conn
+	lifecycle *connLifecycle
+}
+
+func (c *lifecycleConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if err != nil && !isTimeout(err) {
+		c.lifecycle.setCause(err)
+	}
+	return n, err
+}
+
+func (c *lifecycleConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if err != nil && !isTimeout(err) {
+		c.lifecycle.setCause(err)
+	}
+	return n, err
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,113 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LSN is a PostgreSQL write-ahead log location (pg_lsn).
+type LSN uint64
+
+// ParseLSN parses the text format of a pg_lsn such as "16/B374D848".
+func ParseLSN(s string) (LSN, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid LSN: %q", s)
+	}
+	hi, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN: %q", s)
+	}
+	lo, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN: %q", s)
+	}
+	return LSN(hi<<32 | lo), nil
+}
+
+// String returns the LSN in the text format of a pg_lsn.
+func (lsn LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint64(lsn)>>32, uint32(lsn))
+}
+
+// LSNWaitTimeoutError occurs when WaitForLSN times out before the server replays the requested LSN.
+type LSNWaitTimeoutError struct {
+	LSN      LSN // the LSN waited for
+	Replayed LSN // the last LSN replayed by the server
+}
+
+func (e *LSNWaitTimeoutError) Error() string {
+	return fmt.Sprintf("timeout waiting for LSN %s: replayed %s", e.LSN, e.Replayed)
+}
+
+// walFunction returns name adjusted for the server version. PostgreSQL 10 renamed xlog to wal and location to lsn in
+// the names of WAL functions.
+func (c *Conn) walFunction(name string) string {
+	if version := c.ServerVersion(); version != 0 && version < 100000 {
+		name = strings.Replace(name, "wal", "xlog", 1)
+		name = strings.Replace(name, "lsn", "location", 1)
+	}
+	return name
+}
+
+// CurrentWALLSN returns the current write-ahead log location of the server. When called on a primary after a write
+// has committed, a replica that has replayed this LSN can see the write. See WaitForLSN.
+func (c *Conn) CurrentWALLSN(ctx context.Context) (LSN, error) {
+	var s string
+	err := c.QueryRow(ctx, "select "+c.walFunction("pg_current_wal_lsn")+"()::text").Scan(&s)
+	if err != nil {
+		return 0, err
+	}
+	return ParseLSN(s)
+}
+
+// WaitForLSN waits until the server has replayed the write-ahead log up to lsn. It is used on a connection to a
+// replica to read the writes made on the primary up to a location returned by CurrentWALLSN. If the server is not a
+// replica it returns immediately. If timeout is exceeded a *LSNWaitTimeoutError is returned. A timeout of 0 only checks
+// once.
+func (c *Conn) WaitForLSN(ctx context.Context, lsn LSN, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	sql := "select " + c.walFunction("pg_last_wal_replay_lsn") + "()::text"
+	interval := time.Millisecond
+
+	for {
+		var s *string
+		err := c.QueryRow(ctx, sql).Scan(&s)
+		if err != nil {
+			return err
+		}
+		// pg_last_wal_replay_lsn returns null when the server is not in recovery.
+		if s == nil {
+			return nil
+		}
+		replayed, err := ParseLSN(*s)
+		if err != nil {
+			return err
+		}
+		if replayed >= lsn {
+			return nil
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return &LSNWaitTimeoutError{LSN: lsn, Replayed: replayed}
+		}
+		if wait > interval {
+			wait = interval
+		}
+		if interval < 100*time.Millisecond {
+			interval *= 2
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
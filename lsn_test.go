@@ -0,0 +1,45 @@
+package pgx_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLSN(t *testing.T) {
+	t.Parallel()
+
+	lsn, err := pgx.ParseLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, pgx.LSN(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", lsn.String())
+	assert.Equal(t, "0/0", pgx.LSN(0).String())
+
+	for _, s := range []string{"", "16", "16/", "x/1", "1/2/3", "100000000/0"} {
+		_, err := pgx.ParseLSN(s)
+		assert.Errorf(t, err, "%q", s)
+	}
+}
+
+func TestConnWaitForLSN(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	skipCockroachDB(t, conn, "Server does not support pg_current_wal_lsn")
+
+	lsn, err := conn.CurrentWALLSN(context.Background())
+	require.NoError(t, err)
+	assert.NotZero(t, lsn)
+
+	// The test server is a primary so every LSN has been replayed.
+	err = conn.WaitForLSN(context.Background(), lsn, 0)
+	require.NoError(t, err)
+
+	ensureConnValid(t, conn)
+}
@@ -0,0 +1,337 @@
+// Package migrate applies versioned schema migrations.
+//
+// Migrations are SQL or Go functions identified by a version number. The versions that have been applied are recorded
+// in a version table. Each migration runs in its own transaction unless it is marked NoTx so a failed migration leaves
+// the schema at the previous version. A session-level advisory lock prevents concurrent migrators from applying the
+// same migrations.
+//
+//	m := migrate.NewMigrator(conn, migrate.Options{})
+//	err := m.LoadDir("migrations")
+//	if err != nil {
+//		return err
+//	}
+//	steps, err := m.Migrate(ctx)
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// DefaultLockID is the advisory lock ID used when Options.LockID is 0.
+const DefaultLockID = 6870317357011346461
+
+// Migration is a single schema change.
+type Migration struct {
+	Version int64
+	Name    string
+
+	// UpSQL and DownSQL are executed to apply and revert the migration. They may contain multiple statements.
+	UpSQL   string
+	DownSQL string
+
+	// Up and Down, if set, are called after UpSQL or DownSQL. They are passed the transaction the migration runs in or
+	// nil if NoTx is set.
+	Up   func(ctx context.Context, conn *pgx.Conn, tx pgx.Tx) error
+	Down func(ctx context.Context, conn *pgx.Conn, tx pgx.Tx) error
+
+	// NoTx runs the migration without a transaction. This is required for statements that cannot run in a transaction
+	// such as CREATE INDEX CONCURRENTLY. If a NoTx migration fails part way it must be repaired manually.
+	NoTx bool
+}
+
+func (m *Migration) reversible() bool {
+	return m.DownSQL != "" || m.Down != nil
+}
+
+// Step is a migration applied or reverted by Migrate or MigrateTo.
+type Step struct {
+	Migration *Migration
+	Down      bool
+}
+
+// Options configures a Migrator.
+type Options struct {
+	// VersionTable is the table that records the applied versions. It is created if it does not exist. The default is
+	// schema_version.
+	VersionTable pgx.Identifier
+
+	// LockID is the ID of the advisory lock held while migrating. The default is DefaultLockID.
+	LockID int64
+
+	// DryRun returns the steps that would be taken without executing them or creating the version table.
+	DryRun bool
+
+	// OnStep, if set, is called before each step is executed.
+	OnStep func(Step)
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	conn       *pgx.Conn
+	options    Options
+	migrations []*Migration
+}
+
+// NewMigrator returns a Migrator for conn.
+func NewMigrator(conn *pgx.Conn, options Options) *Migrator {
+	if len(options.VersionTable) == 0 {
+		options.VersionTable = pgx.Identifier{"schema_version"}
+	}
+	if options.LockID == 0 {
+		options.LockID = DefaultLockID
+	}
+	return &Migrator{conn: conn, options: options}
+}
+
+// Add adds migrations. It is an error to add a version more than once.
+func (m *Migrator) Add(migrations ...*Migration) error {
+	for _, migration := range migrations {
+		if migration.Version <= 0 {
+			return fmt.Errorf("migration %q has invalid version %d", migration.Name, migration.Version)
+		}
+		for _, existing := range m.migrations {
+			if existing.Version == migration.Version {
+				return fmt.Errorf("duplicate migration version %d", migration.Version)
+			}
+		}
+		m.migrations = append(m.migrations, migration)
+	}
+
+	sort.Slice(m.migrations, func(i, j int) bool { return m.migrations[i].Version < m.migrations[j].Version })
+	return nil
+}
+
+// Migrations returns the migrations ordered by version.
+func (m *Migrator) Migrations() []*Migration {
+	return append([]*Migration(nil), m.migrations...)
+}
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// LoadDir adds the migrations in the files in dir named VERSION_NAME.up.sql and VERSION_NAME.down.sql such as
+// 001_create_users.up.sql. The down file is optional. Other files are ignored.
+func (m *Migrator) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	var migrations []*Migration
+	for _, file := range files {
+		match := migrationFileRegexp.FindStringSubmatch(file.Name())
+		if file.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration version in %s: %w", file.Name(), err)
+		}
+		buf, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+			migrations = append(migrations, migration)
+		} else if migration.Name != match[2] {
+			return fmt.Errorf("migration version %d has different names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.UpSQL = string(buf)
+		} else {
+			migration.DownSQL = string(buf)
+		}
+	}
+
+	for _, migration := range migrations {
+		if migration.UpSQL == "" {
+			return fmt.Errorf("migration version %d has no up file", migration.Version)
+		}
+	}
+
+	return m.Add(migrations...)
+}
+
+// CurrentVersion returns the highest applied version or 0 if no migrations have been applied.
+func (m *Migrator) CurrentVersion(ctx context.Context) (int64, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(applied) == 0 {
+		return 0, nil
+	}
+	return applied[len(applied)-1], nil
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context) ([]int64, error) {
+	var exists bool
+	err := m.conn.QueryRow(ctx, "select to_regclass($1) is not null", m.options.VersionTable.Sanitize()).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := m.conn.Query(ctx, "select version from "+m.options.VersionTable.Sanitize()+" order by version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []int64
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// Migrate applies all migrations that have not been applied. It returns the steps taken.
+func (m *Migrator) Migrate(ctx context.Context) ([]Step, error) {
+	if len(m.migrations) == 0 {
+		return nil, nil
+	}
+	return m.MigrateTo(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// MigrateTo applies or reverts migrations until the schema is at version target. Target 0 reverts all migrations.
+// Migrations that have not been applied with a version at or below target are applied. Applied migrations above target
+// are reverted in descending order. It returns the steps taken. If a step fails the steps taken before it and the error
+// are returned.
+func (m *Migrator) MigrateTo(ctx context.Context, target int64) ([]Step, error) {
+	if target != 0 && m.find(target) == nil {
+		return nil, fmt.Errorf("no migration with version %d", target)
+	}
+
+	if !m.options.DryRun {
+		_, err := m.conn.Exec(ctx, "select pg_advisory_lock($1)", m.options.LockID)
+		if err != nil {
+			return nil, err
+		}
+		defer m.conn.Exec(context.Background(), "select pg_advisory_unlock($1)", m.options.LockID)
+
+		_, err = m.conn.Exec(ctx, "create table if not exists "+m.options.VersionTable.Sanitize()+
+			" (version int8 primary key, name text not null, applied_at timestamptz not null default now())")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	steps, err := m.plan(ctx, target)
+	if err != nil || m.options.DryRun {
+		return steps, err
+	}
+
+	for i, step := range steps {
+		if m.options.OnStep != nil {
+			m.options.OnStep(step)
+		}
+		err := m.run(ctx, step)
+		if err != nil {
+			return steps[:i], fmt.Errorf("migration %d %s: %w", step.Migration.Version, step.Migration.Name, err)
+		}
+	}
+
+	return steps, nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// plan returns the steps that migrate to target.
+func (m *Migrator) plan(ctx context.Context, target int64) ([]Step, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	isApplied := make(map[int64]bool, len(applied))
+	for _, version := range applied {
+		isApplied[version] = true
+	}
+
+	var steps []Step
+	for i := len(applied) - 1; i >= 0 && applied[i] > target; i-- {
+		migration := m.find(applied[i])
+		if migration == nil {
+			return nil, fmt.Errorf("applied migration %d is unknown", applied[i])
+		}
+		if !migration.reversible() {
+			return nil, fmt.Errorf("migration %d %s cannot be reverted", migration.Version, migration.Name)
+		}
+		steps = append(steps, Step{Migration: migration, Down: true})
+	}
+	for _, migration := range m.migrations {
+		if migration.Version <= target && !isApplied[migration.Version] {
+			steps = append(steps, Step{Migration: migration})
+		}
+	}
+
+	return steps, nil
+}
+
+func (m *Migrator) run(ctx context.Context, step Step) error {
+	if step.Migration.NoTx {
+		return m.runStep(ctx, step, nil)
+	}
+
+	return m.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		return m.runStep(ctx, step, tx)
+	})
+}
+
+// runStep executes step in tx or directly on the connection if tx is nil.
+func (m *Migrator) runStep(ctx context.Context, step Step, tx pgx.Tx) error {
+	exec := m.conn.Exec
+	if tx != nil {
+		exec = tx.Exec
+	}
+
+	migration := step.Migration
+	sql, f := migration.UpSQL, migration.Up
+	if step.Down {
+		sql, f = migration.DownSQL, migration.Down
+	}
+
+	if sql != "" {
+		if _, err := exec(ctx, sql); err != nil {
+			return err
+		}
+	}
+	if f != nil {
+		if err := f(ctx, m.conn, tx); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if step.Down {
+		_, err = exec(ctx, "delete from "+m.options.VersionTable.Sanitize()+" where version = $1", migration.Version)
+	} else {
+		_, err = exec(ctx, "insert into "+m.options.VersionTable.Sanitize()+" (version, name) values ($1, $2)", migration.Version, migration.Name)
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package migrate_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/nappspt/schemapgx/v4/migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDir(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "migrate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"002_add_email.up.sql":      "alter table users add column email text;",
+		"001_create_users.up.sql":   "create table users (id int8);",
+		"001_create_users.down.sql": "drop table users;",
+		"README.md":                 "not a migration",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	m := migrate.NewMigrator(nil, migrate.Options{})
+	require.NoError(t, m.LoadDir(dir))
+
+	migrations := m.Migrations()
+	require.Len(t, migrations, 2)
+	assert.Equal(t, &migrate.Migration{Version: 1, Name: "create_users", UpSQL: "create table users (id int8);", DownSQL: "drop table users;"}, migrations[0])
+	assert.Equal(t, &migrate.Migration{Version: 2, Name: "add_email", UpSQL: "alter table users add column email text;"}, migrations[1])
+
+	require.Error(t, m.Add(&migrate.Migration{Version: 2, Name: "duplicate"}))
+}
+
+func TestMigrator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	if conn.PgConn().ParameterStatus("crdb_version") != "" {
+		t.Skip("Server does not support advisory locks")
+	}
+
+	options := migrate.Options{VersionTable: pgx.Identifier{"pg_temp", "migrate_test_version"}}
+	m := migrate.NewMigrator(conn, options)
+	err = m.Add(
+		&migrate.Migration{Version: 1, Name: "create", UpSQL: "create temporary table migrate_test (id int8); insert into migrate_test values (1);", DownSQL: "drop table migrate_test"},
+		&migrate.Migration{
+			Version: 2,
+			Name:    "go",
+			Up: func(ctx context.Context, conn *pgx.Conn, tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, "insert into migrate_test values ($1)", 2)
+				return err
+			},
+			Down: func(ctx context.Context, conn *pgx.Conn, tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, "delete from migrate_test where id = $1", 2)
+				return err
+			},
+		},
+		&migrate.Migration{Version: 3, Name: "fail", UpSQL: "insert into migrate_test values (3); select 1 / 0;", DownSQL: "select 1"},
+	)
+	require.NoError(t, err)
+
+	dryRun := migrate.NewMigrator(conn, migrate.Options{VersionTable: options.VersionTable, DryRun: true})
+	require.NoError(t, dryRun.Add(m.Migrations()...))
+	steps, err := dryRun.MigrateTo(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	version, err := m.CurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, version)
+
+	steps, err = m.MigrateTo(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	version, err = m.CurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, version)
+
+	steps, err = m.Migrate(ctx)
+	require.Error(t, err)
+	assert.Empty(t, steps)
+	version, err = m.CurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, version)
+
+	var n int64
+	require.NoError(t, conn.QueryRow(ctx, "select count(*) from migrate_test").Scan(&n))
+	assert.EqualValues(t, 2, n)
+
+	steps, err = m.MigrateTo(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, steps, 1)
+	assert.True(t, steps[0].Down)
+	require.NoError(t, conn.QueryRow(ctx, "select count(*) from migrate_test").Scan(&n))
+	assert.EqualValues(t, 1, n)
+
+	_, err = m.MigrateTo(ctx, 0)
+	require.NoError(t, err)
+	version, err = m.CurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, version)
+}
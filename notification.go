@@ -0,0 +1,170 @@
+package pgx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+// NotificationOverflowPolicy determines which notification is dropped when the notification buffer is full. See
+// ConnConfig.MaxBufferedNotifications.
+type NotificationOverflowPolicy int
+
+const (
+	// NotificationOverflowDropOldest drops the oldest buffered notification to make room for the new one.
+	NotificationOverflowDropOldest NotificationOverflowPolicy = iota
+
+	// NotificationOverflowDropNewest drops the notification that was just received.
+	NotificationOverflowDropNewest
+
+	// NotificationOverflowError drops the notification that was just received and causes WaitForNotification to
+	// return ErrNotificationOverflow once the buffered notifications have been read.
+	NotificationOverflowError
+)
+
+// ErrNotificationOverflow is returned by WaitForNotification when notifications were dropped because the buffer was
+// full and the NotificationOverflowError policy is in use.
+var ErrNotificationOverflow = errors.New("notification buffer overflowed")
+
+// pollNotificationTimeout is how long PollNotification waits for data from the server. Reads cannot be made with a
+// deadline that has already passed so a short timeout is used instead.
+const pollNotificationTimeout = time.Millisecond
+
+// CollectNotifications removes and returns up to max notifications that have already been received without reading
+// from the server. If max <= 0 all buffered notifications are returned. It returns nil if there are none. Notifications
+// are buffered when they are received while executing other commands or by PollNotification and WaitForNotification.
+func (c *Conn) CollectNotifications(max int) []*pgconn.Notification {
+	n := len(c.notifications)
+	if n == 0 {
+		return nil
+	}
+	if max > 0 && max < n {
+		n = max
+	}
+
+	collected := make([]*pgconn.Notification, n)
+	copy(collected, c.notifications)
+	c.notifications = c.notifications[n:]
+	return collected
+}
+
+// PollNotification returns a notification without waiting for one to arrive. It returns a buffered notification if
+// there is one. Otherwise it reads any notifications the server has already sent and returns the first one. It returns
+// nil and no error if there is no notification. As with WaitForNotification, ErrNotificationOverflow is returned once
+// after notifications were dropped with the NotificationOverflowError policy.
+//
+// An event loop can call PollNotification and then CollectNotifications to process notifications in batches.
+func (c *Conn) PollNotification(ctx context.Context) (*pgconn.Notification, error) {
+	if len(c.notifications) > 0 || c.notificationsOverflowed {
+		return c.WaitForNotification(ctx)
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, pollNotificationTimeout)
+	defer cancel()
+
+	n, err := c.WaitForNotification(pollCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(pollCtx.Err(), context.DeadlineExceeded) && !c.IsClosed() {
+		return nil, nil
+	}
+	return n, err
+}
+
+// ReadPendingMessages reads and processes the messages the server has already sent to an idle connection without
+// waiting for more. Notifications are buffered for WaitForNotification and CollectNotifications, or passed to the
+// OnNotification handler if one is configured, and parameter status changes are applied. This lets an idle connection
+// discover notifications before it is next used and detect that it has been closed by the server. It returns an error
+// if the connection is broken.
+func (c *Conn) ReadPendingMessages(ctx context.Context) error {
+	if c.pgConn.IsBusy() {
+		return ErrConnBusy
+	}
+
+	if c.readTimeoutConn != nil {
+		c.readTimeoutConn.disable()
+		defer c.readTimeoutConn.enable()
+	}
+
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, pollNotificationTimeout)
+		err := c.pgConn.WaitForNotification(pollCtx)
+		timedOut := errors.Is(pollCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && timedOut && !c.IsClosed() {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Notify sends a notification with payload on channel. It uses pg_notify with bound parameters so channel and payload
+// do not need to be quoted or escaped. As with NOTIFY, if c is in a transaction the notification is only delivered
+// when the transaction commits.
+func (c *Conn) Notify(ctx context.Context, channel, payload string) error {
+	_, err := c.Exec(ctx, "select pg_notify($1, $2)", channel, payload)
+	return err
+}
+
+// NotifyJSON marshals v as JSON and sends it as the payload of a notification on channel. See Notify.
+func (c *Conn) NotifyJSON(ctx context.Context, channel string, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Notify(ctx, channel, string(payload))
+}
+
+// UnmarshalNotification unmarshals the JSON payload of n into v.
+func UnmarshalNotification(n *pgconn.Notification, v interface{}) error {
+	err := json.Unmarshal([]byte(n.Payload), v)
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal notification payload on channel %q: %w", n.Channel, err)
+	}
+	return nil
+}
+
+// NotificationDecoder decodes JSON notification payloads into Go types registered per channel. The zero value is ready
+// to use. A NotificationDecoder is safe for concurrent use once all channels have been registered.
+type NotificationDecoder struct {
+	types map[string]reflect.Type
+}
+
+// Register registers the type of v as the payload type for channel. Payloads received on channel will be decoded into
+// a new value of that type. v is only used for its type. e.g. d.Register("orders", OrderEvent{}). Register panics if v
+// is nil as it has no type.
+func (d *NotificationDecoder) Register(channel string, v interface{}) {
+	if v == nil {
+		panic(fmt.Sprintf("payload type for notification channel %q must not be nil", channel))
+	}
+	if d.types == nil {
+		d.types = make(map[string]reflect.Type)
+	}
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	d.types[channel] = t
+}
+
+// Decode decodes the payload of n into a new value of the type registered for n.Channel. The returned value is a
+// pointer to that type. e.g. *OrderEvent.
+func (d *NotificationDecoder) Decode(n *pgconn.Notification) (interface{}, error) {
+	t, ok := d.types[n.Channel]
+	if !ok {
+		return nil, fmt.Errorf("no payload type registered for notification channel %q", n.Channel)
+	}
+
+	v := reflect.New(t).Interface()
+	err := UnmarshalNotification(n, v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
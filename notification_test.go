@@ -0,0 +1,219 @@
+package pgx_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnNotify(t *testing.T) {
+	t.Parallel()
+
+	listener := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, listener)
+	skipCockroachDB(t, listener, "Server does not support LISTEN / NOTIFY (https://github.com/cockroachdb/cockroach/issues/41522)")
+
+	mustExec(t, listener, `listen "weird channel's name"`)
+
+	notifier := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, notifier)
+
+	err := notifier.Notify(context.Background(), "weird channel's name", "it's a payload")
+	require.NoError(t, err)
+
+	notification, err := listener.WaitForNotification(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "weird channel's name", notification.Channel)
+	assert.Equal(t, "it's a payload", notification.Payload)
+}
+
+func TestConnNotifyJSON(t *testing.T) {
+	t.Parallel()
+
+	listener := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, listener)
+	skipCockroachDB(t, listener, "Server does not support LISTEN / NOTIFY (https://github.com/cockroachdb/cockroach/issues/41522)")
+
+	mustExec(t, listener, "listen events")
+
+	notifier := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, notifier)
+
+	err := notifier.NotifyJSON(context.Background(), "events", map[string]interface{}{"id": 42, "name": "created"})
+	require.NoError(t, err)
+
+	notification, err := listener.WaitForNotification(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "events", notification.Channel)
+	assert.JSONEq(t, `{"id": 42, "name": "created"}`, notification.Payload)
+}
+
+func TestUnmarshalNotification(t *testing.T) {
+	t.Parallel()
+
+	var v struct {
+		ID int `json:"id"`
+	}
+	err := pgx.UnmarshalNotification(&pgconn.Notification{Channel: "events", Payload: `{"id": 7}`}, &v)
+	require.NoError(t, err)
+	assert.Equal(t, 7, v.ID)
+
+	err = pgx.UnmarshalNotification(&pgconn.Notification{Channel: "events", Payload: "not json"}, &v)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `channel "events"`)
+}
+
+func TestNotificationDecoder(t *testing.T) {
+	t.Parallel()
+
+	type orderCreated struct {
+		OrderID int `json:"order_id"`
+	}
+	type userDeleted struct {
+		UserID string `json:"user_id"`
+	}
+
+	var d pgx.NotificationDecoder
+	d.Register("order_created", orderCreated{})
+	d.Register("user_deleted", &userDeleted{})
+
+	v, err := d.Decode(&pgconn.Notification{Channel: "order_created", Payload: `{"order_id": 1}`})
+	require.NoError(t, err)
+	assert.Equal(t, &orderCreated{OrderID: 1}, v)
+
+	v, err = d.Decode(&pgconn.Notification{Channel: "user_deleted", Payload: `{"user_id": "abc"}`})
+	require.NoError(t, err)
+	assert.Equal(t, &userDeleted{UserID: "abc"}, v)
+
+	_, err = d.Decode(&pgconn.Notification{Channel: "unknown", Payload: `{}`})
+	require.EqualError(t, err, `no payload type registered for notification channel "unknown"`)
+
+	require.PanicsWithValue(t, `payload type for notification channel "nil" must not be nil`, func() {
+		d.Register("nil", nil)
+	})
+}
+
+func TestConnMaxBufferedNotifications(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		policy          pgx.NotificationOverflowPolicy
+		expected        []string
+		expectOverflow  bool
+		expectedDropped []string
+	}{
+		{pgx.NotificationOverflowDropOldest, []string{"3", "4"}, false, []string{"1", "2"}},
+		{pgx.NotificationOverflowDropNewest, []string{"1", "2"}, false, []string{"3", "4"}},
+		{pgx.NotificationOverflowError, []string{"1", "2"}, true, []string{"3", "4"}},
+	}
+
+	for i, tt := range tests {
+		var dropped []string
+		config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+		config.MaxBufferedNotifications = 2
+		config.NotificationOverflowPolicy = tt.policy
+		config.OnNotificationDropped = func(_ *pgx.Conn, n *pgconn.Notification) {
+			dropped = append(dropped, n.Payload)
+		}
+
+		listener := mustConnect(t, config)
+		skipCockroachDB(t, listener, "Server does not support LISTEN / NOTIFY (https://github.com/cockroachdb/cockroach/issues/41522)")
+		mustExec(t, listener, "listen overflow")
+
+		// Notifications to self are received while executing the query.
+		mustExec(t, listener, "select pg_notify('overflow', n::text) from generate_series(1, 4) n")
+
+		for _, expected := range tt.expected {
+			notification, err := listener.WaitForNotification(context.Background())
+			require.NoErrorf(t, err, "%d", i)
+			assert.Equalf(t, expected, notification.Payload, "%d", i)
+		}
+
+		if tt.expectOverflow {
+			_, err := listener.WaitForNotification(context.Background())
+			require.Equalf(t, pgx.ErrNotificationOverflow, err, "%d", i)
+		}
+
+		assert.EqualValuesf(t, 2, listener.DroppedNotifications(), "%d", i)
+		assert.Equalf(t, tt.expectedDropped, dropped, "%d", i)
+
+		closeConn(t, listener)
+	}
+}
+
+func TestConnPollAndCollectNotifications(t *testing.T) {
+	t.Parallel()
+
+	listener := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, listener)
+	skipCockroachDB(t, listener, "Server does not support LISTEN / NOTIFY (https://github.com/cockroachdb/cockroach/issues/41522)")
+
+	mustExec(t, listener, "listen poll_collect")
+
+	n, err := listener.PollNotification(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, n)
+	assert.Nil(t, listener.CollectNotifications(0))
+
+	notifier := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, notifier)
+	for _, payload := range []string{"a", "b", "c"} {
+		require.NoError(t, notifier.Notify(context.Background(), "poll_collect", payload))
+	}
+
+	var payloads []string
+	deadline := time.Now().Add(5 * time.Second)
+	for len(payloads) < 3 && time.Now().Before(deadline) {
+		n, err := listener.PollNotification(context.Background())
+		require.NoError(t, err)
+		if n == nil {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		payloads = append(payloads, n.Payload)
+		for _, n := range listener.CollectNotifications(1) {
+			payloads = append(payloads, n.Payload)
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, payloads)
+
+	ensureConnValid(t, listener)
+}
+
+func TestConnReadPendingMessages(t *testing.T) {
+	t.Parallel()
+
+	listener := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, listener)
+	skipCockroachDB(t, listener, "Server does not support LISTEN / NOTIFY (https://github.com/cockroachdb/cockroach/issues/41522)")
+
+	mustExec(t, listener, "listen read_pending")
+
+	require.NoError(t, listener.ReadPendingMessages(context.Background()))
+	assert.Nil(t, listener.CollectNotifications(0))
+
+	notifier := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, notifier)
+	for _, payload := range []string{"a", "b"} {
+		require.NoError(t, notifier.Notify(context.Background(), "read_pending", payload))
+	}
+
+	var payloads []string
+	deadline := time.Now().Add(5 * time.Second)
+	for len(payloads) < 2 && time.Now().Before(deadline) {
+		require.NoError(t, listener.ReadPendingMessages(context.Background()))
+		for _, n := range listener.CollectNotifications(0) {
+			payloads = append(payloads, n.Payload)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, []string{"a", "b"}, payloads)
+
+	ensureConnValid(t, listener)
+}
@@ -0,0 +1,58 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// passwordPrompt calls ConnConfig.PromptPassword at most once for a connect so the user is not asked again for each
+// host or address that is tried.
+type passwordPrompt struct {
+	prompt func(ctx context.Context) (string, error)
+
+	prompted bool
+	password string
+	err      error
+}
+
+func (pp *passwordPrompt) get(ctx context.Context) (string, error) {
+	if !pp.prompted {
+		pp.password, pp.err = pp.prompt(ctx)
+		if pp.err != nil {
+			pp.err = fmt.Errorf("failed to prompt for password: %w", pp.err)
+		}
+		pp.prompted = true
+	}
+	return pp.password, pp.err
+}
+
+// passwordPromptFrontend is a pgconn.Frontend that sets the password of config from prompt when the server requests a
+// password. pgconn reads the password from the config of the connection after it receives the request so it is set
+// before the password message is sent.
+type passwordPromptFrontend struct {
+	pgconn.Frontend
+	ctx    context.Context
+	config *pgconn.Config
+	prompt *passwordPrompt
+}
+
+func (f *passwordPromptFrontend) Receive() (pgproto3.BackendMessage, error) {
+	msg, err := f.Frontend.Receive()
+	if err != nil || f.config.Password != "" {
+		return msg, err
+	}
+
+	switch msg.(type) {
+	case *pgproto3.AuthenticationCleartextPassword, *pgproto3.AuthenticationMD5Password, *pgproto3.AuthenticationSASL:
+		password, err := f.prompt.get(f.ctx)
+		if err != nil {
+			return nil, err
+		}
+		f.config.Password = password
+	}
+
+	return msg, nil
+}
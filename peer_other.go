@@ -0,0 +1,14 @@
+//go:build !linux
+
+package pgx
+
+import (
+	"errors"
+	"net"
+)
+
+// peerUID returns the user ID of the process on the other end of the unix domain socket conn. It is only supported
+// on Linux.
+func peerUID(conn net.Conn) (int, error) {
+	return 0, errors.New("requirepeer is not supported on this platform")
+}
@@ -0,0 +1,57 @@
+package pgx
+
+import (
+	"errors"
+
+	"github.com/jackc/pgconn"
+)
+
+// IsLockNotAvailable reports whether err is a lock_not_available (55P03) error. This occurs when a lock cannot be
+// acquired immediately by a statement using NOWAIT or when lock_timeout is exceeded. The statement can usually be retried
+// later.
+func IsLockNotAvailable(err error) bool {
+	return pgErrorCode(err) == "55P03"
+}
+
+// IsLockTimeout reports whether err was caused by lock_timeout being exceeded while waiting for a lock.
+//
+// The server reports lock_timeout with the same SQLSTATE as NOWAIT so they are distinguished by the message. Messages
+// are translated according to lc_messages of the server so IsLockTimeout always reports false unless lc_messages is
+// English or C. Use IsLockNotAvailable when any language must be supported.
+func IsLockTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "55P03" && pgErr.Message == "canceling statement due to lock timeout"
+}
+
+// IsDeadlock reports whether err is a deadlock_detected (40P01) error. The server aborted the transaction to break a
+// deadlock with another transaction. The transaction can be retried.
+func IsDeadlock(err error) bool {
+	return pgErrorCode(err) == "40P01"
+}
+
+// IsSerializationFailure reports whether err is a serialization_failure (40001) error. The transaction could not be
+// serialized with concurrent transactions and can be retried. CockroachDB uses this error for all transaction retry
+// errors. See RetryTxFunc.
+func IsSerializationFailure(err error) bool {
+	return pgErrorCode(err) == "40001"
+}
+
+// IsStatementTimeout reports whether err was caused by statement_timeout being exceeded. It does not include a query
+// canceled by context cancellation or pg_cancel_backend.
+//
+// The server reports statement_timeout with the same SQLSTATE as a cancel request so they are distinguished by the
+// message. Messages are translated according to lc_messages of the server so IsStatementTimeout always reports false
+// unless lc_messages is English or C.
+func IsStatementTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014" && pgErr.Message == "canceling statement due to statement timeout"
+}
+
+// pgErrorCode returns the SQLSTATE of err if it is a *pgconn.PgError.
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
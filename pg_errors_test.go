@@ -0,0 +1,61 @@
+package pgx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorPredicates(t *testing.T) {
+	t.Parallel()
+
+	lockTimeout := &pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout"}
+	nowait := &pgconn.PgError{Code: "55P03", Message: `could not obtain lock on row in relation "t"`}
+	deadlock := &pgconn.PgError{Code: "40P01", Message: "deadlock detected"}
+	statementTimeout := &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}
+	userCancel := &pgconn.PgError{Code: "57014", Message: "canceling statement due to user request"}
+	serializationFailure := &pgconn.PgError{Code: "40001", Message: "could not serialize access due to concurrent update"}
+	other := errors.New("other")
+
+	assert.True(t, pgx.IsLockNotAvailable(lockTimeout))
+	assert.True(t, pgx.IsLockNotAvailable(fmt.Errorf("wrapped: %w", nowait)))
+	assert.False(t, pgx.IsLockNotAvailable(deadlock))
+	assert.False(t, pgx.IsLockNotAvailable(other))
+
+	assert.True(t, pgx.IsLockTimeout(lockTimeout))
+	assert.False(t, pgx.IsLockTimeout(nowait))
+
+	assert.True(t, pgx.IsDeadlock(fmt.Errorf("wrapped: %w", deadlock)))
+	assert.False(t, pgx.IsDeadlock(lockTimeout))
+	assert.False(t, pgx.IsDeadlock(nil))
+
+	assert.True(t, pgx.IsStatementTimeout(statementTimeout))
+	assert.False(t, pgx.IsStatementTimeout(userCancel))
+	assert.False(t, pgx.IsStatementTimeout(other))
+
+	assert.True(t, pgx.IsSerializationFailure(fmt.Errorf("wrapped: %w", serializationFailure)))
+	assert.False(t, pgx.IsSerializationFailure(deadlock))
+	assert.False(t, pgx.IsSerializationFailure(nil))
+}
+
+func TestIsStatementTimeoutFromServer(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	_, err := conn.Exec(context.Background(), "set statement_timeout = 50")
+	require.NoError(t, err)
+
+	_, err = conn.Exec(context.Background(), "select pg_sleep(1)")
+	require.Error(t, err)
+	assert.True(t, pgx.IsStatementTimeout(err))
+	assert.False(t, pgx.IsLockNotAvailable(err))
+}
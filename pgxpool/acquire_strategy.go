@@ -0,0 +1,44 @@
+package pgxpool
+
+import (
+	"github.com/jackc/puddle"
+)
+
+// AcquireStrategy determines which idle connection Acquire returns when more than one is available.
+type AcquireStrategy int
+
+const (
+	// AcquireLIFO returns the most recently released connection. Load concentrates on a small set of connections
+	// which keeps their caches warm and lets the rest stay idle long enough to be closed by MaxConnIdleTime.
+	AcquireLIFO AcquireStrategy = iota
+
+	// AcquireFIFO returns the connection that has been idle the longest. Load is spread evenly over all connections in
+	// the pool. Finding that connection takes time proportional to the number of idle connections.
+	AcquireFIFO
+)
+
+// acquireLongestIdle acquires the connection that has been idle the longest. It returns nil if no connection is idle.
+// The idle connections are all taken from the pool while the longest idle one is found. Concurrent calls are
+// serialized so they wait for that instead of finding the pool empty and establishing a new connection.
+func (p *Pool) acquireLongestIdle() *puddle.Resource {
+	p.fifoMux.Lock()
+	defer p.fifoMux.Unlock()
+
+	resources := p.p.AcquireAllIdle()
+	if len(resources) == 0 {
+		return nil
+	}
+
+	oldest := 0
+	for i, res := range resources {
+		if res.LastUsedNanotime() < resources[oldest].LastUsedNanotime() {
+			oldest = i
+		}
+	}
+	for i, res := range resources {
+		if i != oldest {
+			res.ReleaseUnused()
+		}
+	}
+	return resources[oldest]
+}
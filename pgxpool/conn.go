@@ -13,6 +13,7 @@ import (
 type Conn struct {
 	res *puddle.Resource
 	p   *Pool
+	pin *pinnedConn
 }
 
 // Release returns c to the pool it was acquired from. Once Release has been called, other methods must not be called.
@@ -22,23 +23,63 @@ func (c *Conn) Release() {
 		return
 	}
 
+	if c.pin != nil {
+		c.res = nil
+		c.pin.unlock()
+		return
+	}
+
 	conn := c.Conn()
 	res := c.res
 	c.res = nil
 
+	if c.p.beforeRelease != nil && !c.p.beforeRelease(conn) {
+		res.Destroy()
+		return
+	}
+
 	now := time.Now()
-	if conn.IsClosed() || conn.PgConn().IsBusy() || conn.PgConn().TxStatus() != 'I' || (now.Sub(res.CreationTime()) > c.p.maxConnLifetime) {
+	if conn.IsClosed() || conn.PgConn().IsBusy() || conn.PgConn().TxStatus() != 'I' || (now.Sub(res.CreationTime()) > c.p.maxConnLifetime) || c.p.isStale(res) {
 		res.Destroy()
 		return
 	}
 
-	if c.p.afterRelease == nil {
+	cr := res.Value().(*connResource)
+	needsTenantReset := c.p.tenantResetNeeded(cr)
+	cr.tenantScoped = false
+
+	if c.p.afterRelease == nil && c.p.resetSession == SessionResetNone && !needsTenantReset {
 		res.Release()
 		return
 	}
 
 	go func() {
-		if c.p.afterRelease(conn) {
+		if needsTenantReset {
+			if err := resetTenant(conn, cr); err != nil {
+				res.Destroy()
+				return
+			}
+		}
+
+		if err := resetSession(conn, c.p.resetSession); err != nil {
+			res.Destroy()
+			return
+		}
+
+		if c.p.resetSession == SessionResetDiscardAll {
+			// DISCARD ALL deallocated the statements registered with PrepareAll.
+			cr.prepareGen = 0
+			cr.prepareStmts = nil
+			ctx, cancel := context.WithTimeout(context.Background(), sessionResetTimeout)
+			_, err := c.p.prepareConn(ctx, cr, false)
+			cancel()
+			if err != nil {
+				res.Destroy()
+				return
+			}
+		}
+
+		if c.p.afterRelease == nil || c.p.afterRelease(conn) {
 			res.Release()
 		} else {
 			res.Destroy()
@@ -70,6 +111,10 @@ func (c *Conn) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNam
 	return c.Conn().CopyFrom(ctx, tableName, columnNames, rowSrc)
 }
 
+func (c *Conn) CopyFromWithProgress(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource, progress *pgx.CopyProgressReporter) (int64, error) {
+	return c.Conn().CopyFromWithProgress(ctx, tableName, columnNames, rowSrc, progress)
+}
+
 // Begin starts a transaction block from the *Conn without explicitly setting a transaction mode (see BeginTx with TxOptions if transaction mode is required).
 func (c *Conn) Begin(ctx context.Context) (pgx.Tx, error) {
 	return c.Conn().Begin(ctx)
@@ -88,6 +133,21 @@ func (c *Conn) BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(
 	return c.Conn().BeginTxFunc(ctx, txOptions, f)
 }
 
+// QueryCount calls QueryCount on the underlying connection. See pgx.Conn.QueryCount.
+func (c *Conn) QueryCount(ctx context.Context, sql string, args ...interface{}) (int64, error) {
+	return c.Conn().QueryCount(ctx, sql, args...)
+}
+
+// RetryTxFunc calls RetryTxFunc on the underlying connection. See pgx.Conn.RetryTxFunc.
+func (c *Conn) RetryTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error {
+	return c.Conn().RetryTxFunc(ctx, txOptions, f)
+}
+
+// WithTempTable calls WithTempTable on the underlying connection. See pgx.Conn.WithTempTable.
+func (c *Conn) WithTempTable(ctx context.Context, columns []pgx.TempTableColumn, rowSrc pgx.CopyFromSource, f func(table pgx.Identifier) error) error {
+	return c.Conn().WithTempTable(ctx, columns, rowSrc, f)
+}
+
 func (c *Conn) Ping(ctx context.Context) error {
 	return c.Conn().Ping(ctx)
 }
@@ -0,0 +1,98 @@
+package pgxpool
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const defaultMaxConnectBackoff = time.Minute
+
+// connectLimiter limits how many connections are established at once and delays new connections after consecutive
+// failures so that a pool that empties in a burst does not overwhelm the server or its authentication provider.
+type connectLimiter struct {
+	slots chan struct{} // nil when the number of concurrent connects is not limited
+
+	backoff    time.Duration // delay after the first failure, doubled for each further failure; 0 disables
+	maxBackoff time.Duration
+
+	mux      sync.Mutex
+	failures int
+}
+
+func newConnectLimiter(maxConnecting int32, backoff, maxBackoff time.Duration) *connectLimiter {
+	cl := &connectLimiter{backoff: backoff, maxBackoff: maxBackoff}
+	if maxConnecting > 0 {
+		cl.slots = make(chan struct{}, maxConnecting)
+	}
+	if cl.maxBackoff <= 0 {
+		cl.maxBackoff = defaultMaxConnectBackoff
+	}
+	return cl
+}
+
+// acquire waits out any backoff from previous failures and then for a free connect slot. done must be called with the
+// result of the connect if acquire succeeds.
+func (cl *connectLimiter) acquire(ctx context.Context) error {
+	if d := cl.delay(); d > 0 {
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if cl.slots == nil {
+		return nil
+	}
+	select {
+	case cl.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// done releases the connect slot and records whether the connect failed. A connect canceled by its context is not
+// counted as a failure.
+func (cl *connectLimiter) done(ctx context.Context, err error) {
+	if cl.slots != nil {
+		<-cl.slots
+	}
+
+	cl.mux.Lock()
+	if err == nil {
+		cl.failures = 0
+	} else if ctx.Err() == nil {
+		cl.failures++
+	}
+	cl.mux.Unlock()
+}
+
+// delay returns how long to wait before connecting. It is a random duration between half and all of the backoff for
+// the current number of consecutive failures so that waiting connects do not all retry at the same moment.
+func (cl *connectLimiter) delay() time.Duration {
+	if cl.backoff <= 0 {
+		return 0
+	}
+
+	cl.mux.Lock()
+	failures := cl.failures
+	cl.mux.Unlock()
+	if failures == 0 {
+		return 0
+	}
+
+	d := cl.backoff
+	for i := 1; i < failures && d < cl.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > cl.maxBackoff {
+		d = cl.maxBackoff
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
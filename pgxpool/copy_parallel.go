@@ -0,0 +1,180 @@
+package pgxpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// ErrCopyShardAborted is the error of a shard of CopyFromParallel that was aborted because another shard or the source
+// failed.
+var ErrCopyShardAborted = errors.New("copy aborted because another shard failed")
+
+// CopyFromParallelError is returned by CopyFromParallel when one or more shards failed.
+type CopyFromParallelError struct {
+	// ShardErrors has the error of each shard. It is nil for shards that succeeded and ErrCopyShardAborted for shards
+	// that were aborted because another shard failed.
+	ShardErrors []error
+
+	// RowsCopied is the number of rows copied by shards that succeeded before the failure.
+	RowsCopied int64
+}
+
+func (e *CopyFromParallelError) Error() string {
+	shard, err := e.firstError()
+	failed := 0
+	for _, err := range e.ShardErrors {
+		if err != nil && err != ErrCopyShardAborted {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d copy shards failed: shard %d: %v", failed, len(e.ShardErrors), shard, err)
+}
+
+// Unwrap returns the error of the first shard that failed.
+func (e *CopyFromParallelError) Unwrap() error {
+	_, err := e.firstError()
+	return err
+}
+
+func (e *CopyFromParallelError) firstError() (int, error) {
+	for i, err := range e.ShardErrors {
+		if err != nil && err != ErrCopyShardAborted {
+			return i, err
+		}
+	}
+	return 0, nil
+}
+
+// copyShardSource is a pgx.CopyFromSource that reads rows distributed by CopyFromParallel.
+type copyShardSource struct {
+	rows    <-chan []interface{}
+	abort   <-chan struct{}
+	values  []interface{}
+	aborted bool
+}
+
+func (s *copyShardSource) Next() bool {
+	select {
+	case <-s.abort:
+		s.aborted = true
+		return false
+	default:
+	}
+
+	select {
+	case values, ok := <-s.rows:
+		if ok {
+			s.values = values
+			return true
+		}
+		// The source closes abort before rows when it fails so it is only safe to complete if abort is still open.
+		select {
+		case <-s.abort:
+			s.aborted = true
+		default:
+		}
+		return false
+	case <-s.abort:
+		s.aborted = true
+		return false
+	}
+}
+
+func (s *copyShardSource) Values() ([]interface{}, error) {
+	return s.values, nil
+}
+
+func (s *copyShardSource) Err() error {
+	if s.aborted {
+		return ErrCopyShardAborted
+	}
+	return nil
+}
+
+// CopyFromParallel copies the rows of rowSrc into tableName using shards connections concurrently. Each shard acquires
+// a connection and performs its own COPY into the same table. rowSrc is read by a single goroutine and its rows are
+// distributed to the shards as they are ready for more data. As the values of a row are used after rowSrc advances,
+// rowSrc must not reuse the values it returns. shards should not exceed the MaxConns of the pool. It returns the number
+// of rows copied.
+//
+// Each shard is a separate statement so the copy is not atomic. If any shard fails, the remaining shards are aborted
+// and a *CopyFromParallelError with the error of each shard is returned. Shards that already completed are not rolled
+// back. If rowSrc fails, all shards are aborted and its error is returned.
+func (p *Pool) CopyFromParallel(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource, shards int) (int64, error) {
+	if shards < 1 {
+		shards = 1
+	}
+
+	rows := make(chan []interface{}, shards)
+	abort := make(chan struct{})
+	var abortOnce sync.Once
+	abortAll := func() { abortOnce.Do(func() { close(abort) }) }
+
+	shardErrors := make([]error, shards)
+	shardRows := make([]int64, shards)
+	var wg sync.WaitGroup
+	for i := 0; i < shards; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			src := &copyShardSource{rows: rows, abort: abort}
+			n, err := p.CopyFrom(ctx, tableName, columnNames, src)
+			if src.aborted {
+				err = ErrCopyShardAborted
+			} else if err != nil {
+				abortAll()
+			}
+			shardRows[i] = n
+			shardErrors[i] = err
+		}(i)
+	}
+
+	var srcErr error
+dispatch:
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			srcErr = err
+			break
+		}
+
+		select {
+		case rows <- values:
+		case <-abort:
+			break dispatch
+		}
+	}
+	if srcErr == nil {
+		srcErr = rowSrc.Err()
+	}
+	if srcErr != nil {
+		abortAll()
+	}
+	close(rows)
+
+	wg.Wait()
+
+	var total int64
+	failed := false
+	for i := range shardErrors {
+		if shardErrors[i] == nil {
+			total += shardRows[i]
+		} else if shardErrors[i] != ErrCopyShardAborted {
+			failed = true
+		}
+	}
+
+	if failed {
+		return total, &CopyFromParallelError{ShardErrors: shardErrors, RowsCopied: total}
+	}
+	if srcErr != nil {
+		return total, srcErr
+	}
+
+	return total, nil
+}
@@ -0,0 +1,137 @@
+package pgxpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// copyPartitionBatchRows is the number of rows of a partition that CopyFromPartitioned buffers before copying them.
+const copyPartitionBatchRows = 10000
+
+// CopyPartitionFunc returns the table a row with values should be copied into.
+type CopyPartitionFunc func(values []interface{}) (pgx.Identifier, error)
+
+// copyPartitionBatch is a batch of rows to copy into a single partition.
+type copyPartitionBatch struct {
+	table pgx.Identifier
+	rows  [][]interface{}
+}
+
+// CopyFromPartitioned copies the rows of rowSrc directly into the partitions of a partitioned table, avoiding the cost
+// of the server routing each row to its partition on very large loads. partitionFor returns the partition of each row.
+// It may also return per-partition staging tables instead of the partitions themselves.
+//
+// The rows of each partition are buffered and copied in batches of up to 10,000 rows by workers connections
+// concurrently, so memory use grows with the number of partitions. As the values of a row are used after rowSrc
+// advances, rowSrc must not reuse the values it returns. workers should not exceed the MaxConns of the pool. It returns
+// the number of rows copied.
+//
+// Each batch is a separate statement so the copy is not atomic. If a batch fails, no further batches are copied and its
+// error is returned wrapped with the name of the partition. Batches that already completed are not rolled back. The row
+// reported by pgx.CopyFromErrorRow is relative to the start of the failed batch.
+func (p *Pool) CopyFromPartitioned(ctx context.Context, columnNames []string, rowSrc pgx.CopyFromSource, partitionFor CopyPartitionFunc, workers int) (int64, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	batches := make(chan copyPartitionBatch)
+	abort := make(chan struct{})
+	var abortOnce sync.Once
+	var errMux sync.Mutex
+	var firstErr error
+	fail := func(err error) {
+		errMux.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		errMux.Unlock()
+		abortOnce.Do(func() { close(abort) })
+	}
+
+	var copied int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				select {
+				case <-abort:
+					continue
+				default:
+				}
+
+				n, err := p.CopyFrom(ctx, b.table, columnNames, pgx.CopyFromRows(b.rows))
+				atomic.AddInt64(&copied, n)
+				if err != nil {
+					fail(fmt.Errorf("copy into partition %s: %w", b.table.Sanitize(), err))
+				}
+			}
+		}()
+	}
+
+	send := func(b *copyPartitionBatch) bool {
+		select {
+		case batches <- *b:
+			return true
+		case <-abort:
+			return false
+		}
+	}
+
+	pending := make(map[string]*copyPartitionBatch)
+	var order []string
+	completed := true
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			fail(err)
+			completed = false
+			break
+		}
+
+		table, err := partitionFor(values)
+		if err != nil {
+			fail(err)
+			completed = false
+			break
+		}
+
+		key := table.Sanitize()
+		b, ok := pending[key]
+		if !ok {
+			b = &copyPartitionBatch{table: table}
+			pending[key] = b
+			order = append(order, key)
+		}
+		b.rows = append(b.rows, values)
+
+		if len(b.rows) >= copyPartitionBatchRows {
+			if !send(b) {
+				completed = false
+				break
+			}
+			b.rows = nil
+		}
+	}
+	if completed {
+		if err := rowSrc.Err(); err != nil {
+			fail(err)
+		} else {
+			for _, key := range order {
+				if b := pending[key]; len(b.rows) > 0 && !send(b) {
+					break
+				}
+			}
+		}
+	}
+	close(batches)
+
+	wg.Wait()
+
+	return copied, firstErr
+}
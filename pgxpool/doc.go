@@ -21,5 +21,11 @@ connection with `ConnectConfig`.
     }
 
     pool, err := pgxpool.ConnectConfig(context.Background(), config)
+
+Multiple Hosts
+
+When multiple hosts are configured, a host that fails to connect is put on cooldown with exponential backoff from one
+second up to one minute. New connections try healthy hosts first and only try hosts on cooldown if all healthy hosts
+fail. A host is considered healthy again as soon as a connection to it succeeds.
 */
 package pgxpool
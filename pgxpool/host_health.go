@@ -0,0 +1,152 @@
+package pgxpool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/nappspt/schemapgx/v4"
+)
+
+const (
+	hostMinBackoff = time.Second
+	hostMaxBackoff = time.Minute
+)
+
+// hostHealth tracks hosts that failed to connect. A failed host is put on cooldown with exponential backoff. Hosts on
+// cooldown are tried after all healthy hosts so new connections do not wait for a dead host to time out.
+type hostHealth struct {
+	mux   sync.Mutex
+	hosts map[string]*hostFailure
+}
+
+type hostFailure struct {
+	backoff time.Duration
+	until   time.Time
+}
+
+// hostGroup is the primary config or fallbacks for a single host. A host has a config for each TLS mode to try.
+type hostGroup struct {
+	key     string
+	configs []*pgconn.FallbackConfig
+}
+
+func newHostHealth() *hostHealth {
+	return &hostHealth{hosts: make(map[string]*hostFailure)}
+}
+
+// reset forgets all failures. It is used when the configured hosts change.
+func (hh *hostHealth) reset() {
+	hh.mux.Lock()
+	hh.hosts = make(map[string]*hostFailure)
+	hh.mux.Unlock()
+}
+
+func (hh *hostHealth) failed(key string, now time.Time) {
+	hh.mux.Lock()
+	defer hh.mux.Unlock()
+
+	hf, ok := hh.hosts[key]
+	if !ok {
+		hf = &hostFailure{backoff: hostMinBackoff}
+		hh.hosts[key] = hf
+	} else {
+		hf.backoff *= 2
+		if hf.backoff > hostMaxBackoff {
+			hf.backoff = hostMaxBackoff
+		}
+	}
+	hf.until = now.Add(hf.backoff)
+}
+
+func (hh *hostHealth) succeeded(key string) {
+	hh.mux.Lock()
+	delete(hh.hosts, key)
+	hh.mux.Unlock()
+}
+
+// order returns groups with healthy hosts first in their configured order followed by hosts on cooldown ordered by
+// when their cooldown ends.
+func (hh *hostHealth) order(groups []hostGroup, now time.Time) []hostGroup {
+	hh.mux.Lock()
+	defer hh.mux.Unlock()
+
+	ordered := make([]hostGroup, 0, len(groups))
+	var cooling []hostGroup
+	for _, g := range groups {
+		if hf, ok := hh.hosts[g.key]; ok && now.Before(hf.until) {
+			i := len(cooling)
+			for i > 0 && hh.hosts[cooling[i-1].key].until.After(hf.until) {
+				i--
+			}
+			cooling = append(cooling, hostGroup{})
+			copy(cooling[i+1:], cooling[i:])
+			cooling[i] = g
+		} else {
+			ordered = append(ordered, g)
+		}
+	}
+
+	return append(ordered, cooling...)
+}
+
+func hostGroups(config *pgx.ConnConfig) []hostGroup {
+	all := make([]*pgconn.FallbackConfig, 0, len(config.Fallbacks)+1)
+	all = append(all, &pgconn.FallbackConfig{Host: config.Host, Port: config.Port, TLSConfig: config.TLSConfig})
+	all = append(all, config.Fallbacks...)
+
+	var groups []hostGroup
+	for _, fc := range all {
+		key := net.JoinHostPort(fc.Host, strconv.Itoa(int(fc.Port)))
+		if len(groups) > 0 && groups[len(groups)-1].key == key {
+			groups[len(groups)-1].configs = append(groups[len(groups)-1].configs, fc)
+		} else {
+			groups = append(groups, hostGroup{key: key, configs: []*pgconn.FallbackConfig{fc}})
+		}
+	}
+
+	return groups
+}
+
+// connectHealthiest connects to the hosts in config preferring hosts that have not recently failed. A config with a
+// single host is connected to directly.
+func (hh *hostHealth) connectHealthiest(ctx context.Context, config *pgx.ConnConfig) (*pgx.Conn, error) {
+	groups := hostGroups(config)
+	if len(groups) < 2 {
+		return pgx.ConnectConfig(ctx, config)
+	}
+
+	var err error
+	for _, g := range hh.order(groups, time.Now()) {
+		hostConfig := config.Copy()
+		hostConfig.Host = g.configs[0].Host
+		hostConfig.Port = g.configs[0].Port
+		hostConfig.TLSConfig = g.configs[0].TLSConfig
+		hostConfig.Fallbacks = g.configs[1:]
+
+		var conn *pgx.Conn
+		conn, err = pgx.ConnectConfig(ctx, hostConfig)
+		if err == nil {
+			hh.succeeded(g.key)
+			return conn, nil
+		}
+
+		// The server was reached but rejected the connection. Other hosts are not tried as they share the credentials.
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && (pgErr.Code == "28P01" || pgErr.Code == "28000") {
+			return nil, err
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		hh.failed(g.key, time.Now())
+	}
+
+	return nil, err
+}
@@ -0,0 +1,304 @@
+package pgxpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/nappspt/schemapgx/v4"
+)
+
+var listenerReconnectDelay = time.Second
+
+// Listener receives notifications for all subscribed channels on a single connection dedicated to LISTEN and
+// dispatches them to the subscribers. This allows any number of pool users to receive notifications without each
+// holding a pooled connection in a WaitForNotification loop.
+//
+// The connection is acquired from the pool when the first subscription is made and returned when the last
+// subscription is removed. If the connection is lost a new one is acquired and all channels are listened to again.
+// Notifications sent while reconnecting are lost. Use SubscribeWithGap to be told when this happens.
+type Listener struct {
+	pool *Pool
+
+	mux           sync.Mutex
+	subscriptions map[string][]*subscription
+	gaps          map[string]error // channels that were listening when the connection was lost
+	started       bool
+
+	wake      chan struct{}
+	closeChan chan struct{}
+	doneChan  chan struct{}
+}
+
+type subscription struct {
+	f     func(*pgconn.Notification)
+	onGap func(error)
+}
+
+func newListener(p *Pool) *Listener {
+	return &Listener{
+		pool:          p,
+		subscriptions: make(map[string][]*subscription),
+		gaps:          make(map[string]error),
+		wake:          make(chan struct{}, 1),
+		closeChan:     make(chan struct{}),
+		doneChan:      make(chan struct{}),
+	}
+}
+
+// Listener returns the Listener for the pool. Every call returns the same Listener.
+func (p *Pool) Listener() *Listener {
+	p.listenerMux.Lock()
+	defer p.listenerMux.Unlock()
+
+	if p.listener == nil {
+		p.listener = newListener(p)
+	}
+	return p.listener
+}
+
+// Subscribe calls f with every notification received on channel until the returned unsubscribe function is called. f
+// is called from the goroutine that reads from the listening connection. It must not block or notifications on all
+// channels will be delayed.
+func (l *Listener) Subscribe(channel string, f func(*pgconn.Notification)) (unsubscribe func()) {
+	return l.SubscribeWithGap(channel, f, nil)
+}
+
+// SubscribeWithGap is the same as Subscribe, but onGap is also called after the listening connection was lost and
+// channel has been listened to again on a new connection. Notifications sent in between were lost so onGap can be used
+// to resynchronize, such as by reloading state from the database. err is the error that broke the connection. onGap is
+// called from the same goroutine as f.
+func (l *Listener) SubscribeWithGap(channel string, f func(*pgconn.Notification), onGap func(err error)) (unsubscribe func()) {
+	sub := &subscription{f: f, onGap: onGap}
+
+	l.mux.Lock()
+	l.subscriptions[channel] = append(l.subscriptions[channel], sub)
+	if !l.started {
+		l.started = true
+		go l.run()
+	}
+	l.mux.Unlock()
+	l.signal()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { l.unsubscribe(channel, sub) })
+	}
+}
+
+func (l *Listener) unsubscribe(channel string, sub *subscription) {
+	l.mux.Lock()
+	subs := l.subscriptions[channel]
+	for i := range subs {
+		if subs[i] == sub {
+			subs = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(l.subscriptions, channel)
+	} else {
+		l.subscriptions[channel] = subs
+	}
+	l.mux.Unlock()
+	l.signal()
+}
+
+// signal interrupts the wait for notifications so the listening connection picks up subscription changes.
+func (l *Listener) signal() {
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (l *Listener) close() {
+	close(l.closeChan)
+
+	l.mux.Lock()
+	started := l.started
+	l.mux.Unlock()
+
+	if started {
+		<-l.doneChan
+	}
+}
+
+func (l *Listener) hasSubscriptions() bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return len(l.subscriptions) > 0
+}
+
+func (l *Listener) run() {
+	defer close(l.doneChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-l.closeChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		if !l.hasSubscriptions() {
+			select {
+			case <-l.wake:
+				continue
+			case <-l.closeChan:
+				return
+			}
+		}
+
+		err := l.listen(ctx)
+		if err == nil {
+			continue
+		}
+
+		select {
+		case <-l.closeChan:
+			return
+		case <-time.After(listenerReconnectDelay):
+		}
+	}
+}
+
+// listen acquires a connection and dispatches notifications until there are no more subscriptions, the listener is
+// closed, or an error occurs.
+func (l *Listener) listen(ctx context.Context) error {
+	c, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// The connection has session state from LISTEN so it must not be returned to the pool for reuse.
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		c.Conn().Close(closeCtx)
+		cancel()
+		c.Release()
+	}()
+
+	conn := c.Conn()
+	listening := make(map[string]struct{})
+
+	for {
+		select {
+		case <-l.closeChan:
+			return nil
+		default:
+		}
+
+		err := l.syncChannels(ctx, conn, listening)
+		if err != nil {
+			l.recordGaps(listening, err)
+			return err
+		}
+		if len(listening) == 0 {
+			return nil
+		}
+
+		waitCtx, cancelWait := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-l.wake:
+				cancelWait()
+			case <-waitCtx.Done():
+			}
+		}()
+
+		n, err := conn.WaitForNotification(waitCtx)
+		interrupted := waitCtx.Err() != nil
+		cancelWait()
+		if err != nil {
+			if interrupted && !conn.IsClosed() {
+				continue
+			}
+			l.recordGaps(listening, err)
+			return err
+		}
+
+		l.dispatch(n)
+	}
+}
+
+// syncChannels issues LISTEN and UNLISTEN statements so that listening matches the subscribed channels.
+func (l *Listener) syncChannels(ctx context.Context, conn *pgx.Conn, listening map[string]struct{}) error {
+	var listen, unlisten []string
+
+	l.mux.Lock()
+	for channel := range l.subscriptions {
+		if _, ok := listening[channel]; !ok {
+			listen = append(listen, channel)
+		}
+	}
+	for channel := range listening {
+		if _, ok := l.subscriptions[channel]; !ok {
+			unlisten = append(unlisten, channel)
+		}
+	}
+	l.mux.Unlock()
+
+	for _, channel := range listen {
+		_, err := conn.Exec(ctx, "listen "+pgx.Identifier{channel}.Sanitize())
+		if err != nil {
+			return err
+		}
+		listening[channel] = struct{}{}
+		l.fillGap(channel)
+	}
+
+	for _, channel := range unlisten {
+		_, err := conn.Exec(ctx, "unlisten "+pgx.Identifier{channel}.Sanitize())
+		if err != nil {
+			return err
+		}
+		delete(listening, channel)
+	}
+
+	return nil
+}
+
+// recordGaps records that the channels in listening stopped being listened to because of err.
+func (l *Listener) recordGaps(listening map[string]struct{}, err error) {
+	l.mux.Lock()
+	for channel := range listening {
+		if _, ok := l.gaps[channel]; !ok {
+			l.gaps[channel] = err
+		}
+	}
+	l.mux.Unlock()
+}
+
+// fillGap calls the onGap functions of the subscribers to channel if there was a gap before it was listened to again.
+func (l *Listener) fillGap(channel string) {
+	l.mux.Lock()
+	err, ok := l.gaps[channel]
+	delete(l.gaps, channel)
+	var subs []*subscription
+	if ok {
+		subs = make([]*subscription, len(l.subscriptions[channel]))
+		copy(subs, l.subscriptions[channel])
+	}
+	l.mux.Unlock()
+
+	for _, sub := range subs {
+		if sub.onGap != nil {
+			sub.onGap(err)
+		}
+	}
+}
+
+func (l *Listener) dispatch(n *pgconn.Notification) {
+	l.mux.Lock()
+	subs := make([]*subscription, len(l.subscriptions[n.Channel]))
+	copy(subs, l.subscriptions[n.Channel])
+	l.mux.Unlock()
+
+	for _, sub := range subs {
+		sub.f(n)
+	}
+}
@@ -0,0 +1,145 @@
+package pgxpool_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/nappspt/schemapgx/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+func waitForPayload(t *testing.T, ch chan string, expected string) {
+	select {
+	case payload := <-ch:
+		require.Equal(t, expected, payload)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for notification %q", expected)
+	}
+}
+
+func TestListenerSubscribe(t *testing.T) {
+	t.Parallel()
+
+	pool, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	listener := pool.Listener()
+	require.True(t, listener == pool.Listener())
+
+	a := make(chan string, 10)
+	b := make(chan string, 10)
+	unsubscribeA := listener.Subscribe("listener_a", func(n *pgconn.Notification) { a <- n.Payload })
+	unsubscribeB := listener.Subscribe("listener_b", func(n *pgconn.Notification) { b <- n.Payload })
+	defer unsubscribeB()
+
+	// Subscribing is asynchronous so keep notifying until the first notification arrives.
+	deadline := time.Now().Add(5 * time.Second)
+	for received := false; !received; {
+		require.NoError(t, pool.Notify(context.Background(), "listener_a", "first"))
+		select {
+		case payload := <-a:
+			require.Equal(t, "first", payload)
+			received = true
+		case <-time.After(100 * time.Millisecond):
+			require.True(t, time.Now().Before(deadline), "timed out waiting for subscription")
+		}
+	}
+	require.NoError(t, pool.Notify(context.Background(), "listener_b", "second"))
+	waitForPayload(t, b, "second")
+
+	// Notifications are delivered in order so any extra "first" notifications have been received by now.
+	for len(a) > 0 {
+		<-a
+	}
+
+	unsubscribeA()
+	unsubscribeA()
+
+	require.NoError(t, pool.Notify(context.Background(), "listener_a", "ignored"))
+	require.NoError(t, pool.Notify(context.Background(), "listener_b", "third"))
+	waitForPayload(t, b, "third")
+	require.Len(t, a, 0)
+}
+
+func TestListenerReconnects(t *testing.T) {
+	t.Parallel()
+
+	pool, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	received := make(chan string, 100)
+	unsubscribe := pool.Listener().Subscribe("listener_reconnect", func(n *pgconn.Notification) { received <- n.Payload })
+	defer unsubscribe()
+
+	notifyUntilReceived := func(payload string) {
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			require.NoError(t, pool.Notify(context.Background(), "listener_reconnect", payload))
+			select {
+			case p := <-received:
+				if p == payload {
+					return
+				}
+			case <-time.After(100 * time.Millisecond):
+				require.True(t, time.Now().Before(deadline), "timed out waiting for %q", payload)
+			}
+		}
+	}
+
+	notifyUntilReceived("before")
+
+	_, err = pool.Exec(context.Background(), `select pg_terminate_backend(pid) from pg_stat_activity where pid <> pg_backend_pid() and query = 'listen "listener_reconnect"'`)
+	require.NoError(t, err)
+
+	notifyUntilReceived("after")
+}
+
+func TestListenerSubscribeWithGap(t *testing.T) {
+	t.Parallel()
+
+	pool, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	received := make(chan string, 100)
+	gaps := make(chan error, 10)
+	unsubscribe := pool.Listener().SubscribeWithGap("listener_gap",
+		func(n *pgconn.Notification) { received <- n.Payload },
+		func(err error) { gaps <- err },
+	)
+	defer unsubscribe()
+
+	notifyUntilReceived := func(payload string) {
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			require.NoError(t, pool.Notify(context.Background(), "listener_gap", payload))
+			select {
+			case p := <-received:
+				if p == payload {
+					return
+				}
+			case <-time.After(100 * time.Millisecond):
+				require.True(t, time.Now().Before(deadline), "timed out waiting for %q", payload)
+			}
+		}
+	}
+
+	notifyUntilReceived("before")
+	require.Len(t, gaps, 0)
+
+	_, err = pool.Exec(context.Background(), `select pg_terminate_backend(pid) from pg_stat_activity where pid <> pg_backend_pid() and query = 'listen "listener_gap"'`)
+	require.NoError(t, err)
+
+	notifyUntilReceived("after")
+	select {
+	case err := <-gaps:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("onGap was not called")
+	}
+}
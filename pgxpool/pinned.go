@@ -0,0 +1,128 @@
+package pgxpool
+
+import (
+	"context"
+	"fmt"
+)
+
+// pinnedConn is a connection held out of the pool for a key until it is unpinned.
+type pinnedConn struct {
+	conn *Conn
+
+	// inUse has a value while the connection is acquired with AcquirePinned. It serializes users of the same key.
+	inUse chan struct{}
+}
+
+func (pc *pinnedConn) lock(ctx context.Context) error {
+	select {
+	case pc.inUse <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (pc *pinnedConn) unlock() {
+	<-pc.inUse
+}
+
+// AcquirePinned acquires the connection pinned to key. The first call for a key acquires a connection from the pool
+// and pins it. Later calls with the same key return the same underlying connection until Unpin is called. This allows
+// session state such as temporary tables, settings, and cursors to be used across multiple calls.
+//
+// The returned *Conn must be released with Release as usual, but the connection stays pinned and is not returned to the
+// pool. Only one caller at a time may hold the connection for a key. AcquirePinned blocks until it is released by any
+// previous caller.
+//
+// If the pinned connection has been closed, it is unpinned and an error is returned as its session state is lost. The
+// next call for the key pins a new connection.
+func (p *Pool) AcquirePinned(ctx context.Context, key string) (*Conn, error) {
+	p.pinnedMux.Lock()
+	pc, ok := p.pinned[key]
+	if !ok {
+		pc = &pinnedConn{inUse: make(chan struct{}, 1)}
+		if p.pinned == nil {
+			p.pinned = make(map[string]*pinnedConn)
+		}
+		p.pinned[key] = pc
+	}
+	p.pinnedMux.Unlock()
+
+	err := pc.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pin may have been removed while waiting for the lock.
+	p.pinnedMux.Lock()
+	current := p.pinned[key]
+	p.pinnedMux.Unlock()
+	if current != pc {
+		pc.unlock()
+		return p.AcquirePinned(ctx, key)
+	}
+
+	if pc.conn == nil {
+		pc.conn, err = p.Acquire(ctx)
+		if err != nil {
+			p.removePin(key, pc)
+			pc.unlock()
+			return nil, err
+		}
+	} else if pc.conn.Conn().IsClosed() {
+		p.removePin(key, pc)
+		pc.conn.Release()
+		pc.unlock()
+		return nil, fmt.Errorf("pinned connection for key %q was closed", key)
+	}
+
+	return &Conn{res: pc.conn.res, p: p, pin: pc}, nil
+}
+
+// Unpin releases the connection pinned to key back to the pool. It blocks until the connection is released by any
+// current holder. It does nothing if key is not pinned.
+func (p *Pool) Unpin(ctx context.Context, key string) error {
+	p.pinnedMux.Lock()
+	pc, ok := p.pinned[key]
+	p.pinnedMux.Unlock()
+	if !ok {
+		return nil
+	}
+
+	err := pc.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer pc.unlock()
+
+	p.removePin(key, pc)
+	if pc.conn != nil {
+		pc.conn.Release()
+		pc.conn = nil
+	}
+
+	return nil
+}
+
+func (p *Pool) removePin(key string, pc *pinnedConn) {
+	p.pinnedMux.Lock()
+	if p.pinned[key] == pc {
+		delete(p.pinned, key)
+	}
+	p.pinnedMux.Unlock()
+}
+
+// unpinAll releases all pinned connections back to the pool. It is used when closing the pool as closing blocks until
+// all connections are released.
+func (p *Pool) unpinAll() {
+	p.pinnedMux.Lock()
+	keys := make([]string, 0, len(p.pinned))
+	for key := range p.pinned {
+		keys = append(keys, key)
+	}
+	p.pinnedMux.Unlock()
+
+	for _, key := range keys {
+		p.Unpin(context.Background(), key)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgconn"
@@ -21,7 +22,24 @@ var defaultHealthCheckPeriod = time.Minute
 
 type connResource struct {
 	conn      *pgx.Conn
+	configGen uint64
 	conns     []Conn
+
+	// prepareGen is the generation of the statements registered with PrepareAll that conn has prepared.
+	prepareGen uint64
+
+	// prepareStmts are the statements registered with PrepareAll that conn has prepared by name.
+	prepareStmts map[string]string
+
+	// tenantScoped is set while search_path is set by AcquireTenant. The connection must not be reused until it has
+	// been reset.
+	tenantScoped bool
+
+	// tenantSearchPath is the search_path before AcquireTenant set it. It is restored on release. It is not known if
+	// the result of setting search_path was lost, in which case search_path is reset to its default instead.
+	tenantSearchPath      string
+	tenantSearchPathKnown bool
+
 	poolRows  []poolRow
 	poolRowss []poolRows
 }
@@ -70,17 +88,42 @@ func (cr *connResource) getPoolRows(c *Conn, r pgx.Rows) *poolRows {
 
 // Pool allows for connection reuse.
 type Pool struct {
+	idleAcquireCount int64 // acquires by acquireLongestIdle; accessed atomically so it must stay 64-bit aligned
+
 	p                 *puddle.Pool
+	configMux         sync.RWMutex
 	config            *Config
+	configGen         uint64 // incremented by UpdateConfig
 	beforeConnect     func(context.Context, *pgx.ConnConfig) error
 	afterConnect      func(context.Context, *pgx.Conn) error
 	beforeAcquire     func(context.Context, *pgx.Conn) bool
+	beforeRelease     func(*pgx.Conn) bool
 	afterRelease      func(*pgx.Conn) bool
+	resetSession      SessionReset
+	acquireStrategy   AcquireStrategy
+	fifoMux           sync.Mutex // serializes acquireLongestIdle
 	minConns          int32
 	maxConnLifetime   time.Duration
 	maxConnIdleTime   time.Duration
 	healthCheckPeriod time.Duration
 
+	listenerMux sync.Mutex
+	listener    *Listener
+
+	pinnedMux sync.Mutex
+	pinned    map[string]*pinnedConn
+
+	hostHealth          *hostHealth
+	connectLimiter      *connectLimiter
+	readOnlyTxFailovers int32
+	connBudget          *connBudget // shared with the other pools of a PoolSet; nil when not in a PoolSet
+
+	prepareAllMux sync.Mutex // serializes PrepareAll
+	prepareMux    sync.RWMutex
+	prepareStmts  map[string]string
+	prepareGen    uint64
+	prepareStats  map[string]PrepareLatency
+
 	closeOnce sync.Once
 	closeChan chan struct{}
 }
@@ -102,10 +145,24 @@ type Config struct {
 	// acquired.
 	BeforeAcquire func(context.Context, *pgx.Conn) bool
 
+	// BeforeRelease is called synchronously by Release before any other release processing. It can inspect the state the
+	// previous user left the connection in, which may include an open or failed transaction. It must return true to
+	// continue releasing the connection or false to destroy it.
+	BeforeRelease func(*pgx.Conn) bool
+
 	// AfterRelease is called after a connection is released, but before it is returned to the pool. It must return true to
 	// return the connection to the pool or false to destroy the connection.
 	AfterRelease func(*pgx.Conn) bool
 
+	// ResetSession determines how session state left by the previous user of a connection is cleared when it is
+	// released. This prevents settings, temporary tables, advisory locks, and similar state from one user from affecting
+	// the next. The default is SessionResetNone.
+	ResetSession SessionReset
+
+	// AcquireStrategy determines which idle connection is acquired when more than one is available. The default is
+	// AcquireLIFO.
+	AcquireStrategy AcquireStrategy
+
 	// MaxConnLifetime is the duration since creation after which a connection will be automatically closed.
 	MaxConnLifetime time.Duration
 
@@ -122,6 +179,28 @@ type Config struct {
 	// HealthCheckPeriod is the duration between checks of the health of idle connections.
 	HealthCheckPeriod time.Duration
 
+	// IdleReadPeriod is the duration between reads of the messages the server has sent to idle connections. Reading
+	// them processes notifications and parameter status changes while a connection is idle instead of when it is next
+	// used, and closes connections the server has terminated before they are acquired. 0 disables the reads.
+	IdleReadPeriod time.Duration
+
+	// MaxConnecting is the maximum number of connections that are established at the same time. Further connections
+	// wait for one to finish connecting. This prevents a burst of demand on an empty pool from opening many connections
+	// to the server and its authentication provider at once. 0 means no limit.
+	MaxConnecting int32
+
+	// ConnectBackoff is how long a new connection waits after a connection failed. The wait doubles with each further
+	// consecutive failure up to MaxConnectBackoff and is jittered by up to half. A successful connection resets it. 0
+	// disables the backoff.
+	ConnectBackoff time.Duration
+
+	// MaxConnectBackoff is the longest wait caused by ConnectBackoff. 0 or less uses the default of one minute.
+	MaxConnectBackoff time.Duration
+
+	// ReadOnlyTxFailovers is how many times BeginTxFunc reruns a read only transaction on another connection when the
+	// connection it was running on is lost. See BeginTxFunc. 0 disables failover.
+	ReadOnlyTxFailovers int32
+
 	// If set to true, pool doesn't do any I/O operation on initialization.
 	// And connects to the server only when the pool starts to be used.
 	// The default is false.
@@ -157,6 +236,11 @@ func Connect(ctx context.Context, connString string) (*Pool, error) {
 // ConnectConfig creates a new Pool and immediately establishes one connection. ctx can be used to cancel this initial
 // connection. config must have been created by ParseConfig.
 func ConnectConfig(ctx context.Context, config *Config) (*Pool, error) {
+	return connectConfig(ctx, config, nil)
+}
+
+// connectConfig creates a new Pool whose connections also count against budget if it is not nil.
+func connectConfig(ctx context.Context, config *Config, budget *connBudget) (*Pool, error) {
 	// Default values are set in ParseConfig. Enforce initial creation by ParseConfig rather than setting defaults from
 	// zero values.
 	if !config.createdByParseConfig {
@@ -164,30 +248,56 @@ func ConnectConfig(ctx context.Context, config *Config) (*Pool, error) {
 	}
 
 	p := &Pool{
-		config:            config,
-		beforeConnect:     config.BeforeConnect,
-		afterConnect:      config.AfterConnect,
-		beforeAcquire:     config.BeforeAcquire,
-		afterRelease:      config.AfterRelease,
-		minConns:          config.MinConns,
-		maxConnLifetime:   config.MaxConnLifetime,
-		maxConnIdleTime:   config.MaxConnIdleTime,
-		healthCheckPeriod: config.HealthCheckPeriod,
-		closeChan:         make(chan struct{}),
+		config:              config,
+		beforeConnect:       config.BeforeConnect,
+		afterConnect:        config.AfterConnect,
+		beforeAcquire:       config.BeforeAcquire,
+		beforeRelease:       config.BeforeRelease,
+		afterRelease:        config.AfterRelease,
+		resetSession:        config.ResetSession,
+		acquireStrategy:     config.AcquireStrategy,
+		minConns:            config.MinConns,
+		maxConnLifetime:     config.MaxConnLifetime,
+		maxConnIdleTime:     config.MaxConnIdleTime,
+		healthCheckPeriod:   config.HealthCheckPeriod,
+		hostHealth:          newHostHealth(),
+		connectLimiter:      newConnectLimiter(config.MaxConnecting, config.ConnectBackoff, config.MaxConnectBackoff),
+		readOnlyTxFailovers: config.ReadOnlyTxFailovers,
+		connBudget:          budget,
+		closeChan:           make(chan struct{}),
 	}
 
 	p.p = puddle.NewPool(
-		func(ctx context.Context) (interface{}, error) {
+		func(ctx context.Context) (res interface{}, err error) {
+			if p.connBudget != nil {
+				if err := p.connBudget.acquire(ctx, p); err != nil {
+					return nil, err
+				}
+				defer func() {
+					if err != nil {
+						p.connBudget.release()
+					}
+				}()
+			}
+
+			if err := p.connectLimiter.acquire(ctx); err != nil {
+				return nil, err
+			}
+			defer func() { p.connectLimiter.done(ctx, err) }()
+
+			p.configMux.RLock()
 			connConfig := p.config.ConnConfig
+			configGen := p.configGen
+			p.configMux.RUnlock()
 
 			if p.beforeConnect != nil {
-				connConfig = p.config.ConnConfig.Copy()
+				connConfig = connConfig.Copy()
 				if err := p.beforeConnect(ctx, connConfig); err != nil {
 					return nil, err
 				}
 			}
 
-			conn, err := pgx.ConnectConfig(ctx, connConfig)
+			conn, err := p.hostHealth.connectHealthiest(ctx, connConfig)
 			if err != nil {
 				return nil, err
 			}
@@ -202,17 +312,26 @@ func ConnectConfig(ctx context.Context, config *Config) (*Pool, error) {
 
 			cr := &connResource{
 				conn:      conn,
+				configGen: configGen,
 				conns:     make([]Conn, 64),
 				poolRows:  make([]poolRow, 64),
 				poolRowss: make([]poolRows, 64),
 			}
 
+			if _, err := p.prepareConn(ctx, cr, false); err != nil {
+				conn.Close(ctx)
+				return nil, err
+			}
+
 			return cr, nil
 		},
 		func(value interface{}) {
 			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 			conn := value.(*connResource).conn
 			conn.Close(ctx)
+			if p.connBudget != nil {
+				p.connBudget.release()
+			}
 			select {
 			case <-conn.PgConn().CleanupDone():
 			case <-ctx.Done():
@@ -223,6 +342,9 @@ func ConnectConfig(ctx context.Context, config *Config) (*Pool, error) {
 	)
 
 	go p.backgroundHealthCheck()
+	if config.IdleReadPeriod > 0 {
+		go p.backgroundIdleRead(config.IdleReadPeriod)
+	}
 
 	if !config.LazyConnect {
 		if err := p.createIdleResources(ctx, int(p.minConns)); err != nil {
@@ -251,6 +373,13 @@ func ConnectConfig(ctx context.Context, config *Config) (*Pool, error) {
 // pool_max_conn_lifetime: duration string
 // pool_max_conn_idle_time: duration string
 // pool_health_check_period: duration string
+// pool_reset_session: none, reset, or discard_all
+// pool_acquire_strategy: lifo or fifo
+// pool_idle_read_period: duration string
+// pool_max_connecting: integer 0 or greater
+// pool_connect_backoff: duration string
+// pool_max_connect_backoff: duration string
+// pool_read_only_tx_failovers: integer 0 or greater
 //
 // See Config for definitions of these arguments.
 //
@@ -331,6 +460,89 @@ func ParseConfig(connString string) (*Config, error) {
 		config.HealthCheckPeriod = defaultHealthCheckPeriod
 	}
 
+	if s, ok := config.ConnConfig.Config.RuntimeParams["pool_idle_read_period"]; ok {
+		delete(connConfig.Config.RuntimeParams, "pool_idle_read_period")
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool_idle_read_period: %w", err)
+		}
+		config.IdleReadPeriod = d
+	}
+
+	if s, ok := config.ConnConfig.Config.RuntimeParams["pool_max_connecting"]; ok {
+		delete(connConfig.Config.RuntimeParams, "pool_max_connecting")
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse pool_max_connecting: %w", err)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("pool_max_connecting too small: %d", n)
+		}
+		config.MaxConnecting = int32(n)
+	}
+
+	if s, ok := config.ConnConfig.Config.RuntimeParams["pool_connect_backoff"]; ok {
+		delete(connConfig.Config.RuntimeParams, "pool_connect_backoff")
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool_connect_backoff: %w", err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("pool_connect_backoff too small: %v", d)
+		}
+		config.ConnectBackoff = d
+	}
+
+	if s, ok := config.ConnConfig.Config.RuntimeParams["pool_max_connect_backoff"]; ok {
+		delete(connConfig.Config.RuntimeParams, "pool_max_connect_backoff")
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool_max_connect_backoff: %w", err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("pool_max_connect_backoff too small: %v", d)
+		}
+		config.MaxConnectBackoff = d
+	}
+
+	if s, ok := config.ConnConfig.Config.RuntimeParams["pool_read_only_tx_failovers"]; ok {
+		delete(connConfig.Config.RuntimeParams, "pool_read_only_tx_failovers")
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse pool_read_only_tx_failovers: %w", err)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("pool_read_only_tx_failovers too small: %d", n)
+		}
+		config.ReadOnlyTxFailovers = int32(n)
+	}
+
+	if s, ok := config.ConnConfig.Config.RuntimeParams["pool_acquire_strategy"]; ok {
+		delete(connConfig.Config.RuntimeParams, "pool_acquire_strategy")
+		switch s {
+		case "lifo":
+			config.AcquireStrategy = AcquireLIFO
+		case "fifo":
+			config.AcquireStrategy = AcquireFIFO
+		default:
+			return nil, fmt.Errorf("invalid pool_acquire_strategy: %s", s)
+		}
+	}
+
+	if s, ok := config.ConnConfig.Config.RuntimeParams["pool_reset_session"]; ok {
+		delete(connConfig.Config.RuntimeParams, "pool_reset_session")
+		switch s {
+		case "none":
+			config.ResetSession = SessionResetNone
+		case "reset":
+			config.ResetSession = SessionResetReset
+		case "discard_all":
+			config.ResetSession = SessionResetDiscardAll
+		default:
+			return nil, fmt.Errorf("invalid pool_reset_session: %s", s)
+		}
+	}
+
 	return config, nil
 }
 
@@ -339,6 +551,17 @@ func ParseConfig(connString string) (*Config, error) {
 func (p *Pool) Close() {
 	p.closeOnce.Do(func() {
 		close(p.closeChan)
+
+		// The listener holds a connection until it is closed. It must be closed before the underlying pool as that
+		// blocks until all connections are released.
+		p.listenerMux.Lock()
+		listener := p.listener
+		p.listenerMux.Unlock()
+		if listener != nil {
+			listener.close()
+		}
+		p.unpinAll()
+
 		p.p.Close()
 	})
 }
@@ -365,6 +588,8 @@ func (p *Pool) checkIdleConnsHealth() {
 	for _, res := range resources {
 		if now.Sub(res.CreationTime()) > p.maxConnLifetime {
 			res.Destroy()
+		} else if p.isStale(res) {
+			res.Destroy()
 		} else if res.IdleDuration() > p.maxConnIdleTime {
 			res.Destroy()
 		} else {
@@ -373,6 +598,31 @@ func (p *Pool) checkIdleConnsHealth() {
 	}
 }
 
+func (p *Pool) backgroundIdleRead(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.closeChan:
+			return
+		case <-ticker.C:
+			p.readIdleConns()
+		}
+	}
+}
+
+// readIdleConns reads the messages pending on idle connections and destroys those that are broken.
+func (p *Pool) readIdleConns() {
+	for _, res := range p.p.AcquireAllIdle() {
+		conn := res.Value().(*connResource).conn
+		if err := conn.ReadPendingMessages(context.Background()); err != nil {
+			res.Destroy()
+		} else {
+			res.ReleaseUnused()
+		}
+	}
+}
+
 func (p *Pool) checkMinConns() {
 	for i := p.minConns - p.Stat().TotalConns(); i > 0; i-- {
 		go func() {
@@ -410,15 +660,34 @@ func (p *Pool) createIdleResources(parentCtx context.Context, targetResources in
 
 // Acquire returns a connection (*Conn) from the Pool
 func (p *Pool) Acquire(ctx context.Context) (*Conn, error) {
+	startTime := time.Now()
 	for {
-		res, err := p.p.Acquire(ctx)
-		if err != nil {
-			return nil, err
+		var res *puddle.Resource
+		if p.acquireStrategy == AcquireFIFO {
+			res = p.acquireLongestIdle()
+			if res != nil {
+				// puddle does not count resources taken with AcquireAllIdle as acquires.
+				atomic.AddInt64(&p.idleAcquireCount, 1)
+			}
+		}
+		if res == nil {
+			var err error
+			res, err = p.p.Acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		cr := res.Value().(*connResource)
 		if p.beforeAcquire == nil || p.beforeAcquire(ctx, cr.conn) {
-			return cr.getConn(p, res), nil
+			if _, err := p.prepareConn(ctx, cr, false); err != nil {
+				res.Destroy()
+				return nil, err
+			}
+			cr.conn.SetPoolWait(pgx.PoolWait{Duration: time.Since(startTime), NewConn: res.CreationTime().After(startTime)})
+			c := cr.getConn(p, res)
+			setQueryInfo(ctx, c)
+			return c, nil
 		}
 
 		res.Destroy()
@@ -455,12 +724,46 @@ func (p *Pool) AcquireAllIdle(ctx context.Context) []*Conn {
 	return conns
 }
 
-// Config returns a copy of config that was used to initialize this pool.
-func (p *Pool) Config() *Config { return p.config.Copy() }
+// Config returns a copy of config that was used to initialize this pool. If UpdateConfig has been called it includes
+// the updated ConnConfig.
+func (p *Pool) Config() *Config {
+	p.configMux.RLock()
+	defer p.configMux.RUnlock()
+	return p.config.Copy()
+}
+
+// UpdateConfig replaces the ConnConfig used for new connections with the ConnConfig of newConfig. This allows changing
+// credentials, hosts, and TLS configuration such as when a password is rotated or the database is migrated to a new
+// endpoint. Other settings in newConfig are ignored as they cannot be changed after the pool is created. newConfig must
+// have been created by ParseConfig.
+//
+// Existing connections are not interrupted. Connections made with the previous configuration are closed instead of
+// being returned to the pool when they are released and idle ones are closed by the next health check.
+func (p *Pool) UpdateConfig(newConfig *Config) {
+	if !newConfig.createdByParseConfig {
+		panic("config must be created by ParseConfig")
+	}
+
+	p.configMux.Lock()
+	config := p.config.Copy()
+	config.ConnConfig = newConfig.ConnConfig.Copy()
+	p.config = config
+	p.configGen++
+	p.configMux.Unlock()
+
+	p.hostHealth.reset()
+}
+
+// isStale reports whether res was connected with a configuration that has since been replaced by UpdateConfig.
+func (p *Pool) isStale(res *puddle.Resource) bool {
+	p.configMux.RLock()
+	defer p.configMux.RUnlock()
+	return res.Value().(*connResource).configGen != p.configGen
+}
 
 // Stat returns a pgxpool.Stat struct with a snapshot of Pool statistics.
 func (p *Pool) Stat() *Stat {
-	return &Stat{s: p.p.Stat()}
+	return &Stat{s: p.p.Stat(), idleAcquireCount: atomic.LoadInt64(&p.idleAcquireCount)}
 }
 
 // Exec acquires a connection from the Pool and executes the given SQL.
@@ -575,7 +878,16 @@ func (p *Pool) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
 	return p.BeginTxFunc(ctx, pgx.TxOptions{}, f)
 }
 
+// BeginTxFunc acquires a connection from the Pool and calls BeginTxFunc on it. See pgx.Conn.BeginTxFunc.
+//
+// If Config.ReadOnlyTxFailovers is set and txOptions.AccessMode is pgx.ReadOnly, a transaction that fails because its
+// connection was lost, such as when a replica restarts, is run again from the start on another connection. f may be
+// called more than once so it must not have side effects outside the transaction.
 func (p *Pool) BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error {
+	if txOptions.AccessMode == pgx.ReadOnly && p.readOnlyTxFailovers > 0 {
+		return p.beginTxFuncFailover(ctx, txOptions, f)
+	}
+
 	c, err := p.Acquire(ctx)
 	if err != nil {
 		return err
@@ -585,6 +897,57 @@ func (p *Pool) BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(
 	return c.BeginTxFunc(ctx, txOptions, f)
 }
 
+// CurrentWALLSN acquires a connection from the Pool and calls CurrentWALLSN on it. See pgx.Conn.CurrentWALLSN.
+func (p *Pool) CurrentWALLSN(ctx context.Context) (pgx.LSN, error) {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release()
+
+	return c.Conn().CurrentWALLSN(ctx)
+}
+
+// WaitForLSN acquires a connection from the Pool and calls WaitForLSN on it. It is used on a pool of replica
+// connections. Queries executed on the same connection see the writes up to lsn. See pgx.Conn.WaitForLSN.
+func (p *Pool) WaitForLSN(ctx context.Context, lsn pgx.LSN, timeout time.Duration) (*Conn, error) {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Conn().WaitForLSN(ctx, lsn, timeout)
+	if err != nil {
+		c.Release()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// QueryCount acquires a connection from the Pool and calls QueryCount on it. See pgx.Conn.QueryCount.
+func (p *Pool) QueryCount(ctx context.Context, sql string, args ...interface{}) (int64, error) {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release()
+
+	return c.QueryCount(ctx, sql, args...)
+}
+
+// RetryTxFunc acquires a connection from the Pool and calls RetryTxFunc on it. The connection is held for all retries.
+// See pgx.Conn.RetryTxFunc.
+func (p *Pool) RetryTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	return c.RetryTxFunc(ctx, txOptions, f)
+}
+
 func (p *Pool) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
 	c, err := p.Acquire(ctx)
 	if err != nil {
@@ -595,6 +958,40 @@ func (p *Pool) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNam
 	return c.Conn().CopyFrom(ctx, tableName, columnNames, rowSrc)
 }
 
+// CopyFromWithProgress acquires a connection from the Pool and calls CopyFromWithProgress on it. See
+// pgx.Conn.CopyFromWithProgress.
+func (p *Pool) CopyFromWithProgress(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource, progress *pgx.CopyProgressReporter) (int64, error) {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release()
+
+	return c.Conn().CopyFromWithProgress(ctx, tableName, columnNames, rowSrc, progress)
+}
+
+// InsertRows acquires a connection from the Pool and calls InsertRows on it. See pgx.Conn.InsertRows.
+func (p *Pool) InsertRows(ctx context.Context, tableName pgx.Identifier, columnNames []string, rows [][]interface{}, opts *pgx.SplitOptions) (int64, error) {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release()
+
+	return c.Conn().InsertRows(ctx, tableName, columnNames, rows, opts)
+}
+
+// ExecBatch acquires a connection from the Pool and calls ExecBatch on it. See pgx.Conn.ExecBatch.
+func (p *Pool) ExecBatch(ctx context.Context, b *pgx.Batch, opts *pgx.SplitOptions) ([]pgconn.CommandTag, error) {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	return c.Conn().ExecBatch(ctx, b, opts)
+}
+
 // Ping acquires a connection from the Pool and executes an empty sql statement against it.
 // If the sql returns without error, the database Ping is considered successful, otherwise, the error is returned.
 func (p *Pool) Ping(ctx context.Context) error {
@@ -605,3 +1002,24 @@ func (p *Pool) Ping(ctx context.Context) error {
 	defer c.Release()
 	return c.Ping(ctx)
 }
+
+// Notify acquires a connection from the Pool and sends a notification with payload on channel. See pgx.Conn.Notify.
+func (p *Pool) Notify(ctx context.Context, channel, payload string) error {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+	return c.Conn().Notify(ctx, channel, payload)
+}
+
+// NotifyJSON acquires a connection from the Pool and sends v marshalled as JSON as the payload of a notification on
+// channel. See pgx.Conn.NotifyJSON.
+func (p *Pool) NotifyJSON(ctx context.Context, channel string, v interface{}) error {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+	return c.Conn().NotifyJSON(ctx, channel, v)
+}
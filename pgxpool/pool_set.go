@@ -0,0 +1,249 @@
+package pgxpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// poolSetEvictInterval is how often a connect waiting for the total connection limit of a PoolSet retries closing an
+// idle connection of another pool.
+const poolSetEvictInterval = 50 * time.Millisecond
+
+// ErrPoolSetClosed occurs when a pool is requested from a closed PoolSet.
+var ErrPoolSetClosed = errors.New("pool set closed")
+
+// PoolSetConfig is the configuration of a PoolSet.
+type PoolSetConfig struct {
+	// Config is the template of the pool of every database. Each pool uses a copy with ConnConfig.Database replaced by
+	// the name of its database. All other settings, including MaxConns, apply to each pool on its own.
+	Config *Config
+
+	// MaxTotalConns is the maximum number of connections of all pools combined. When it is reached, a new connection
+	// closes the longest idle connection of the pool with the most idle connections or waits for one to be closed. Set
+	// to 0 for no limit beyond the MaxConns of each pool. MinConns of Config must be 0 when it is set as pools would
+	// otherwise keep closing each other's connections to refill their own.
+	MaxTotalConns int32
+}
+
+// PoolSet lazily creates and holds one pool per database of the same server and credentials. It is intended for
+// multi-tenant applications that map each tenant to its own database.
+type PoolSet struct {
+	config *Config
+	budget *connBudget
+
+	mux    sync.Mutex
+	pools  map[string]*poolSetEntry
+	closed bool
+}
+
+type poolSetEntry struct {
+	ready chan struct{} // closed when pool or err is set
+	pool  *Pool
+	err   error
+}
+
+// NewPoolSet creates a PoolSet. No connections are established until a pool is requested. config.Config must have been
+// created by ParseConfig.
+func NewPoolSet(config PoolSetConfig) (*PoolSet, error) {
+	if config.Config == nil {
+		return nil, errors.New("no config")
+	}
+	if config.MaxTotalConns < 0 {
+		return nil, errors.New("MaxTotalConns must not be negative")
+	}
+	if config.MaxTotalConns > 0 && config.Config.MinConns > 0 {
+		return nil, errors.New("MinConns must be 0 when MaxTotalConns is set")
+	}
+
+	ps := &PoolSet{
+		config: config.Config.Copy(),
+		pools:  make(map[string]*poolSetEntry),
+	}
+	if config.MaxTotalConns > 0 {
+		ps.budget = &connBudget{slots: make(chan struct{}, config.MaxTotalConns), set: ps}
+	}
+
+	return ps, nil
+}
+
+// Pool returns the pool of database. The pool is created and its first connection established by the first call for
+// database. ctx can be used to cancel that connection. If it fails the error is returned and the next call tries
+// again.
+func (ps *PoolSet) Pool(ctx context.Context, database string) (*Pool, error) {
+	ps.mux.Lock()
+	if ps.closed {
+		ps.mux.Unlock()
+		return nil, ErrPoolSetClosed
+	}
+	entry, ok := ps.pools[database]
+	if !ok {
+		entry = &poolSetEntry{ready: make(chan struct{})}
+		ps.pools[database] = entry
+	}
+	ps.mux.Unlock()
+
+	if ok {
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if entry.err != nil {
+			// The creating call failed. Try again as the error may have been its context or a transient failure.
+			return ps.Pool(ctx, database)
+		}
+		return entry.pool, nil
+	}
+
+	config := ps.config.Copy()
+	config.ConnConfig.Database = database
+	entry.pool, entry.err = connectConfig(ctx, config, ps.budget)
+
+	ps.mux.Lock()
+	if entry.err != nil {
+		delete(ps.pools, database)
+	} else if ps.closed {
+		// Close ran while the pool was created and did not see it.
+		entry.pool.Close()
+		entry.pool, entry.err = nil, ErrPoolSetClosed
+	}
+	ps.mux.Unlock()
+	close(entry.ready)
+
+	return entry.pool, entry.err
+}
+
+// Acquire returns a connection (*Conn) from the pool of database.
+func (ps *PoolSet) Acquire(ctx context.Context, database string) (*Conn, error) {
+	pool, err := ps.Pool(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Acquire(ctx)
+}
+
+// Pools returns the pool of every database that has been created by name.
+func (ps *PoolSet) Pools() map[string]*Pool {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	pools := make(map[string]*Pool, len(ps.pools))
+	for database, entry := range ps.pools {
+		select {
+		case <-entry.ready:
+			pools[database] = entry.pool
+		default:
+		}
+	}
+	return pools
+}
+
+// Remove closes and forgets the pool of database, such as when its tenant is removed. A later call to Pool creates a
+// new pool.
+func (ps *PoolSet) Remove(database string) {
+	ps.mux.Lock()
+	entry, ok := ps.pools[database]
+	if ok {
+		delete(ps.pools, database)
+	}
+	ps.mux.Unlock()
+
+	if ok {
+		<-entry.ready
+		if entry.pool != nil {
+			entry.pool.Close()
+		}
+	}
+}
+
+// TotalConns returns the number of connections of all pools combined.
+func (ps *PoolSet) TotalConns() int32 {
+	var total int32
+	for _, pool := range ps.Pools() {
+		total += pool.Stat().TotalConns()
+	}
+	return total
+}
+
+// Close closes the pool of every database. Pool returns ErrPoolSetClosed afterwards.
+func (ps *PoolSet) Close() {
+	ps.mux.Lock()
+	if ps.closed {
+		ps.mux.Unlock()
+		return
+	}
+	ps.closed = true
+	entries := ps.pools
+	ps.pools = make(map[string]*poolSetEntry)
+	ps.mux.Unlock()
+
+	for _, entry := range entries {
+		select {
+		case <-entry.ready:
+			if entry.pool != nil {
+				entry.pool.Close()
+			}
+		default:
+			// Still connecting. Pool closes it when it sees the set is closed.
+		}
+	}
+}
+
+// closeIdleConn closes the longest idle connection of the pool in the set, other than except, with the most idle
+// connections. The idle connections of the other pools are not touched.
+func (ps *PoolSet) closeIdleConn(except *Pool) {
+	var victim *Pool
+	var victimIdle int32
+	for _, pool := range ps.Pools() {
+		if pool == except {
+			continue
+		}
+		if idle := pool.Stat().IdleConns(); idle > victimIdle {
+			victim, victimIdle = pool, idle
+		}
+	}
+	if victim == nil {
+		return
+	}
+
+	if res := victim.acquireLongestIdle(); res != nil {
+		res.Destroy()
+	}
+}
+
+// connBudget limits the number of connections of all pools of a PoolSet combined. A slot is held for the lifetime of
+// each connection.
+type connBudget struct {
+	slots chan struct{}
+	set   *PoolSet
+}
+
+// acquire waits for a free slot for a new connection of p. While none is free it closes idle connections of the other
+// pools of the set. release must be called when the connection is closed or fails to connect.
+func (cb *connBudget) acquire(ctx context.Context, p *Pool) error {
+	select {
+	case cb.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	ticker := time.NewTicker(poolSetEvictInterval)
+	defer ticker.Stop()
+	for {
+		cb.set.closeIdleConn(p)
+
+		select {
+		case cb.slots <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cb *connBudget) release() {
+	<-cb.slots
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgconn"
 	"github.com/nappspt/schemapgx"
 	"github.com/nappspt/schemapgx/pgxpool"
 	"github.com/stretchr/testify/assert"
@@ -46,6 +50,54 @@ func TestParseConfigExtractsPoolArguments(t *testing.T) {
 	assert.NotContains(t, config.ConnConfig.Config.RuntimeParams, "pool_min_conns")
 }
 
+func TestParseConfigExtractsConnectLimits(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig("pool_max_connecting=3 pool_connect_backoff=250ms pool_max_connect_backoff=10s")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, config.MaxConnecting)
+	assert.Equal(t, 250*time.Millisecond, config.ConnectBackoff)
+	assert.Equal(t, 10*time.Second, config.MaxConnectBackoff)
+	assert.NotContains(t, config.ConnConfig.Config.RuntimeParams, "pool_max_connecting")
+	assert.NotContains(t, config.ConnConfig.Config.RuntimeParams, "pool_connect_backoff")
+	assert.NotContains(t, config.ConnConfig.Config.RuntimeParams, "pool_max_connect_backoff")
+
+	_, err = pgxpool.ParseConfig("pool_max_connecting=-1")
+	require.Error(t, err)
+	_, err = pgxpool.ParseConfig("pool_connect_backoff=-1s")
+	require.Error(t, err)
+	_, err = pgxpool.ParseConfig("pool_max_connect_backoff=-1s")
+	require.Error(t, err)
+}
+
+func TestPoolNegativeMaxConnectBackoffUsesDefault(t *testing.T) {
+	t.Parallel()
+
+	// Nothing listens on the address so every connect fails.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	config, err := pgxpool.ParseConfig("host=" + host + " port=" + port + " sslmode=disable")
+	require.NoError(t, err)
+	config.LazyConnect = true
+	config.ConnectBackoff = time.Millisecond
+	config.MaxConnectBackoff = -time.Second
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// The second connect waits out the backoff after the first failure.
+	for i := 0; i < 2; i++ {
+		_, err = pool.Acquire(context.Background())
+		require.Error(t, err)
+	}
+}
+
 func TestConnectCancel(t *testing.T) {
 	t.Parallel()
 
@@ -164,6 +216,259 @@ func TestPoolBeforeConnect(t *testing.T) {
 	assert.EqualValues(t, "pgx", str)
 }
 
+func TestPoolAcquireStrategy(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig("pool_acquire_strategy=fifo")
+	require.NoError(t, err)
+	assert.Equal(t, pgxpool.AcquireFIFO, config.AcquireStrategy)
+	assert.NotContains(t, config.ConnConfig.Config.RuntimeParams, "pool_acquire_strategy")
+
+	_, err = pgxpool.ParseConfig("pool_acquire_strategy=random")
+	require.Error(t, err)
+
+	for _, tt := range []struct {
+		strategy pgxpool.AcquireStrategy
+		expected int // index of the released connection that is acquired next
+	}{
+		{pgxpool.AcquireLIFO, 2},
+		{pgxpool.AcquireFIFO, 0},
+	} {
+		config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+		require.NoError(t, err)
+		config.MaxConns = 3
+		config.AcquireStrategy = tt.strategy
+
+		db, err := pgxpool.ConnectConfig(context.Background(), config)
+		require.NoError(t, err)
+
+		var conns []*pgxpool.Conn
+		var pids []uint32
+		for i := 0; i < 3; i++ {
+			c, err := db.Acquire(context.Background())
+			require.NoError(t, err)
+			conns = append(conns, c)
+			pids = append(pids, c.Conn().PgConn().PID())
+		}
+		for _, c := range conns {
+			c.Release()
+		}
+
+		c, err := db.Acquire(context.Background())
+		require.NoError(t, err)
+		assert.Equalf(t, pids[tt.expected], c.Conn().PgConn().PID(), "strategy %d", tt.strategy)
+		c.Release()
+
+		db.Close()
+	}
+}
+
+func TestPoolAcquireFIFODoesNotEstablishConns(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MaxConns = 10
+	config.AcquireStrategy = pgxpool.AcquireFIFO
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var conns []*pgxpool.Conn
+	for i := 0; i < 8; i++ {
+		c, err := db.Acquire(context.Background())
+		require.NoError(t, err)
+		conns = append(conns, c)
+	}
+	for _, c := range conns {
+		c.Release()
+	}
+	waitForReleaseToComplete()
+	acquireCount := db.Stat().AcquireCount()
+
+	// Two acquirers hold far fewer connections than are idle, counting those still being released. Neither may find
+	// the pool empty while the other looks for the longest idle connection.
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c, err := db.Acquire(context.Background())
+				if !assert.NoError(t, err) {
+					return
+				}
+				c.Release()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 8, db.Stat().TotalConns())
+	assert.EqualValues(t, acquireCount+200, db.Stat().AcquireCount())
+}
+
+func TestPoolReportsPoolWait(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.LazyConnect = true
+
+	var mux sync.Mutex
+	var stats []pgx.QueryStat
+	config.ConnConfig.OnQueryStat = func(stat pgx.QueryStat) {
+		mux.Lock()
+		stats = append(stats, stat)
+		mux.Unlock()
+	}
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(context.Background(), "select 1")
+	require.NoError(t, err)
+
+	c, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+	_, err = c.Exec(context.Background(), "select 2")
+	require.NoError(t, err)
+	_, err = c.Exec(context.Background(), "select 3")
+	require.NoError(t, err)
+	c.Release()
+
+	mux.Lock()
+	defer mux.Unlock()
+	require.Len(t, stats, 3)
+	require.NotNil(t, stats[0].PoolWait)
+	assert.True(t, stats[0].PoolWait.NewConn)
+	assert.True(t, stats[0].PoolWait.Duration > 0)
+	require.NotNil(t, stats[1].PoolWait)
+	assert.False(t, stats[1].PoolWait.NewConn)
+	assert.Nil(t, stats[2].PoolWait)
+}
+
+func TestPoolIdleRead(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig("pool_idle_read_period=20ms")
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Millisecond, config.IdleReadPeriod)
+	assert.NotContains(t, config.ConnConfig.Config.RuntimeParams, "pool_idle_read_period")
+
+	config, err = pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MaxConns = 1
+	config.IdleReadPeriod = 10 * time.Millisecond
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+	_, err = c.Exec(context.Background(), "listen pool_idle_read")
+	require.NoError(t, err)
+	c.Release()
+
+	notifier, err := pgx.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer notifier.Close(context.Background())
+	require.NoError(t, notifier.Notify(context.Background(), "pool_idle_read", "idle"))
+
+	var payloads []string
+	deadline := time.Now().Add(5 * time.Second)
+	for len(payloads) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		c, err := db.Acquire(context.Background())
+		require.NoError(t, err)
+		for _, n := range c.Conn().CollectNotifications(0) {
+			payloads = append(payloads, n.Payload)
+		}
+		c.Release()
+	}
+	assert.Equal(t, []string{"idle"}, payloads)
+}
+
+func TestPoolMaxConnecting(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig("host=localhost")
+	require.NoError(t, err)
+	config.LazyConnect = true
+	config.MaxConns = 8
+	config.MaxConnecting = 2
+
+	var connecting, maxConnecting int32
+	config.BeforeConnect = func(ctx context.Context, cfg *pgx.ConnConfig) error {
+		n := atomic.AddInt32(&connecting, 1)
+		defer atomic.AddInt32(&connecting, -1)
+		for {
+			m := atomic.LoadInt32(&maxConnecting)
+			if n <= m || atomic.CompareAndSwapInt32(&maxConnecting, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return errors.New("refused")
+	}
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			_, err := pool.Acquire(context.Background())
+			errs <- err
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		assert.EqualError(t, <-errs, "refused")
+	}
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&maxConnecting))
+}
+
+func TestPoolConnectBackoff(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig("host=localhost")
+	require.NoError(t, err)
+	config.LazyConnect = true
+	config.ConnectBackoff = 200 * time.Millisecond
+
+	var fail int32 = 1
+	config.BeforeConnect = func(ctx context.Context, cfg *pgx.ConnConfig) error {
+		if atomic.LoadInt32(&fail) == 1 {
+			return errors.New("refused")
+		}
+		return errors.New("connected")
+	}
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Acquire(context.Background())
+	require.EqualError(t, err, "refused")
+
+	// The next connect waits between half and all of the backoff.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	atomic.StoreInt32(&fail, 0)
+	start := time.Now()
+	_, err = pool.Acquire(context.Background())
+	require.EqualError(t, err, "connected")
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(100*time.Millisecond))
+}
+
 func TestPoolAfterConnect(t *testing.T) {
 	t.Parallel()
 
@@ -270,6 +575,199 @@ func TestPoolAfterRelease(t *testing.T) {
 	assert.EqualValues(t, 5, len(connPIDs))
 }
 
+func TestParseConfigExtractsResetSession(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig("pool_reset_session=discard_all")
+	require.NoError(t, err)
+	assert.Equal(t, pgxpool.SessionResetDiscardAll, config.ResetSession)
+	assert.NotContains(t, config.ConnConfig.Config.RuntimeParams, "pool_reset_session")
+
+	config, err = pgxpool.ParseConfig("pool_reset_session=reset")
+	require.NoError(t, err)
+	assert.Equal(t, pgxpool.SessionResetReset, config.ResetSession)
+
+	_, err = pgxpool.ParseConfig("pool_reset_session=bogus")
+	require.EqualError(t, err, "invalid pool_reset_session: bogus")
+}
+
+func TestPoolResetSession(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []pgxpool.SessionReset{pgxpool.SessionResetReset, pgxpool.SessionResetDiscardAll} {
+		config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+		require.NoError(t, err)
+		config.MaxConns = 1
+		config.ResetSession = mode
+
+		db, err := pgxpool.ConnectConfig(context.Background(), config)
+		require.NoError(t, err)
+
+		conn, err := db.Acquire(context.Background())
+		require.NoError(t, err)
+		pid := conn.Conn().PgConn().PID()
+		_, err = conn.Exec(context.Background(), "set search_path to pg_catalog")
+		require.NoError(t, err)
+		_, err = conn.Exec(context.Background(), "select $1::int4", 1)
+		require.NoError(t, err)
+		conn.Release()
+		waitForReleaseToComplete()
+
+		conn, err = db.Acquire(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, pid, conn.Conn().PgConn().PID(), "connection should be reused")
+
+		var searchPath string
+		err = conn.QueryRow(context.Background(), "show search_path").Scan(&searchPath)
+		require.NoError(t, err)
+		assert.NotEqual(t, "pg_catalog", searchPath)
+
+		// The statement cache must still work after the session is reset.
+		var n int32
+		err = conn.QueryRow(context.Background(), "select $1::int4", 2).Scan(&n)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, n)
+
+		conn.Release()
+		db.Close()
+	}
+}
+
+func TestPoolBeforeRelease(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+
+	config.BeforeRelease = func(c *pgx.Conn) bool {
+		var searchPath string
+		err := c.QueryRow(context.Background(), "show search_path").Scan(&searchPath)
+		return err == nil && searchPath != "pg_catalog"
+	}
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	conn, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+	conn.Release()
+	waitForReleaseToComplete()
+	assert.EqualValues(t, 1, db.Stat().TotalConns())
+
+	conn, err = db.Acquire(context.Background())
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), "set search_path to pg_catalog")
+	require.NoError(t, err)
+	conn.Release()
+	waitForReleaseToComplete()
+	assert.EqualValues(t, 0, db.Stat().TotalConns())
+}
+
+func TestPoolAcquirePinned(t *testing.T) {
+	t.Parallel()
+
+	db, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	conn, err := db.AcquirePinned(context.Background(), "a")
+	require.NoError(t, err)
+	pid := conn.Conn().PgConn().PID()
+	_, err = conn.Exec(context.Background(), "create temporary table pinned(id int)")
+	require.NoError(t, err)
+	conn.Release()
+	waitForReleaseToComplete()
+	assert.EqualValues(t, 0, db.Stat().IdleConns())
+
+	conn, err = db.AcquirePinned(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, pid, conn.Conn().PgConn().PID())
+	_, err = conn.Exec(context.Background(), "insert into pinned values (1)")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = db.AcquirePinned(ctx, "a")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	conn.Release()
+
+	err = db.Unpin(context.Background(), "a")
+	require.NoError(t, err)
+	waitForReleaseToComplete()
+	assert.EqualValues(t, 1, db.Stat().IdleConns())
+}
+
+func TestPoolSkipsFailedHost(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	if strings.HasPrefix(config.ConnConfig.Host, "/") {
+		t.Skip("skipping test with unix socket")
+	}
+
+	config.ConnConfig.Fallbacks = append([]*pgconn.FallbackConfig{{
+		Host:      config.ConnConfig.Host,
+		Port:      config.ConnConfig.Port,
+		TLSConfig: config.ConnConfig.TLSConfig,
+	}}, config.ConnConfig.Fallbacks...)
+	config.ConnConfig.Host = "127.0.0.1"
+	config.ConnConfig.Port = 1
+
+	var deadDials int32
+	dialer := &net.Dialer{KeepAlive: 5 * time.Minute}
+	config.ConnConfig.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == "127.0.0.1:1" {
+			atomic.AddInt32(&deadDials, 1)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.EqualValues(t, 1, atomic.LoadInt32(&deadDials))
+
+	conns := make([]*pgxpool.Conn, 3)
+	for i := range conns {
+		conns[i], err = db.Acquire(context.Background())
+		require.NoError(t, err)
+	}
+	for _, c := range conns {
+		c.Release()
+	}
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&deadDials))
+}
+
+func TestPoolUpdateConfig(t *testing.T) {
+	t.Parallel()
+
+	db, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	oldConn, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.ConnConfig.RuntimeParams["application_name"] = "pgxpool_update_config"
+	db.UpdateConfig(config)
+	assert.Equal(t, "pgxpool_update_config", db.Config().ConnConfig.RuntimeParams["application_name"])
+
+	oldConn.Release()
+	waitForReleaseToComplete()
+	assert.EqualValues(t, 0, db.Stat().TotalConns())
+
+	var appName string
+	err = db.QueryRow(context.Background(), "show application_name").Scan(&appName)
+	require.NoError(t, err)
+	assert.Equal(t, "pgxpool_update_config", appName)
+}
+
 func TestPoolAcquireAllIdle(t *testing.T) {
 	t.Parallel()
 
@@ -488,55 +986,203 @@ func TestPoolSendBatch(t *testing.T) {
 	testSendBatch(t, pool)
 	waitForReleaseToComplete()
 
-	stats := pool.Stat()
-	assert.EqualValues(t, 0, stats.AcquiredConns())
-	assert.EqualValues(t, 1, stats.TotalConns())
+	stats := pool.Stat()
+	assert.EqualValues(t, 0, stats.AcquiredConns())
+	assert.EqualValues(t, 1, stats.TotalConns())
+}
+
+func TestPoolCopyFrom(t *testing.T) {
+	// Not able to use testCopyFrom because it relies on temporary tables and the pool may run subsequent calls under
+	// different connections.
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `drop table if exists poolcopyfromtest`)
+	require.NoError(t, err)
+
+	_, err = pool.Exec(ctx, `create table poolcopyfromtest(a int2, b int4, c int8, d varchar, e text, f date, g timestamptz)`)
+	require.NoError(t, err)
+	defer pool.Exec(ctx, `drop table poolcopyfromtest`)
+
+	tzedTime := time.Date(2010, 2, 3, 4, 5, 6, 0, time.Local)
+
+	inputRows := [][]interface{}{
+		{int16(0), int32(1), int64(2), "abc", "efg", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), tzedTime},
+		{nil, nil, nil, nil, nil, nil, nil},
+	}
+
+	copyCount, err := pool.CopyFrom(ctx, pgx.Identifier{"poolcopyfromtest"}, []string{"a", "b", "c", "d", "e", "f", "g"}, pgx.CopyFromRows(inputRows))
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(inputRows), copyCount)
+
+	rows, err := pool.Query(ctx, "select * from poolcopyfromtest")
+	assert.NoError(t, err)
+
+	var outputRows [][]interface{}
+	for rows.Next() {
+		row, err := rows.Values()
+		if err != nil {
+			t.Errorf("Unexpected error for rows.Values(): %v", err)
+		}
+		outputRows = append(outputRows, row)
+	}
+
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, inputRows, outputRows)
+}
+
+func TestPoolCopyFromParallel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MaxConns = 4
+
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `drop table if exists poolcopyfromparalleltest`)
+	require.NoError(t, err)
+
+	_, err = pool.Exec(ctx, `create table poolcopyfromparalleltest(a int8 not null)`)
+	require.NoError(t, err)
+	defer pool.Exec(ctx, `drop table poolcopyfromparalleltest`)
+
+	inputRows := make([][]interface{}, 10000)
+	for i := range inputRows {
+		inputRows[i] = []interface{}{int64(i)}
+	}
+
+	copyCount, err := pool.CopyFromParallel(ctx, pgx.Identifier{"poolcopyfromparalleltest"}, []string{"a"}, pgx.CopyFromRows(inputRows), 4)
+	require.NoError(t, err)
+	assert.EqualValues(t, len(inputRows), copyCount)
+
+	var n, sum int64
+	err = pool.QueryRow(ctx, "select count(*), sum(a) from poolcopyfromparalleltest").Scan(&n, &sum)
+	require.NoError(t, err)
+	assert.EqualValues(t, len(inputRows), n)
+	assert.EqualValues(t, len(inputRows)*(len(inputRows)-1)/2, sum)
+
+	_, err = pool.Exec(ctx, `truncate poolcopyfromparalleltest`)
+	require.NoError(t, err)
+
+	inputRows[5000] = []interface{}{nil}
+	_, err = pool.CopyFromParallel(ctx, pgx.Identifier{"poolcopyfromparalleltest"}, []string{"a"}, pgx.CopyFromRows(inputRows), 4)
+	var parallelErr *pgxpool.CopyFromParallelError
+	require.True(t, errors.As(err, &parallelErr), err)
+	require.Len(t, parallelErr.ShardErrors, 4)
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr))
+	assert.Equal(t, "23502", pgErr.Code)
+
+	err = pool.QueryRow(ctx, "select count(*) from poolcopyfromparalleltest").Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, parallelErr.RowsCopied, n)
+}
+
+func TestPoolCopyFromPartitioned(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MaxConns = 4
+
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `drop table if exists poolcopyfrompartitionedtest`)
+	require.NoError(t, err)
+
+	_, err = pool.Exec(ctx, `create table poolcopyfrompartitionedtest(a int8 not null) partition by list ((a % 2))`)
+	if err != nil {
+		t.Skipf("partitioned tables not supported: %v", err)
+	}
+	defer pool.Exec(ctx, `drop table poolcopyfrompartitionedtest`)
+
+	_, err = pool.Exec(ctx, `create table poolcopyfrompartitionedtest_even partition of poolcopyfrompartitionedtest for values in (0)`)
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, `create table poolcopyfrompartitionedtest_odd partition of poolcopyfrompartitionedtest for values in (1)`)
+	require.NoError(t, err)
+
+	inputRows := make([][]interface{}, 25000)
+	for i := range inputRows {
+		inputRows[i] = []interface{}{int64(i)}
+	}
+
+	partitionFor := func(values []interface{}) (pgx.Identifier, error) {
+		if values[0] == nil {
+			return pgx.Identifier{"poolcopyfrompartitionedtest_even"}, nil
+		}
+		if values[0].(int64)%2 == 0 {
+			return pgx.Identifier{"poolcopyfrompartitionedtest_even"}, nil
+		}
+		return pgx.Identifier{"poolcopyfrompartitionedtest_odd"}, nil
+	}
+
+	copyCount, err := pool.CopyFromPartitioned(ctx, []string{"a"}, pgx.CopyFromRows(inputRows), partitionFor, 4)
+	require.NoError(t, err)
+	assert.EqualValues(t, len(inputRows), copyCount)
+
+	var even, odd int64
+	err = pool.QueryRow(ctx, "select count(*) from poolcopyfrompartitionedtest_even").Scan(&even)
+	require.NoError(t, err)
+	err = pool.QueryRow(ctx, "select count(*) from poolcopyfrompartitionedtest_odd").Scan(&odd)
+	require.NoError(t, err)
+	assert.EqualValues(t, len(inputRows)/2, even)
+	assert.EqualValues(t, len(inputRows)/2, odd)
+
+	inputRows[5000] = []interface{}{nil}
+	_, err = pool.CopyFromPartitioned(ctx, []string{"a"}, pgx.CopyFromRows(inputRows), partitionFor, 4)
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr), err)
+	assert.Equal(t, "23502", pgErr.Code)
+	assert.Contains(t, err.Error(), "poolcopyfrompartitionedtest_even")
 }
 
-func TestPoolCopyFrom(t *testing.T) {
-	// Not able to use testCopyFrom because it relies on temporary tables and the pool may run subsequent calls under
-	// different connections.
+func TestPoolNotify(t *testing.T) {
 	t.Parallel()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	pool, err := pgxpool.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	pool, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
 	require.NoError(t, err)
 	defer pool.Close()
 
-	_, err = pool.Exec(ctx, `drop table if exists poolcopyfromtest`)
+	listener, err := pool.Acquire(context.Background())
 	require.NoError(t, err)
+	defer listener.Release()
 
-	_, err = pool.Exec(ctx, `create table poolcopyfromtest(a int2, b int4, c int8, d varchar, e text, f date, g timestamptz)`)
+	_, err = listener.Exec(context.Background(), "listen pool_notify")
 	require.NoError(t, err)
-	defer pool.Exec(ctx, `drop table poolcopyfromtest`)
-
-	tzedTime := time.Date(2010, 2, 3, 4, 5, 6, 0, time.Local)
 
-	inputRows := [][]interface{}{
-		{int16(0), int32(1), int64(2), "abc", "efg", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), tzedTime},
-		{nil, nil, nil, nil, nil, nil, nil},
-	}
+	err = pool.Notify(context.Background(), "pool_notify", "hello")
+	require.NoError(t, err)
 
-	copyCount, err := pool.CopyFrom(ctx, pgx.Identifier{"poolcopyfromtest"}, []string{"a", "b", "c", "d", "e", "f", "g"}, pgx.CopyFromRows(inputRows))
-	assert.NoError(t, err)
-	assert.EqualValues(t, len(inputRows), copyCount)
+	notification, err := listener.Conn().WaitForNotification(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "hello", notification.Payload)
 
-	rows, err := pool.Query(ctx, "select * from poolcopyfromtest")
-	assert.NoError(t, err)
+	err = pool.NotifyJSON(context.Background(), "pool_notify", []int{1, 2, 3})
+	require.NoError(t, err)
 
-	var outputRows [][]interface{}
-	for rows.Next() {
-		row, err := rows.Values()
-		if err != nil {
-			t.Errorf("Unexpected error for rows.Values(): %v", err)
-		}
-		outputRows = append(outputRows, row)
-	}
+	notification, err = listener.Conn().WaitForNotification(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "[1,2,3]", notification.Payload)
 
-	assert.NoError(t, rows.Err())
-	assert.Equal(t, inputRows, outputRows)
+	_, err = listener.Exec(context.Background(), "unlisten *")
+	require.NoError(t, err)
 }
 
 func TestConnReleaseClosesConnInFailedTransaction(t *testing.T) {
@@ -979,3 +1625,418 @@ func TestCreateMinPoolReturnsFirstError(t *testing.T) {
 	require.True(t, connectAttempts >= 5, "Expected %d got %d", 5, connectAttempts)
 	require.ErrorIs(t, err, mockErr)
 }
+
+func TestShardedPoolRouting(t *testing.T) {
+	t.Parallel()
+
+	newShards := func(names ...string) map[string]*pgxpool.Pool {
+		shards := make(map[string]*pgxpool.Pool)
+		for _, name := range names {
+			config, err := pgxpool.ParseConfig("host=127.0.0.1 port=1 connect_timeout=1")
+			require.NoError(t, err)
+			config.LazyConnect = true
+			pool, err := pgxpool.ConnectConfig(context.Background(), config)
+			require.NoError(t, err)
+			shards[name] = pool
+		}
+		return shards
+	}
+
+	sp3, err := pgxpool.NewSharded(pgxpool.ShardedConfig{Shards: newShards("a", "b", "c")})
+	require.NoError(t, err)
+	defer sp3.Close()
+
+	sp4, err := pgxpool.NewSharded(pgxpool.ShardedConfig{Shards: newShards("a", "b", "c", "d")})
+	require.NoError(t, err)
+	defer sp4.Close()
+
+	counts := make(map[string]int)
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("customer-%d", i)
+		name := sp3.ShardName(key)
+		require.Equal(t, name, sp3.ShardName(key))
+		counts[name]++
+
+		if newName := sp4.ShardName(key); newName != name {
+			require.Equal(t, "d", newName)
+			moved++
+		}
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Greater(t, counts[name], 500, name)
+	}
+	assert.Greater(t, moved, 300)
+	assert.Less(t, moved, 1200)
+
+	pool, err := sp3.Shard("customer-1")
+	require.NoError(t, err)
+	assert.Same(t, sp3.Pools()[sp3.ShardName("customer-1")], pool)
+
+	_, err = sp3.Exec(context.Background(), "select 1")
+	require.ErrorIs(t, err, pgxpool.ErrNoShardKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errs := sp3.CheckHealth(ctx)
+	require.Len(t, errs, 3)
+
+	_, err = sp3.Shard("customer-1")
+	var unavailableErr *pgxpool.ShardUnavailableError
+	require.ErrorAs(t, err, &unavailableErr)
+	assert.Equal(t, sp3.ShardName("customer-1"), unavailableErr.Shard)
+
+	var n int
+	err = sp3.QueryRow(context.Background(), "select $1::int", 1).Scan(&n)
+	require.ErrorAs(t, err, &unavailableErr)
+}
+
+func TestPoolQueryInfoAndCancelQuery(t *testing.T) {
+	t.Parallel()
+
+	pool, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	var info pgxpool.QueryInfo
+	var pid uint32
+	err = pool.QueryRow(pgxpool.WithQueryInfo(context.Background(), &info), "select pg_backend_pid()").Scan(&pid)
+	require.NoError(t, err)
+	assert.NotZero(t, info.BackendPID)
+	assert.Equal(t, pid, info.BackendPID)
+
+	info = pgxpool.QueryInfo{}
+	errChan := make(chan error)
+	go func() {
+		_, err := pool.Exec(pgxpool.WithQueryInfo(context.Background(), &info), "select pg_sleep(10)")
+		errChan <- err
+	}()
+
+	var sleepingPID uint32
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		err = pool.QueryRow(context.Background(), "select pid from pg_stat_activity where query = 'select pg_sleep(10)' and state = 'active'").Scan(&sleepingPID)
+		if err == nil {
+			break
+		}
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+		require.True(t, time.Now().Before(deadline), "timed out waiting for query to start")
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	signaled, err := pool.CancelQuery(context.Background(), sleepingPID)
+	require.NoError(t, err)
+	assert.True(t, signaled)
+
+	err = <-errChan
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, "57014", pgErr.Code)
+	assert.Equal(t, sleepingPID, info.BackendPID)
+}
+
+func TestPoolReadOnlyTxFailover(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig("pool_read_only_tx_failovers=2")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, config.ReadOnlyTxFailovers)
+	assert.NotContains(t, config.ConnConfig.Config.RuntimeParams, "pool_read_only_tx_failovers")
+
+	_, err = pgxpool.ParseConfig("pool_read_only_tx_failovers=-1")
+	require.Error(t, err)
+
+	config, err = pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.ReadOnlyTxFailovers = 1
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	killer, err := pgx.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer killer.Close(context.Background())
+
+	var calls int
+	var n int32
+	err = db.BeginTxFunc(context.Background(), pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		calls++
+		if calls == 1 {
+			_, err := killer.Exec(context.Background(), "select pg_terminate_backend($1)", tx.Conn().PgConn().PID())
+			require.NoError(t, err)
+		}
+		return tx.QueryRow(context.Background(), "select 42").Scan(&n)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.EqualValues(t, 42, n)
+
+	// Read write transactions are not run again.
+	calls = 0
+	err = db.BeginTxFunc(context.Background(), pgx.TxOptions{}, func(tx pgx.Tx) error {
+		calls++
+		_, err := killer.Exec(context.Background(), "select pg_terminate_backend($1)", tx.Conn().PgConn().PID())
+		require.NoError(t, err)
+		return tx.QueryRow(context.Background(), "select 42").Scan(&n)
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestPoolSet(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	database := config.ConnConfig.Database
+
+	set, err := pgxpool.NewPoolSet(pgxpool.PoolSetConfig{Config: config, MaxTotalConns: 1})
+	require.NoError(t, err)
+	defer set.Close()
+
+	pool, err := set.Pool(ctx, database)
+	require.NoError(t, err)
+	again, err := set.Pool(ctx, database)
+	require.NoError(t, err)
+	assert.True(t, pool == again)
+
+	var currentDatabase string
+	err = pool.QueryRow(ctx, "select current_database()").Scan(&currentDatabase)
+	require.NoError(t, err)
+	assert.Equal(t, database, currentDatabase)
+
+	// The only connection allowed is idle in pool so the connection to template1 must close it.
+	other, err := set.Pool(ctx, "template1")
+	require.NoError(t, err)
+	err = other.QueryRow(ctx, "select current_database()").Scan(&currentDatabase)
+	require.NoError(t, err)
+	assert.Equal(t, "template1", currentDatabase)
+
+	assert.Eventually(t, func() bool { return set.TotalConns() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, set.Pools(), 2)
+
+	set.Remove("template1")
+	assert.Len(t, set.Pools(), 1)
+
+	set.Close()
+	_, err = set.Pool(ctx, database)
+	assert.Equal(t, pgxpool.ErrPoolSetClosed, err)
+}
+
+func TestNewPoolSetRejectsMinConnsWithMaxTotalConns(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig("pool_min_conns=1")
+	require.NoError(t, err)
+
+	_, err = pgxpool.NewPoolSet(pgxpool.PoolSetConfig{Config: config, MaxTotalConns: 4})
+	require.Error(t, err)
+
+	set, err := pgxpool.NewPoolSet(pgxpool.PoolSetConfig{Config: config})
+	require.NoError(t, err)
+	set.Close()
+}
+
+func TestPoolAcquireTenant(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MaxConns = 1
+
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	var defaultSearchPath string
+	err = pool.QueryRow(ctx, "show search_path").Scan(&defaultSearchPath)
+	require.NoError(t, err)
+
+	_, err = pool.Exec(ctx, `create schema if not exists "pool acquire ""tenant"""`)
+	require.NoError(t, err)
+	defer pool.Exec(context.Background(), `drop schema "pool acquire ""tenant"""`)
+
+	err = pool.AcquireTenantFunc(ctx, `pool acquire "tenant"`, func(c *pgxpool.Conn) error {
+		var schema string
+		err := c.QueryRow(ctx, "select current_schema()").Scan(&schema)
+		require.NoError(t, err)
+		assert.Equal(t, `pool acquire "tenant"`, schema)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The only connection of the pool must have been reset when it was released.
+	var searchPath string
+	err = pool.QueryRow(ctx, "show search_path").Scan(&searchPath)
+	require.NoError(t, err)
+	assert.Equal(t, defaultSearchPath, searchPath)
+
+	for _, schema := range []string{"", "pg_catalog", strings.Repeat("a", 64), "a\x00b"} {
+		_, err = pool.AcquireTenant(ctx, schema)
+		assert.Errorf(t, err, "%q", schema)
+	}
+}
+
+func TestPoolAcquireTenantRestoresSearchPathSetByAfterConnect(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MaxConns = 1
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, "set search_path = pg_temp, public")
+		return err
+	}
+
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	var afterConnectSearchPath string
+	err = pool.QueryRow(ctx, "show search_path").Scan(&afterConnectSearchPath)
+	require.NoError(t, err)
+
+	err = pool.AcquireTenantFunc(ctx, "public", func(c *pgxpool.Conn) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	var searchPath string
+	err = pool.QueryRow(ctx, "show search_path").Scan(&searchPath)
+	require.NoError(t, err)
+	assert.Equal(t, afterConnectSearchPath, searchPath)
+}
+
+func TestPoolPrepareAll(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.LazyConnect = true
+	config.MaxConns = 2
+
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	latencies, err := pool.PrepareAll(ctx, map[string]string{
+		"add":   "select $1::int4 + $2::int4",
+		"upper": "select upper($1::text)",
+	})
+	require.NoError(t, err)
+	require.Len(t, latencies, 2)
+	for name, latency := range latencies {
+		assert.EqualValuesf(t, 1, latency.Conns, "%s", name)
+		assert.Truef(t, latency.Max > 0, "%s", name)
+		assert.Equalf(t, latency.Max, latency.Mean(), "%s", name)
+	}
+
+	// Both connections must have the statements prepared, including the one established after PrepareAll.
+	c1, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	defer c1.Release()
+	c2, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	defer c2.Release()
+	for _, c := range []*pgxpool.Conn{c1, c2} {
+		var n int32
+		err = c.QueryRow(ctx, "add", 1, 2).Scan(&n)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, n)
+	}
+	assert.EqualValues(t, 2, pool.PrepareStats()["add"].Conns)
+
+	_, err = pool.PrepareAll(ctx, map[string]string{"add": "select $1::int8 + $2::int8"})
+	assert.Error(t, err)
+}
+
+func TestPoolPrepareAllInvalidStatement(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.PrepareAll(ctx, map[string]string{"bad": "selct 1"})
+	require.Error(t, err)
+
+	// The failed statement must not be prepared on later connections.
+	err = pool.Ping(ctx)
+	require.NoError(t, err)
+}
+
+func TestPoolPrepareAllStatementFailsAfterRegistration(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MaxConns = 2
+
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, "drop table if exists pgxpool_prepare_all; create table pgxpool_prepare_all (a int4)")
+	require.NoError(t, err)
+	defer pool.Exec(context.Background(), "drop table if exists pgxpool_prepare_all")
+
+	_, err = pool.PrepareAll(ctx, map[string]string{
+		"select_a": "select a from pgxpool_prepare_all",
+		"one":      "select 1",
+	})
+	require.NoError(t, err)
+
+	// A migration removes the column a registered statement references.
+	_, err = pool.Exec(ctx, "alter table pgxpool_prepare_all drop column a")
+	require.NoError(t, err)
+
+	// The connection established now cannot prepare select_a but must still be usable.
+	c1, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	c2, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	for _, c := range []*pgxpool.Conn{c1, c2} {
+		var n int32
+		err = c.QueryRow(ctx, "one").Scan(&n)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, n)
+	}
+	c1.Release()
+	c2.Release()
+
+	stats := pool.PrepareStats()
+	assert.EqualValues(t, 1, stats["select_a"].Failures)
+	assert.Error(t, stats["select_a"].Err)
+	assert.EqualValues(t, 0, stats["one"].Failures)
+
+	// Once unregistered the name can be registered again with different sql.
+	pool.Unprepare("select_a")
+	assert.NotContains(t, pool.PrepareStats(), "select_a")
+	_, err = pool.PrepareAll(ctx, map[string]string{"select_a": "select count(*) from pgxpool_prepare_all"})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		var n int64
+		err = pool.QueryRow(ctx, "select_a").Scan(&n)
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, n)
+	}
+}
@@ -0,0 +1,273 @@
+package pgxpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// PrepareLatency is the time taken to prepare a statement registered with PrepareAll.
+type PrepareLatency struct {
+	Conns int64         // number of connections the statement was prepared on
+	Total time.Duration // time spent preparing on all connections
+	Max   time.Duration // longest time spent preparing on one connection
+
+	Failures int64 // number of connections the statement failed to prepare on after PrepareAll returned
+	Err      error // most recent failure
+}
+
+// Mean returns the average time to prepare the statement on one connection.
+func (pl PrepareLatency) Mean() time.Duration {
+	if pl.Conns == 0 {
+		return 0
+	}
+	return pl.Total / time.Duration(pl.Conns)
+}
+
+func (pl *PrepareLatency) add(d time.Duration) {
+	pl.Conns++
+	pl.Total += d
+	if d > pl.Max {
+		pl.Max = d
+	}
+}
+
+func (pl *PrepareLatency) merge(other PrepareLatency) {
+	pl.Conns += other.Conns
+	pl.Total += other.Total
+	if other.Max > pl.Max {
+		pl.Max = other.Max
+	}
+	pl.Failures += other.Failures
+	if other.Err != nil {
+		pl.Err = other.Err
+	}
+}
+
+// PrepareAll registers statements, a map of prepared statement name to sql, to be prepared on every connection of the
+// pool. It is intended to be called at startup so that latency-sensitive services pay the cost of parsing and
+// describing their statements before taking traffic. The statements are prepared on all idle connections before
+// PrepareAll returns, establishing a connection first if the pool has none. New connections prepare them before they
+// are added to the pool and connections in use prepare them when they are next acquired. PrepareAll may be called more
+// than once to register more statements but a name cannot be registered again with different sql unless it is first
+// unregistered with Unprepare.
+//
+// PrepareAll returns the latency of preparing each statement on the idle connections. If any statement fails to
+// prepare, the statements of this call are unregistered and the error is returned. PrepareStats returns the latencies
+// of all connections including those established later.
+//
+// A statement that fails to prepare on a connection after PrepareAll returned, such as when a migration dropped a
+// column it references, does not fail the connection. It is logged with the connection's Logger, counted in the
+// Failures of PrepareStats, and not tried again on that connection until the registered statements change. Unprepare
+// removes it.
+func (p *Pool) PrepareAll(ctx context.Context, statements map[string]string) (map[string]PrepareLatency, error) {
+	p.prepareAllMux.Lock()
+	defer p.prepareAllMux.Unlock()
+
+	p.prepareMux.RLock()
+	prev := p.prepareStmts
+	p.prepareMux.RUnlock()
+
+	stmts := make(map[string]string, len(prev)+len(statements))
+	for name, sql := range prev {
+		stmts[name] = sql
+	}
+	for name, sql := range statements {
+		if name == "" {
+			return nil, fmt.Errorf("prepared statement name must not be empty: %s", sql)
+		}
+		if prevSQL, ok := prev[name]; ok && prevSQL != sql {
+			return nil, fmt.Errorf("prepared statement %q already registered with different sql: %s", name, prevSQL)
+		}
+		stmts[name] = sql
+	}
+
+	// Establish a connection before registering the statements so its preparation is part of the returned latencies.
+	if p.Stat().TotalConns() == 0 {
+		res, err := p.p.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		res.ReleaseUnused()
+	}
+
+	p.setPrepareStmts(stmts)
+
+	resources := p.p.AcquireAllIdle()
+	results := make([]map[string]PrepareLatency, len(resources))
+	errs := make([]error, len(resources))
+	var wg sync.WaitGroup
+	for i := range resources {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = p.prepareConn(ctx, resources[i].Value().(*connResource), true)
+		}(i)
+	}
+	wg.Wait()
+
+	var firstErr error
+	for i, res := range resources {
+		if errs[i] != nil {
+			res.Destroy()
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+		} else {
+			res.ReleaseUnused()
+		}
+	}
+	if firstErr != nil {
+		p.setPrepareStmts(prev)
+		return nil, firstErr
+	}
+
+	latencies := make(map[string]PrepareLatency, len(statements))
+	for _, result := range results {
+		for name, latency := range result {
+			if _, ok := statements[name]; ok {
+				l := latencies[name]
+				l.merge(latency)
+				latencies[name] = l
+			}
+		}
+	}
+
+	return latencies, nil
+}
+
+// PrepareStats returns the latency of preparing each statement registered with PrepareAll on all connections of the
+// pool so far.
+func (p *Pool) PrepareStats() map[string]PrepareLatency {
+	p.prepareMux.RLock()
+	defer p.prepareMux.RUnlock()
+
+	stats := make(map[string]PrepareLatency, len(p.prepareStats))
+	for name, latency := range p.prepareStats {
+		stats[name] = latency
+	}
+	return stats
+}
+
+// Unprepare unregisters statements registered with PrepareAll and removes their PrepareStats. Each connection
+// deallocates them the next time it is acquired.
+func (p *Pool) Unprepare(names ...string) {
+	p.prepareAllMux.Lock()
+	defer p.prepareAllMux.Unlock()
+
+	p.prepareMux.RLock()
+	prev := p.prepareStmts
+	p.prepareMux.RUnlock()
+
+	stmts := make(map[string]string, len(prev))
+	for name, sql := range prev {
+		stmts[name] = sql
+	}
+	for _, name := range names {
+		delete(stmts, name)
+	}
+	p.setPrepareStmts(stmts)
+
+	p.prepareMux.Lock()
+	for _, name := range names {
+		delete(p.prepareStats, name)
+	}
+	p.prepareMux.Unlock()
+}
+
+func (p *Pool) setPrepareStmts(stmts map[string]string) {
+	p.prepareMux.Lock()
+	p.prepareStmts = stmts
+	p.prepareGen++
+	p.prepareMux.Unlock()
+}
+
+// prepareConn prepares the registered statements that cr has not prepared yet and deallocates those it prepared that
+// are no longer registered. It returns the latency of each statement it prepared. If failFast is set a statement that
+// fails to prepare is returned as an error. Otherwise it is logged and recorded in PrepareStats and the others are
+// still prepared. An error that is not from the server, which means the connection is broken, is always returned.
+func (p *Pool) prepareConn(ctx context.Context, cr *connResource, failFast bool) (map[string]PrepareLatency, error) {
+	p.prepareMux.RLock()
+	gen := p.prepareGen
+	stmts := p.prepareStmts
+	p.prepareMux.RUnlock()
+
+	if cr.prepareGen == gen {
+		return nil, nil
+	}
+
+	prepared := cr.conn.PreparedStatements()
+	for name, sql := range cr.prepareStmts {
+		if stmts[name] == sql {
+			continue
+		}
+		if sd, ok := prepared[name]; ok && sd.SQL == sql {
+			if err := cr.conn.Deallocate(ctx, name); err != nil {
+				var pgErr *pgconn.PgError
+				if !errors.As(err, &pgErr) {
+					return nil, fmt.Errorf("deallocate %s: %w", name, err)
+				}
+			}
+			delete(prepared, name)
+		}
+	}
+
+	latencies := make(map[string]PrepareLatency)
+	prepareStmts := make(map[string]string, len(stmts))
+	for name, sql := range stmts {
+		if sd, ok := prepared[name]; ok && sd.SQL == sql {
+			prepareStmts[name] = sql
+			continue
+		}
+
+		start := time.Now()
+		if _, err := cr.conn.Prepare(ctx, name, sql); err != nil {
+			var pgErr *pgconn.PgError
+			if failFast || !errors.As(err, &pgErr) {
+				return nil, fmt.Errorf("prepare %s: %w", name, err)
+			}
+
+			logPrepareFailure(ctx, cr.conn, name, err)
+			l := latencies[name]
+			l.Failures++
+			l.Err = err
+			latencies[name] = l
+			continue
+		}
+		prepareStmts[name] = sql
+		l := latencies[name]
+		l.add(time.Since(start))
+		latencies[name] = l
+	}
+
+	p.prepareMux.Lock()
+	if p.prepareStats == nil {
+		p.prepareStats = make(map[string]PrepareLatency)
+	}
+	for name, latency := range latencies {
+		if _, ok := p.prepareStmts[name]; !ok {
+			// Unprepare ran concurrently.
+			continue
+		}
+		l := p.prepareStats[name]
+		l.merge(latency)
+		p.prepareStats[name] = l
+	}
+	p.prepareMux.Unlock()
+
+	cr.prepareGen = gen
+	cr.prepareStmts = prepareStmts
+	return latencies, nil
+}
+
+func logPrepareFailure(ctx context.Context, conn *pgx.Conn, name string, err error) {
+	config := conn.Config()
+	if config.Logger != nil && config.LogLevel >= pgx.LogLevelWarn {
+		config.Logger.Log(ctx, pgx.LogLevelWarn, "failed to prepare statement registered with PrepareAll", map[string]interface{}{"name": name, "err": err})
+	}
+}
@@ -0,0 +1,40 @@
+package pgxpool
+
+import "context"
+
+// QueryInfo receives information about the connection used to execute a query through a Pool. See WithQueryInfo.
+type QueryInfo struct {
+	// BackendPID is the process ID of the server backend that executed the query. It can be matched with the pid column
+	// of pg_stat_activity or passed to CancelQuery.
+	BackendPID uint32
+}
+
+type queryInfoKey struct{}
+
+// WithQueryInfo returns a context that causes the Pool to fill in info when it acquires a connection with the context.
+// This applies to Acquire and to every Pool method that acquires a connection such as Exec, Query, QueryRow, and Begin.
+// If the context is used for more than one acquire info describes the last one.
+//
+//	var info pgxpool.QueryInfo
+//	_, err := pool.Exec(pgxpool.WithQueryInfo(ctx, &info), sql)
+//	log.Printf("executed by backend %d", info.BackendPID)
+func WithQueryInfo(ctx context.Context, info *QueryInfo) context.Context {
+	return context.WithValue(ctx, queryInfoKey{}, info)
+}
+
+// setQueryInfo fills in the QueryInfo of ctx, if any, for c.
+func setQueryInfo(ctx context.Context, c *Conn) {
+	if info, ok := ctx.Value(queryInfoKey{}).(*QueryInfo); ok {
+		info.BackendPID = c.Conn().PgConn().PID()
+	}
+}
+
+// CancelQuery asks the server to cancel the query currently being executed by the backend with process ID pid, such as
+// one found with WithQueryInfo or in pg_stat_activity. It uses pg_cancel_backend so the user of the pool must be
+// allowed to signal that backend. It reports whether the backend was signaled. Cancellation is asynchronous and the
+// query may complete before it is canceled.
+func (p *Pool) CancelQuery(ctx context.Context, pid uint32) (bool, error) {
+	var signaled bool
+	err := p.QueryRow(ctx, "select pg_cancel_backend($1)", int32(pid)).Scan(&signaled)
+	return signaled, err
+}
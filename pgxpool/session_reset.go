@@ -0,0 +1,51 @@
+package pgxpool
+
+import (
+	"context"
+	"time"
+
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// SessionReset determines how session state is cleared when a connection is released to the pool.
+type SessionReset int
+
+const (
+	// SessionResetNone leaves the session state as is.
+	SessionResetNone SessionReset = iota
+
+	// SessionResetReset resets run-time parameters, stops listening on all channels, and releases advisory locks. It
+	// keeps prepared statements so the statement cache remains effective. Temporary tables are not dropped.
+	//
+	// Parameters are reset to their values at the start of the session, which include those sent in
+	// ConnConfig.RuntimeParams. Parameters set with SET in AfterConnect, such as search_path, are lost. Set them in
+	// RuntimeParams instead.
+	SessionResetReset
+
+	// SessionResetDiscardAll executes DISCARD ALL. This resets all session state including prepared statements and
+	// temporary tables. The statement caches of the connection are cleared. Like SessionResetReset, parameters set with
+	// SET in AfterConnect are lost.
+	SessionResetDiscardAll
+)
+
+var sessionResetTimeout = 5 * time.Second
+
+func resetSession(conn *pgx.Conn, mode SessionReset) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionResetTimeout)
+	defer cancel()
+
+	switch mode {
+	case SessionResetReset:
+		_, err := conn.Exec(ctx, "reset all; unlisten *; select pg_advisory_unlock_all()")
+		return err
+	case SessionResetDiscardAll:
+		err := conn.DeallocateAll(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = conn.Exec(ctx, "discard all")
+		return err
+	}
+
+	return nil
+}
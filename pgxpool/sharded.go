@@ -0,0 +1,262 @@
+package pgxpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// shardVirtualNodes is the number of points each shard has on the hash ring. More points spread keys more evenly.
+const shardVirtualNodes = 128
+
+// ErrNoShardKey occurs when a query is routed by its arguments but the shard key cannot be determined.
+var ErrNoShardKey = errors.New("no shard key")
+
+// ShardUnavailableError occurs when a key maps to a shard whose last health check failed.
+type ShardUnavailableError struct {
+	Shard string
+	Err   error // error of the last health check
+}
+
+func (e *ShardUnavailableError) Error() string {
+	return fmt.Sprintf("shard %s is unavailable: %v", e.Shard, e.Err)
+}
+
+func (e *ShardUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// ShardedConfig is the configuration of a ShardedPool.
+type ShardedConfig struct {
+	// Shards maps a shard name to the pool for that shard. Keys are placed on a consistent hash ring by shard name so
+	// adding or removing a shard only moves the keys of that shard. The same names must be used by every process.
+	Shards map[string]*Pool
+
+	// ShardKey returns the shard key of a query executed with Exec, Query, or QueryRow from its arguments. If nil, the
+	// first argument is used.
+	ShardKey func(sql string, args []interface{}) (string, error)
+
+	// HealthCheckPeriod is the duration between health checks of every shard. A shard that fails its health check is
+	// unavailable until it passes again. Set to 0 to disable health checks.
+	HealthCheckPeriod time.Duration
+}
+
+// ShardedPool routes queries to one of several pools by a shard key. It is intended for horizontally partitioned
+// databases where the application determines the shard of each row. Each key always maps to the same shard as long as
+// the set of shards does not change. Unlike host fallbacks, a key is never routed to a different shard when its shard
+// is unhealthy as the data only exists on that shard; ShardUnavailableError is returned instead.
+type ShardedPool struct {
+	shards   map[string]*poolShard
+	ring     []ringPoint
+	shardKey func(sql string, args []interface{}) (string, error)
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+type poolShard struct {
+	name string
+	pool *Pool
+
+	mux       sync.Mutex
+	healthErr error
+}
+
+type ringPoint struct {
+	hash  uint64
+	shard *poolShard
+}
+
+// NewSharded creates a ShardedPool. The ShardedPool takes ownership of the pools and closes them when it is closed.
+func NewSharded(config ShardedConfig) (*ShardedPool, error) {
+	if len(config.Shards) == 0 {
+		return nil, errors.New("no shards")
+	}
+
+	sp := &ShardedPool{
+		shards:    make(map[string]*poolShard, len(config.Shards)),
+		ring:      make([]ringPoint, 0, len(config.Shards)*shardVirtualNodes),
+		shardKey:  config.ShardKey,
+		closeChan: make(chan struct{}),
+	}
+	if sp.shardKey == nil {
+		sp.shardKey = firstArgShardKey
+	}
+
+	for name, pool := range config.Shards {
+		if pool == nil {
+			return nil, fmt.Errorf("shard %s has no pool", name)
+		}
+		shard := &poolShard{name: name, pool: pool}
+		sp.shards[name] = shard
+		for i := 0; i < shardVirtualNodes; i++ {
+			sp.ring = append(sp.ring, ringPoint{hash: hashShardKey(name + "#" + strconv.Itoa(i)), shard: shard})
+		}
+	}
+	sort.Slice(sp.ring, func(i, j int) bool {
+		if sp.ring[i].hash == sp.ring[j].hash {
+			return sp.ring[i].shard.name < sp.ring[j].shard.name
+		}
+		return sp.ring[i].hash < sp.ring[j].hash
+	})
+
+	if config.HealthCheckPeriod > 0 {
+		go sp.backgroundHealthCheck(config.HealthCheckPeriod)
+	}
+
+	return sp, nil
+}
+
+func firstArgShardKey(sql string, args []interface{}) (string, error) {
+	if len(args) == 0 || args[0] == nil {
+		return "", ErrNoShardKey
+	}
+	return fmt.Sprint(args[0]), nil
+}
+
+// hashShardKey hashes key with FNV-1a followed by the splitmix64 finalizer. FNV-1a alone distributes similar short
+// strings such as virtual node names poorly.
+func hashShardKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// ShardName returns the name of the shard key maps to.
+func (sp *ShardedPool) ShardName(key string) string {
+	return sp.shardFor(key).name
+}
+
+func (sp *ShardedPool) shardFor(key string) *poolShard {
+	hash := hashShardKey(key)
+	i := sort.Search(len(sp.ring), func(i int) bool { return sp.ring[i].hash >= hash })
+	if i == len(sp.ring) {
+		i = 0
+	}
+	return sp.ring[i].shard
+}
+
+// Shard returns the pool of the shard key maps to. It returns a *ShardUnavailableError if the last health check of the
+// shard failed.
+func (sp *ShardedPool) Shard(key string) (*Pool, error) {
+	shard := sp.shardFor(key)
+
+	shard.mux.Lock()
+	healthErr := shard.healthErr
+	shard.mux.Unlock()
+	if healthErr != nil {
+		return nil, &ShardUnavailableError{Shard: shard.name, Err: healthErr}
+	}
+
+	return shard.pool, nil
+}
+
+// Pools returns the pool of every shard by name.
+func (sp *ShardedPool) Pools() map[string]*Pool {
+	pools := make(map[string]*Pool, len(sp.shards))
+	for name, shard := range sp.shards {
+		pools[name] = shard.pool
+	}
+	return pools
+}
+
+func (sp *ShardedPool) shardForQuery(sql string, args []interface{}) (*Pool, error) {
+	key, err := sp.shardKey(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	return sp.Shard(key)
+}
+
+// Exec executes sql on the shard of the shard key of the query. See ShardedConfig.ShardKey.
+func (sp *ShardedPool) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	pool, err := sp.shardForQuery(sql, arguments)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Exec(ctx, sql, arguments...)
+}
+
+// Query executes sql on the shard of the shard key of the query. See ShardedConfig.ShardKey.
+func (sp *ShardedPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	pool, err := sp.shardForQuery(sql, args)
+	if err != nil {
+		return errRows{err: err}, err
+	}
+	return pool.Query(ctx, sql, args...)
+}
+
+// QueryRow executes sql on the shard of the shard key of the query. See ShardedConfig.ShardKey.
+func (sp *ShardedPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	pool, err := sp.shardForQuery(sql, args)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return pool.QueryRow(ctx, sql, args...)
+}
+
+// CheckHealth pings every shard and updates its availability. It returns the error of each shard that failed.
+func (sp *ShardedPool) CheckHealth(ctx context.Context) map[string]error {
+	type result struct {
+		shard *poolShard
+		err   error
+	}
+	results := make(chan result, len(sp.shards))
+	for _, shard := range sp.shards {
+		go func(shard *poolShard) {
+			results <- result{shard: shard, err: shard.pool.Ping(ctx)}
+		}(shard)
+	}
+
+	errs := make(map[string]error)
+	for range sp.shards {
+		r := <-results
+		r.shard.mux.Lock()
+		r.shard.healthErr = r.err
+		r.shard.mux.Unlock()
+		if r.err != nil {
+			errs[r.shard.name] = r.err
+		}
+	}
+	return errs
+}
+
+func (sp *ShardedPool) backgroundHealthCheck(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sp.closeChan:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), period)
+			sp.CheckHealth(ctx)
+			cancel()
+		}
+	}
+}
+
+// Close stops health checks and closes the pool of every shard.
+func (sp *ShardedPool) Close() {
+	sp.closeOnce.Do(func() {
+		close(sp.closeChan)
+		for _, shard := range sp.shards {
+			shard.pool.Close()
+		}
+	})
+}
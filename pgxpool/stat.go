@@ -8,12 +8,13 @@ import (
 
 // Stat is a snapshot of Pool statistics.
 type Stat struct {
-	s *puddle.Stat
+	s                *puddle.Stat
+	idleAcquireCount int64 // acquires of AcquireFIFO that puddle does not count
 }
 
 // AcquireCount returns the cumulative count of successful acquires from the pool.
 func (s *Stat) AcquireCount() int64 {
-	return s.s.AcquireCount()
+	return s.s.AcquireCount() + s.idleAcquireCount
 }
 
 // AcquireDuration returns the total duration of all successful acquires from
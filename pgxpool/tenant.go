@@ -0,0 +1,89 @@
+package pgxpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// maxIdentifierLen is the longest identifier PostgreSQL keeps with the default NAMEDATALEN. Longer names are silently
+// truncated which could map one tenant to the schema of another.
+const maxIdentifierLen = 63
+
+// AcquireTenant acquires a *Conn with search_path set to only schema. It is intended for multi-tenant applications with
+// a schema per tenant. search_path is restored to its previous value, such as one set by AfterConnect, when the *Conn is
+// released so the tenant cannot leak to the next borrower of the connection. With SessionResetReset and
+// SessionResetDiscardAll it is instead reset to the default of the session along with all other parameters. Objects in
+// other schemas such as extensions in public must be qualified with their schema.
+func (p *Pool) AcquireTenant(ctx context.Context, schema string) (*Conn, error) {
+	if err := validateTenantSchema(schema); err != nil {
+		return nil, err
+	}
+
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mark the connection before setting search_path so it is reset even if the result of set_config is lost.
+	cr := c.res.Value().(*connResource)
+	cr.tenantScoped = true
+	cr.tenantSearchPathKnown = false
+	err = c.QueryRow(ctx, "select current_setting('search_path'), set_config('search_path', $1, false)",
+		pgx.Identifier{schema}.Sanitize()).Scan(&cr.tenantSearchPath, nil)
+	if err != nil {
+		c.Release()
+		return nil, fmt.Errorf("set search_path for tenant %s: %w", schema, err)
+	}
+	cr.tenantSearchPathKnown = true
+
+	return c, nil
+}
+
+// AcquireTenantFunc acquires a *Conn scoped to schema with AcquireTenant and calls f with it. The *Conn is released
+// after f returns. The return value is either an error acquiring the *Conn or the return value of f.
+func (p *Pool) AcquireTenantFunc(ctx context.Context, schema string, f func(*Conn) error) error {
+	c, err := p.AcquireTenant(ctx, schema)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	return f(c)
+}
+
+func validateTenantSchema(schema string) error {
+	switch {
+	case schema == "":
+		return errors.New("invalid tenant schema: empty")
+	case len(schema) > maxIdentifierLen:
+		return fmt.Errorf("invalid tenant schema %q: longer than %d bytes", schema, maxIdentifierLen)
+	case strings.IndexByte(schema, 0) >= 0:
+		return fmt.Errorf("invalid tenant schema %q: contains NUL", schema)
+	case strings.HasPrefix(schema, "pg_"):
+		return fmt.Errorf("invalid tenant schema %q: pg_ is reserved for system schemas", schema)
+	}
+	return nil
+}
+
+func resetTenant(conn *pgx.Conn, cr *connResource) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionResetTimeout)
+	defer cancel()
+
+	if !cr.tenantSearchPathKnown {
+		_, err := conn.Exec(ctx, "reset search_path")
+		return err
+	}
+
+	_, err := conn.Exec(ctx, "select set_config('search_path', $1, false)", cr.tenantSearchPath)
+	return err
+}
+
+// tenantResetNeeded reports whether the search_path of a released connection must be restored before it is reused.
+// SessionResetReset and SessionResetDiscardAll already reset it.
+func (p *Pool) tenantResetNeeded(cr *connResource) bool {
+	return cr.tenantScoped && p.resetSession == SessionResetNone
+}
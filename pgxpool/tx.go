@@ -69,6 +69,11 @@ func (tx *Tx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementD
 	return tx.t.Prepare(ctx, name, sql)
 }
 
+// SetConstraints sets when deferrable constraints are checked for the rest of the transaction. See pgx.Tx.SetConstraints.
+func (tx *Tx) SetConstraints(ctx context.Context, mode pgx.ConstraintMode, names ...pgx.Identifier) error {
+	return tx.t.SetConstraints(ctx, mode, names...)
+}
+
 func (tx *Tx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
 	return tx.t.Exec(ctx, sql, arguments...)
 }
@@ -0,0 +1,64 @@
+package pgxpool
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// beginTxFuncFailover calls BeginTxFunc on an acquired connection. If the connection is lost during the transaction it
+// is called again on another connection up to p.readOnlyTxFailovers more times.
+//
+// The statements of the failed attempt are not replayed. The results they returned may already have been used by f, so
+// f itself is run again from the start of a new transaction. The host of a lost connection is put on cooldown so new
+// connections prefer other hosts, and idle connections to it made before it failed are closed instead of being used.
+func (p *Pool) beginTxFuncFailover(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error {
+	failed := make(map[string]time.Time)
+	for attempt := int32(0); ; attempt++ {
+		c, err := p.acquireAvoiding(ctx, failed)
+		if err != nil {
+			return err
+		}
+
+		err = c.BeginTxFunc(ctx, txOptions, f)
+		lost := err != nil && c.Conn().IsClosed() && ctx.Err() == nil
+		key := connHostKey(c.Conn())
+		c.Release()
+
+		if !lost || attempt >= p.readOnlyTxFailovers {
+			return err
+		}
+
+		now := time.Now()
+		p.hostHealth.failed(key, now)
+		failed[key] = now
+	}
+}
+
+// acquireAvoiding acquires a connection that was not made to a host in failed before the time it failed.
+func (p *Pool) acquireAvoiding(ctx context.Context, failed map[string]time.Time) (*Conn, error) {
+	for {
+		c, err := p.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		failedAt, ok := failed[connHostKey(c.Conn())]
+		if !ok || c.res.CreationTime().After(failedAt) {
+			return c, nil
+		}
+
+		res := c.res
+		c.res = nil
+		res.Destroy()
+	}
+}
+
+// connHostKey returns the key of the host conn is connected to as used by hostHealth.
+func connHostKey(conn *pgx.Conn) string {
+	config := conn.Config()
+	return net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port)))
+}
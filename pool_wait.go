@@ -0,0 +1,36 @@
+package pgx
+
+import "time"
+
+// PoolWait describes how a connection was obtained from a connection pool.
+type PoolWait struct {
+	// Duration is how long the acquire waited for the connection, including the time to establish it if NewConn is
+	// true.
+	Duration time.Duration
+
+	// NewConn is true if the pool had no idle connection and established a new one for the acquire.
+	NewConn bool
+}
+
+// SetPoolWait records how the connection was acquired from a pool. It is reported as QueryStat.PoolWait and in the log
+// of the next Query, Exec or CopyFrom on the connection so that latency can be attributed to waiting for the pool
+// instead of to the query. pgxpool calls it on every acquire. It is only needed by other pool implementations.
+func (c *Conn) SetPoolWait(w PoolWait) {
+	c.poolWait = &w
+}
+
+// takePoolWait returns and clears the PoolWait recorded by SetPoolWait.
+func (c *Conn) takePoolWait() *PoolWait {
+	w := c.poolWait
+	c.poolWait = nil
+	return w
+}
+
+// addPoolWaitLogFields adds w to the fields of a log message.
+func addPoolWaitLogFields(data map[string]interface{}, w *PoolWait) map[string]interface{} {
+	if w != nil {
+		data["poolWait"] = w.Duration
+		data["poolNewConn"] = w.NewConn
+	}
+	return data
+}
@@ -0,0 +1,157 @@
+package pgx
+
+import (
+	"container/list"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgconn"
+)
+
+// preparedStatementCache tracks the statements created with Conn.Prepare in least recently used order.
+type preparedStatementCache struct {
+	l *list.List               // *pgconn.StatementDescription, most recently used first
+	m map[string]*list.Element // statement name to element of l
+
+	// stale contains statements that must be prepared again before they are used because they were evicted or
+	// invalidated.
+	stale map[string]staleStatement
+
+	// paramOIDs are the parameter types given to PrepareWithParamOIDs by statement name. They are sent again when a
+	// stale statement is prepared again.
+	paramOIDs map[string][]uint32
+}
+
+type staleStatement struct {
+	sd   *pgconn.StatementDescription
+	open bool // statement still exists on the server and must be closed before it is prepared again
+}
+
+func newPreparedStatementCache() *preparedStatementCache {
+	return &preparedStatementCache{
+		l:         list.New(),
+		m:         make(map[string]*list.Element),
+		stale:     make(map[string]staleStatement),
+		paramOIDs: make(map[string][]uint32),
+	}
+}
+
+func (psc *preparedStatementCache) get(name string) *pgconn.StatementDescription {
+	if el, ok := psc.m[name]; ok {
+		psc.l.MoveToFront(el)
+		return el.Value.(*pgconn.StatementDescription)
+	}
+	return nil
+}
+
+func (psc *preparedStatementCache) put(sd *pgconn.StatementDescription, paramOIDs []uint32) {
+	delete(psc.stale, sd.Name)
+	if len(paramOIDs) > 0 {
+		psc.paramOIDs[sd.Name] = paramOIDs
+	} else {
+		delete(psc.paramOIDs, sd.Name)
+	}
+	if el, ok := psc.m[sd.Name]; ok {
+		el.Value = sd
+		psc.l.MoveToFront(el)
+		return
+	}
+	psc.m[sd.Name] = psc.l.PushFront(sd)
+}
+
+func (psc *preparedStatementCache) remove(name string) {
+	if el, ok := psc.m[name]; ok {
+		psc.l.Remove(el)
+		delete(psc.m, name)
+	}
+	delete(psc.stale, name)
+	delete(psc.paramOIDs, name)
+}
+
+// markStale removes name from the cache and records it so it is prepared again on the next use.
+func (psc *preparedStatementCache) markStale(name string, open bool) {
+	el, ok := psc.m[name]
+	if !ok {
+		return
+	}
+	sd := el.Value.(*pgconn.StatementDescription)
+	psc.l.Remove(el)
+	delete(psc.m, name)
+	psc.stale[name] = staleStatement{sd: sd, open: open}
+}
+
+// preparedStatement returns the statement that was prepared as name with Prepare. A statement that was evicted or
+// invalidated is transparently prepared again. It returns nil if name is not the name of a prepared statement.
+func (c *Conn) preparedStatement(ctx context.Context, name string) (*pgconn.StatementDescription, error) {
+	if sd := c.preparedStatements.get(name); sd != nil {
+		return sd, nil
+	}
+
+	if stale, ok := c.preparedStatements.stale[name]; ok {
+		return c.prepare(ctx, name, stale.sd.SQL, c.preparedStatements.paramOIDs[name])
+	}
+
+	return nil, nil
+}
+
+// PreparedStatements returns the statements created with Prepare by name. This includes statements that were evicted
+// or invalidated and will be prepared again when next used. The returned map is a copy and may be modified.
+func (c *Conn) PreparedStatements() map[string]*pgconn.StatementDescription {
+	statements := make(map[string]*pgconn.StatementDescription, len(c.preparedStatements.m)+len(c.preparedStatements.stale))
+	for name, el := range c.preparedStatements.m {
+		statements[name] = el.Value.(*pgconn.StatementDescription)
+	}
+	for name, stale := range c.preparedStatements.stale {
+		statements[name] = stale.sd
+	}
+	return statements
+}
+
+// evictPreparedStatements closes the least recently used statements until no more than c.config.MaxPreparedStatements
+// remain prepared.
+func (c *Conn) evictPreparedStatements(ctx context.Context) error {
+	max := c.config.MaxPreparedStatements
+	if max <= 0 {
+		return nil
+	}
+
+	for c.preparedStatements.l.Len() > max {
+		sd := c.preparedStatements.l.Back().Value.(*pgconn.StatementDescription)
+		c.preparedStatements.markStale(sd.Name, false)
+
+		err := c.closeStatement(ctx, sd.Name)
+		if err != nil {
+			c.preparedStatements.stale[sd.Name] = staleStatement{sd: sd, open: true}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// preparedStatementErrored invalidates the statement prepared as name when err indicates the statement can no longer
+// be used. PostgreSQL reports "cached plan must not change result type" when the result of a prepared statement
+// changes due to DDL. The statement is prepared again the next time it is used.
+func (c *Conn) preparedStatementErrored(name string, err error) {
+	if isInvalidCachedPlanError(err) {
+		c.preparedStatements.markStale(name, true)
+	}
+}
+
+func isInvalidCachedPlanError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "0A000" && pgErr.Message == "cached plan must not change result type"
+}
+
+// canRetryInvalidCachedPlan reports whether a statement that failed with err can be executed again after its prepared
+// statement is replaced. A failure inside a transaction aborts the transaction so only statements executed outside of
+// a transaction are retried.
+func (c *Conn) canRetryInvalidCachedPlan(err error) bool {
+	return isInvalidCachedPlanError(err) && c.pgConn.TxStatus() == 'I'
+}
+
+// closeStatement closes the prepared statement name on the server with the extended protocol Close message. It is not
+// an error to close a statement that does not exist.
+func (c *Conn) closeStatement(ctx context.Context, name string) error {
+	return c.closeObject(ctx, 'S', name)
+}
@@ -0,0 +1,134 @@
+package pgx
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// ProtocolError occurs when a message received from the server is malformed. The connection cannot be used after a
+// ProtocolError as it is no longer known where the next message begins.
+type ProtocolError struct {
+	MessageType byte // type byte of the message, or 0 if it is not known
+	Err         error
+}
+
+func (e *ProtocolError) Error() string {
+	if e.MessageType == 0 {
+		return fmt.Sprintf("protocol error: %v", e.Err)
+	}
+	return fmt.Sprintf("protocol error in message '%c': %v", e.MessageType, e.Err)
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateMessage decodes the backend message with type byte typ and body body, the bytes following the length, and
+// returns a *ProtocolError if it is malformed. It never panics regardless of the input. It is intended for tools that
+// capture or replay the wire protocol, such as tracers and proxies, to check messages before handing them to a
+// connection.
+func ValidateMessage(typ byte, body []byte) (err error) {
+	if len(body) > math.MaxInt32-4 {
+		return &ProtocolError{MessageType: typ, Err: fmt.Errorf("message body too large: %d bytes", len(body))}
+	}
+
+	buf := make([]byte, 5, 5+len(body))
+	buf[0] = typ
+	binary.BigEndian.PutUint32(buf[1:], uint32(len(body)+4))
+	buf = append(buf, body...)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = &ProtocolError{MessageType: typ, Err: fmt.Errorf("decode panicked: %v", r)}
+		}
+	}()
+
+	frontend := pgproto3.NewFrontend(&bufChunkReader{buf: buf}, nil)
+	if _, err := frontend.Receive(); err != nil {
+		return &ProtocolError{MessageType: typ, Err: err}
+	}
+
+	return nil
+}
+
+// CloseStatement closes the prepared statement name with the wire protocol Close message. Unlike Deallocate it does not
+// send SQL so it can be used where only the extended protocol is allowed such as with some poolers. Closing a statement
+// that does not exist is not an error.
+func (c *Conn) CloseStatement(ctx context.Context, name string) error {
+	if err := c.checkCloseObject(); err != nil {
+		return err
+	}
+	if err := c.closeObject(ctx, 'S', name); err != nil {
+		return err
+	}
+	c.preparedStatements.remove(name)
+	return nil
+}
+
+// ClosePortal closes the portal name with the wire protocol Close message. This releases the resources held by a
+// portal bound to a cursor or a suspended query without a SQL round trip. Closing a portal that does not exist is not an
+// error.
+func (c *Conn) ClosePortal(ctx context.Context, name string) error {
+	if err := c.checkCloseObject(); err != nil {
+		return err
+	}
+	return c.closeObject(ctx, 'P', name)
+}
+
+func (c *Conn) checkCloseObject() error {
+	if c.config.TextOnly {
+		return ErrTextOnly
+	}
+	if c.pgConn.IsBusy() {
+		return ErrConnBusy
+	}
+	return nil
+}
+
+// closeObject sends a Close message for the statement or portal name followed by a Sync and waits for the server to
+// be ready for the next query. If sending or receiving fails the connection is closed as the replies to the Close and
+// Sync would otherwise be read by the next query.
+func (c *Conn) closeObject(ctx context.Context, objectType byte, name string) error {
+	buf := (&pgproto3.Close{ObjectType: objectType, Name: name}).Encode(nil)
+	buf = (&pgproto3.Sync{}).Encode(buf)
+	if err := c.pgConn.SendBytes(ctx, buf); err != nil {
+		c.die(err)
+		return err
+	}
+
+	var closeErr error
+	for {
+		msg, err := c.pgConn.ReceiveMessage(ctx)
+		if err != nil {
+			c.die(err)
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.ErrorResponse:
+			closeErr = pgconn.ErrorResponseToPgError(msg)
+		case *pgproto3.ReadyForQuery:
+			return closeErr
+		}
+	}
+}
+
+// bufChunkReader is a pgproto3.ChunkReader that reads from a byte slice.
+type bufChunkReader struct {
+	buf []byte
+}
+
+func (cr *bufChunkReader) Next(n int) ([]byte, error) {
+	if n < 0 || n > len(cr.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := cr.buf[:n]
+	cr.buf = cr.buf[n:]
+	return b, nil
+}
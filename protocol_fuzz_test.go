@@ -0,0 +1,22 @@
+//go:build go1.18
+
+package pgx_test
+
+import (
+	"testing"
+
+	"github.com/nappspt/schemapgx/v4"
+)
+
+func FuzzValidateMessage(f *testing.F) {
+	f.Add(byte('D'), []byte{0, 1, 0, 0, 0, 3, 'f', 'o', 'o'})
+	f.Add(byte('T'), []byte{0, 1, 'n', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 23, 0, 4, 255, 255, 255, 255, 0, 0})
+	f.Add(byte('E'), []byte{'S', 'E', 'R', 'R', 'O', 'R', 0, 0})
+	f.Add(byte('R'), []byte{0, 0, 0, 5, 1, 2, 3, 4})
+	f.Add(byte('Z'), []byte{'I'})
+
+	f.Fuzz(func(t *testing.T, typ byte, body []byte) {
+		// ValidateMessage must return rather than panic or hang for any input.
+		_ = pgx.ValidateMessage(typ, body)
+	})
+}
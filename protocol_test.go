@@ -0,0 +1,60 @@
+package pgx_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgtype"
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMessage(t *testing.T) {
+	t.Parallel()
+
+	dataRow := (&pgproto3.DataRow{Values: [][]byte{[]byte("foo"), nil}}).Encode(nil)
+	rowDescription := (&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{{Name: []byte("n"), DataTypeOID: pgtype.Int4OID, DataTypeSize: 4}}}).Encode(nil)
+
+	tests := []struct {
+		name  string
+		typ   byte
+		body  []byte
+		valid bool
+	}{
+		{"DataRow", 'D', dataRow[5:], true},
+		{"RowDescription", 'T', rowDescription[5:], true},
+		{"ReadyForQuery", 'Z', []byte{'I'}, true},
+		{"truncated DataRow", 'D', dataRow[5 : len(dataRow)-2], false},
+		{"DataRow value length past end", 'D', []byte{0, 1, 0, 0, 0, 10, 'a'}, false},
+		{"truncated RowDescription", 'T', rowDescription[5 : len(rowDescription)-4], false},
+		{"empty ReadyForQuery", 'Z', nil, false},
+		{"short authentication", 'R', []byte{0, 0}, false},
+		{"unknown type", '!', nil, false},
+	}
+
+	for _, tt := range tests {
+		err := pgx.ValidateMessage(tt.typ, tt.body)
+		if tt.valid {
+			assert.NoErrorf(t, err, "%s", tt.name)
+			continue
+		}
+
+		var protocolErr *pgx.ProtocolError
+		if assert.Truef(t, errors.As(err, &protocolErr), "%s: %v", tt.name, err) {
+			assert.Equalf(t, tt.typ, protocolErr.MessageType, "%s", tt.name)
+		}
+	}
+}
+
+func TestScanRowValueCountMismatch(t *testing.T) {
+	t.Parallel()
+
+	fieldDescriptions := []pgproto3.FieldDescription{{Name: []byte("a"), DataTypeOID: pgtype.Int4OID}, {Name: []byte("b"), DataTypeOID: pgtype.Int4OID}}
+	var a, b int32
+	err := pgx.ScanRow(pgtype.NewConnInfo(), fieldDescriptions, [][]byte{[]byte("1")}, &a, &b)
+	var protocolErr *pgx.ProtocolError
+	require.ErrorAs(t, err, &protocolErr)
+	assert.Equal(t, byte('D'), protocolErr.MessageType)
+}
@@ -0,0 +1,72 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+// QueryStat describes a completed Query, QueryRow, Exec, or CopyFrom. It is passed to ConnConfig.OnQueryStat.
+type QueryStat struct {
+	// Operation is "Query", "Exec", or "CopyFrom". QueryRow is reported as "Query".
+	Operation string
+
+	// SQL is the SQL or prepared statement name as passed to Query or Exec. For CopyFrom it is the COPY statement.
+	SQL string
+
+	// Fingerprint is SQL with constants replaced by placeholders and whitespace and comments normalized. Statements
+	// that differ only by constant values have the same fingerprint so it is suitable as a metric label.
+	Fingerprint string
+
+	Duration time.Duration
+
+	// Rows is the number of rows read by Query or affected by Exec or CopyFrom.
+	Rows int64
+
+	Err error
+
+	// ErrorClass is the two character SQLSTATE class of Err (e.g. "23" for integrity constraint violations) if it is a
+	// *pgconn.PgError. It is "client" for any other error and empty if Err is nil.
+	ErrorClass string
+
+	// Fields are the fields returned by ConnConfig.ContextLogFields for the context of the query.
+	Fields map[string]interface{}
+
+	// PoolWait describes how the connection was acquired from a pool if this is the first query since it was. See
+	// Conn.SetPoolWait.
+	PoolWait *PoolWait
+}
+
+func (c *Conn) reportQueryStat(ctx context.Context, operation, sql string, startTime time.Time, rows int64, err error, poolWait *PoolWait) {
+	var fields map[string]interface{}
+	if c.config.ContextLogFields != nil {
+		fields = c.config.ContextLogFields(ctx)
+	}
+
+	c.config.OnQueryStat(QueryStat{
+		Operation:   operation,
+		SQL:         sql,
+		Fingerprint: Fingerprint(sql),
+		Duration:    time.Since(startTime),
+		Rows:        rows,
+		Err:         err,
+		ErrorClass:  errorClass(err),
+		Fields:      fields,
+		PoolWait:    poolWait,
+	})
+}
+
+func errorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && len(pgErr.Code) == 5 {
+		return pgErr.Code[:2]
+	}
+
+	return "client"
+}
@@ -379,6 +379,39 @@ func TestConnQueryRawValues(t *testing.T) {
 	assert.EqualValues(t, 10, rowCount)
 }
 
+func TestConnQueryScanRawBytes(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	rows, err := conn.Query(context.Background(), "select 'foo' || n, null::text, n from generate_series(1, 3) n", pgx.QueryResultFormats{pgx.TextFormatCode, pgx.TextFormatCode, pgx.BinaryFormatCode})
+	require.NoError(t, err)
+
+	var rowCount int32
+	for rows.Next() {
+		rowCount++
+		var s, null pgx.RawBytes
+		var n int32
+		require.NoError(t, rows.Scan(&s, &null, &n))
+		assert.Equal(t, "foo"+strconv.Itoa(int(rowCount)), string(s))
+		assert.Nil(t, null)
+		assert.Equal(t, rowCount, n)
+
+		var raw pgx.RawBytes
+		require.NoError(t, rows.Scan(nil, nil, &raw))
+		assert.Equal(t, []byte{0, 0, 0, byte(rowCount)}, []byte(raw))
+	}
+	require.NoError(t, rows.Err())
+	assert.EqualValues(t, 3, rowCount)
+
+	var raw pgx.RawBytes
+	err = conn.QueryRow(context.Background(), "select 'foo'").Scan(&raw)
+	require.Error(t, err)
+
+	ensureConnValid(t, conn)
+}
+
 // Test that a connection stays valid when query results are closed early
 func TestConnQueryCloseEarly(t *testing.T) {
 	t.Parallel()
@@ -1064,6 +1097,44 @@ func TestQueryRowErrors(t *testing.T) {
 	}
 }
 
+func TestQueryArgumentCountMismatch(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		tests := []struct {
+			sql  string
+			args []interface{}
+			err  string
+		}{
+			{"select $1::int4, $2::int4", []interface{}{1}, "expected 2 arguments, got 1: missing $2"},
+			{"select $1::int4", []interface{}{1, 2, 3}, "expected 1 arguments, got 3: extra arguments for $2 through $3"},
+		}
+
+		for i, tt := range tests {
+			var a, b int32
+			err := conn.QueryRow(context.Background(), tt.sql, tt.args...).Scan(&a, &b)
+			var argErr *pgx.ArgumentCountError
+			require.Truef(t, errors.As(err, &argErr), "%d. expected *pgx.ArgumentCountError, got %v", i, err)
+			require.EqualErrorf(t, err, tt.err, "%d.", i)
+
+			_, err = conn.Exec(context.Background(), tt.sql, tt.args...)
+			require.Truef(t, errors.As(err, &argErr), "%d. expected *pgx.ArgumentCountError, got %v", i, err)
+
+			ensureConnValid(t, conn)
+		}
+	})
+}
+
+func TestArgumentCountErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	err := &pgx.ArgumentCountError{Expected: 3, Actual: 1}
+	assert.EqualError(t, err, "expected 3 arguments, got 1: missing $2 through $3")
+
+	err = &pgx.ArgumentCountError{Expected: 0, Actual: 1}
+	assert.EqualError(t, err, "expected 0 arguments, got 1: extra arguments for $1")
+}
+
 func TestQueryRowNoResults(t *testing.T) {
 	t.Parallel()
 
@@ -1079,6 +1150,73 @@ func TestQueryRowNoResults(t *testing.T) {
 	ensureConnValid(t, conn)
 }
 
+func TestQueryRowStrict(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.StrictQueryRow = true
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var n int32
+	err := conn.QueryRow(context.Background(), "select 42").Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+
+	err = conn.QueryRow(context.Background(), "select 1 where 1=0").Scan(&n)
+	require.Equal(t, pgx.ErrNoRows, err)
+
+	err = conn.QueryRow(context.Background(), "select generate_series(1, 3)").Scan(&n)
+	var notSingleRowErr *pgx.NotSingleRowError
+	require.ErrorAs(t, err, &notSingleRowErr)
+	assert.Equal(t, 3, notSingleRowErr.RowCount)
+	assert.EqualError(t, err, "expected 1 row, got 3")
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryStrictUnknownTypes(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.StrictUnknownTypes = true
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+	skipCockroachDB(t, conn, "Server does not support composite types")
+
+	mustExec(t, conn, "create type pg_temp.strict_unknown as (a int4)")
+
+	var s string
+	err := conn.QueryRow(context.Background(), "select 'foo'::text").Scan(&s)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", s)
+
+	var oid uint32
+	err = conn.QueryRow(context.Background(), "select 'pg_temp.strict_unknown'::regtype::oid").Scan(&oid)
+	require.NoError(t, err)
+
+	err = conn.QueryRow(context.Background(), "select row(1)::pg_temp.strict_unknown").Scan(&s)
+	var unknownTypeErr *pgx.UnknownTypeError
+	require.ErrorAs(t, err, &unknownTypeErr)
+	assert.Equal(t, oid, unknownTypeErr.OID)
+
+	var buf []byte
+	err = conn.QueryRow(context.Background(), "select row(1)::pg_temp.strict_unknown").Scan(&buf)
+	require.ErrorAs(t, err, &unknownTypeErr)
+
+	var raw pgx.RawBytes
+	rows, err := conn.Query(context.Background(), "select row(1)::pg_temp.strict_unknown")
+	require.NoError(t, err)
+	for rows.Next() {
+		require.NoError(t, rows.Scan(&raw))
+	}
+	require.NoError(t, rows.Err())
+
+	ensureConnValid(t, conn)
+}
+
 func TestQueryRowEmptyQuery(t *testing.T) {
 	t.Parallel()
 
@@ -1901,7 +2039,215 @@ func TestConnSimpleProtocolRefusesNonUTF8ClientEncoding(t *testing.T) {
 	ensureConnValid(t, conn)
 }
 
-func TestConnSimpleProtocolRefusesNonStandardConformingStrings(t *testing.T) {
+func TestConnQueryScanConversions(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.ScanConversions = pgx.ScanConvertNumbers | pgx.ScanConvertText
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var (
+		f64 float64
+		f32 float32
+		i   int
+		u8  uint8
+		s   string
+	)
+	err := conn.QueryRow(context.Background(), "select 42::int4, 7::int2, 3::float8, 200::float4, 12.5::float8").Scan(&f64, &f32, &i, &u8, &s)
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), f64)
+	assert.Equal(t, float32(7), f32)
+	assert.Equal(t, 3, i)
+	assert.Equal(t, uint8(200), u8)
+	assert.Equal(t, "12.5", s)
+
+	for _, tt := range []struct {
+		sql string
+		dst interface{}
+	}{
+		{"select 1.5::float8", new(int)},
+		{"select 300::float8", new(uint8)},
+		{"select 9007199254740993::int8", new(float64)},
+		{"select 16777217::int4", new(float32)},
+		{"select 1.1::float8", new(float32)},
+		{"select 1.5::numeric", new(float64)},
+	} {
+		err := conn.QueryRow(context.Background(), tt.sql).Scan(tt.dst)
+		assert.Errorf(t, err, "%s into %T", tt.sql, tt.dst)
+	}
+
+	config = mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.ScanConversions = pgx.ScanConvertNumbers | pgx.ScanConvertLossy
+	lossyConn := mustConnect(t, config)
+	defer closeConn(t, lossyConn)
+
+	err = lossyConn.QueryRow(context.Background(), "select 9007199254740993::int8, 1.5::numeric").Scan(&f64, &f32)
+	require.NoError(t, err)
+	assert.Equal(t, float64(9007199254740992), f64)
+	assert.Equal(t, float32(1.5), f32)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryParamFormats(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	// int4 42 and point(1.5, -2) already encoded in the binary format.
+	int4Bin := []byte{0, 0, 0, 42}
+	pointBin := []byte{0x3f, 0xf8, 0, 0, 0, 0, 0, 0, 0xc0, 0, 0, 0, 0, 0, 0, 0}
+
+	var n int32
+	var s string
+	err := conn.QueryRow(context.Background(), "select $1::int4, $2::point::text", pgx.QueryParamFormats{0: pgx.BinaryFormatCode, 1: pgx.BinaryFormatCode}, int4Bin, pointBin).Scan(&n, &s)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+	assert.Equal(t, "(1.5,-2)", s)
+
+	err = conn.QueryRow(context.Background(), "select $1::int4 + $2", pgx.QueryParamFormats{1: pgx.TextFormatCode}, 1, 2).Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+
+	err = conn.QueryRow(context.Background(), "select $1::int4", pgx.QueryExecParams(true), pgx.QueryParamFormats{0: pgx.BinaryFormatCode}, int4Bin).Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+
+	commandTag, err := conn.Exec(context.Background(), "select $1::int4", pgx.QueryParamFormats{0: pgx.BinaryFormatCode}, int4Bin)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, commandTag.RowsAffected())
+
+	// The override only applies to the query it was passed to.
+	var b []byte
+	err = conn.QueryRow(context.Background(), "select $1::bytea", []byte{1, 2}).Scan(&b)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2}, b)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryMaxResultBytes(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.MaxResultBytes = 1000
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	rows, err := conn.Query(context.Background(), "select n, repeat('x', 100) as filler from generate_series(1, 100) n")
+	require.NoError(t, err)
+	var rowCount int
+	for rows.Next() {
+		rowCount++
+	}
+	var sizeErr *pgx.ResultSizeError
+	require.ErrorAs(t, rows.Err(), &sizeErr)
+	assert.EqualValues(t, 1000, sizeErr.Limit)
+	assert.Equal(t, 9, sizeErr.Row)
+	assert.Equal(t, 1, sizeErr.Column)
+	assert.Equal(t, "filler", sizeErr.ColumnName)
+	assert.Equal(t, 9, rowCount)
+
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from generate_series(1, 100)").Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, n)
+
+	n, err = conn.QueryCount(context.Background(), "select repeat('x', 100) from generate_series(1, 100)", pgx.QueryMaxResultBytes(0))
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, n)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryCount(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	n, err := conn.QueryCount(context.Background(), "select n, 'not decoded'::text from generate_series(1, $1::int4) n", 42)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+
+	n, err = conn.QueryCount(context.Background(), "select 1 where false")
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+
+	rows, err := conn.Query(context.Background(), "select generate_series(1, 3)")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+	assert.EqualValues(t, 3, rows.CommandTag().RowsAffected())
+
+	_, err = conn.QueryCount(context.Background(), "select 1 / (n - 2) from generate_series(1, 3) n")
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, "22012", pgErr.Code)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryExecParams(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgx.ParseConfig(os.Getenv("PGX_TEST_DATABASE") + " prefer_exec_params=true")
+	require.NoError(t, err)
+	require.True(t, config.PreferExecParams)
+	require.Empty(t, config.RuntimeParams["prefer_exec_params"])
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, "create temporary table foo(id int4, name text, data bytea)")
+
+	commandTag, err := conn.Exec(context.Background(), "insert into foo values ($1, $2, $3)", 1, `it's \ $1`, []byte{0, 1, 255})
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT 0 1", string(commandTag))
+
+	var name string
+	var data []byte
+	err = conn.QueryRow(context.Background(), "select name, data from foo where id = $1", int64(1)).Scan(&name, &data)
+	require.NoError(t, err)
+	assert.Equal(t, `it's \ $1`, name)
+	assert.Equal(t, []byte{0, 1, 255}, data)
+
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from pg_prepared_statements").Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+
+	_, err = conn.Exec(context.Background(), "select $1::int4, $2::int4", 1)
+	var argErr *pgx.ArgumentCountError
+	require.ErrorAs(t, err, &argErr)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryExecParamsOption(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	var n int32
+	var s string
+	err := conn.QueryRow(context.Background(), "select $1::int4 + 1, $2::text", pgx.QueryExecParams(true), 41, "foo").Scan(&n, &s)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+	assert.Equal(t, "foo", s)
+
+	err = conn.QueryRow(context.Background(), "select $1::int4", pgx.QueryExecParams(true), pgx.QueryResultFormats{pgx.BinaryFormatCode}, 7).Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, n)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnSimpleProtocolNonStandardConformingStrings(t *testing.T) {
 	t.Parallel()
 
 	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
@@ -1911,17 +2257,27 @@ func TestConnSimpleProtocolRefusesNonStandardConformingStrings(t *testing.T) {
 
 	mustExec(t, conn, "set standard_conforming_strings to off")
 
-	var expected string
-	err := conn.QueryRow(
-		context.Background(),
-		"select $1",
-		pgx.QuerySimpleProtocol(true),
-		`\'; drop table users; --`,
-	).Scan(&expected)
-	if err == nil {
-		t.Error("expected error when standard_conforming_strings is off, but no error occurred")
+	for _, s := range []string{`\'; drop table users; --`, `foo\`, `a\\b`, `it's`} {
+		// The backslash escaped quote must not end the literal or $1 in it would be replaced.
+		var actual, literal string
+		err := conn.QueryRow(
+			context.Background(),
+			"select $1::text, 'x\\' $1'",
+			pgx.QuerySimpleProtocol(true),
+			s,
+		).Scan(&actual, &literal)
+		require.NoError(t, err)
+		assert.Equal(t, s, actual)
+		assert.Equal(t, "x' $1", literal)
 	}
 
+	var buf []byte
+	err := conn.QueryRow(context.Background(), "select $1::bytea", pgx.QuerySimpleProtocol(true), []byte{0, 1, '\\', 255}).Scan(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, '\\', 255}, buf)
+
+	mustExec(t, conn, "set standard_conforming_strings to on")
+
 	ensureConnValid(t, conn)
 }
 
@@ -0,0 +1,238 @@
+// Package rdsiam provides AWS RDS IAM database authentication for pgx.
+//
+// With IAM database authentication the password is a short-lived token signed with AWS credentials. Configure sets
+// ConnConfig.GetPassword so a new token is generated for every connection and requires TLS verified with the RDS root
+// certificates.
+//
+//	config, err := pgx.ParseConfig("host=mydb.123456789012.us-east-1.rds.amazonaws.com user=app_user database=app")
+//	if err != nil {
+//		return err
+//	}
+//	rootCAs, err := rdsiam.LoadRootCAs("global-bundle.pem")
+//	if err != nil {
+//		return err
+//	}
+//	err = rdsiam.Configure(config, rdsiam.Options{
+//		Region:      "us-east-1",
+//		Credentials: credentialsFromSDK,
+//		RootCAs:     rootCAs,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//
+// The RDS root certificate bundle can be downloaded from https://truststore.pki.rds.amazonaws.com/global/global-bundle.pem.
+//
+// This package does not depend on the AWS SDK. Credentials are provided by a CredentialsProvider which is usually a small
+// adapter around the credentials provider of the AWS SDK in use.
+package rdsiam
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/nappspt/schemapgx/v4"
+)
+
+// Credentials are AWS credentials used to sign authentication tokens.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Only required for temporary credentials.
+}
+
+// CredentialsProvider returns the credentials used to sign an authentication token. It is called for every token so
+// it should cache credentials.
+type CredentialsProvider func(ctx context.Context) (Credentials, error)
+
+// Options configures IAM authentication.
+type Options struct {
+	// Region is the AWS region of the database such as us-east-1.
+	Region string
+
+	// Credentials provides the AWS credentials used to sign tokens.
+	Credentials CredentialsProvider
+
+	// RootCAs are the certificate authorities used to verify the server. If nil the system roots are used. See
+	// LoadRootCAs.
+	RootCAs *x509.CertPool
+}
+
+// tokenExpiresIn is how long a token can be used to connect. It is the maximum RDS allows.
+const tokenExpiresIn = 15 * time.Minute
+
+// Configure configures config to use IAM authentication as described by opts. Every connection uses a new token so
+// connections made after an earlier token expired still succeed. TLS with full verification is required for the
+// primary host and all fallbacks. Fallbacks without TLS, such as those added for sslmode=prefer, are removed.
+//
+// The token is signed for the primary host and port and pgx sends the same password to every fallback so only a single
+// host is supported. Configure returns an error without modifying config if a fallback uses a different host or port.
+func Configure(config *pgx.ConnConfig, opts Options) error {
+	for _, fb := range config.Fallbacks {
+		if fb.TLSConfig != nil && (fb.Host != config.Host || fb.Port != config.Port) {
+			return fmt.Errorf("fallback %s is not supported: the token is only valid for %s",
+				net.JoinHostPort(fb.Host, strconv.FormatUint(uint64(fb.Port), 10)),
+				net.JoinHostPort(config.Host, strconv.FormatUint(uint64(config.Port), 10)))
+		}
+	}
+
+	config.GetPassword = func(ctx context.Context, cc *pgx.ConnConfig) (string, error) {
+		endpoint := net.JoinHostPort(cc.Host, strconv.FormatUint(uint64(cc.Port), 10))
+		return BuildAuthToken(ctx, endpoint, opts.Region, cc.User, opts.Credentials)
+	}
+
+	config.TLSConfig = verifyFullTLSConfig(config.Host, opts.RootCAs)
+	fallbacks := make([]*pgconn.FallbackConfig, 0, len(config.Fallbacks))
+	for _, fb := range config.Fallbacks {
+		if fb.TLSConfig == nil {
+			continue
+		}
+		fb.TLSConfig = verifyFullTLSConfig(fb.Host, opts.RootCAs)
+		fallbacks = append(fallbacks, fb)
+	}
+	config.Fallbacks = fallbacks
+
+	return nil
+}
+
+func verifyFullTLSConfig(host string, rootCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		RootCAs:    rootCAs,
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// LoadRootCAs reads the PEM encoded certificates in path such as the RDS global bundle.
+func LoadRootCAs(path string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return rootCAs, nil
+}
+
+// BuildAuthToken returns an authentication token for user on the database at endpoint (host:port) in region. The token
+// is used as the password and can be used to connect for 15 minutes.
+func BuildAuthToken(ctx context.Context, endpoint, region, user string, credentials CredentialsProvider) (string, error) {
+	if credentials == nil {
+		return "", errors.New("no credentials provider")
+	}
+	creds, err := credentials(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+
+	return SignAuthToken(endpoint, region, user, creds, time.Now())
+}
+
+// SignAuthToken returns an authentication token signed at signTime. Most callers should use BuildAuthToken.
+func SignAuthToken(endpoint, region, user string, creds Credentials, signTime time.Time) (string, error) {
+	if endpoint == "" || region == "" || user == "" {
+		return "", errors.New("endpoint, region, and user are required")
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return "", errors.New("AWS credentials are required")
+	}
+
+	signTime = signTime.UTC()
+	date := signTime.Format("20060102")
+	dateTime := signTime.Format("20060102T150405Z")
+	scope := date + "/" + region + "/rds-db/aws4_request"
+
+	params := map[string]string{
+		"Action":              "connect",
+		"DBUser":              user,
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    creds.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          dateTime,
+		"X-Amz-Expires":       strconv.Itoa(int(tokenExpiresIn / time.Second)),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if creds.SessionToken != "" {
+		params["X-Amz-Security-Token"] = creds.SessionToken
+	}
+	query := canonicalQuery(params)
+
+	emptyPayloadHash := sha256.Sum256(nil)
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		"/",
+		query,
+		"host:" + endpoint + "\n",
+		"host",
+		hex.EncodeToString(emptyPayloadHash[:]),
+	}, "\n")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		dateTime,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "rds-db")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return endpoint + "/?" + query + "&X-Amz-Signature=" + signature, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery returns params sorted by name and encoded as required by AWS signature version 4.
+func canonicalQuery(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = uriEncode(name) + "=" + uriEncode(params[name])
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes every byte of s except the unreserved characters of RFC 3986.
+func uriEncode(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&15])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,95 @@
+package pgx
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MessageReadTimeoutError occurs when no data is received from the server within ConnConfig.MessageReadTimeout. The
+// connection is closed as it is no longer in a known state.
+type MessageReadTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *MessageReadTimeoutError) Error() string {
+	return "no message received from server within " + e.Timeout.String()
+}
+
+// messageReadTimeoutConn is a net.Conn that fails any read that does not receive data within timeout. Deadlines set by
+// pgconn for context cancellation still apply. The error returned on timeout is deliberately not a net.Error timeout so
+// pgconn treats it as fatal and closes the connection.
+type messageReadTimeoutConn struct {
+	net.Conn
+	timeout  time.Duration
+	disabled int32
+
+	mux          sync.Mutex
+	readDeadline time.Time // deadline set through SetDeadline or SetReadDeadline
+}
+
+func newMessageReadTimeoutConn(conn net.Conn, timeout time.Duration) *messageReadTimeoutConn {
+	return &messageReadTimeoutConn{Conn: conn, timeout: timeout}
+}
+
+// disable suspends the timeout until enable is called. This is used while intentionally waiting for a message that may
+// never arrive such as a notification.
+func (c *messageReadTimeoutConn) disable() {
+	atomic.StoreInt32(&c.disabled, 1)
+}
+
+func (c *messageReadTimeoutConn) enable() {
+	atomic.StoreInt32(&c.disabled, 0)
+}
+
+func (c *messageReadTimeoutConn) Read(b []byte) (int, error) {
+	c.mux.Lock()
+	explicit := c.readDeadline
+	deadline := explicit
+	armed := atomic.LoadInt32(&c.disabled) == 0
+	if armed {
+		timeoutDeadline := time.Now().Add(c.timeout)
+		if explicit.IsZero() || timeoutDeadline.Before(explicit) {
+			deadline = timeoutDeadline
+		} else {
+			armed = false
+		}
+	}
+	err := c.Conn.SetReadDeadline(deadline)
+	c.mux.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := c.Conn.Read(b)
+	if err != nil && armed {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			c.mux.Lock()
+			explicit = c.readDeadline
+			c.mux.Unlock()
+
+			if explicit.IsZero() || time.Now().Before(explicit) {
+				err = &MessageReadTimeoutError{Timeout: c.timeout}
+			}
+		}
+	}
+
+	return n, err
+}
+
+func (c *messageReadTimeoutConn) SetDeadline(t time.Time) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.readDeadline = t
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *messageReadTimeoutConn) SetReadDeadline(t time.Time) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.readDeadline = t
+	return c.Conn.SetReadDeadline(t)
+}
@@ -61,7 +61,8 @@ type Rows interface {
 type Row interface {
 	// Scan works the same as Rows. with the following exceptions. If no
 	// rows were found it returns ErrNoRows. If multiple rows are returned it
-	// ignores all but the first.
+	// ignores all but the first unless ConnConfig.StrictQueryRow is set, in
+	// which case it returns a *NotSingleRowError.
 	Scan(dest ...interface{}) error
 }
 
@@ -75,6 +76,13 @@ func (r *connRow) Scan(dest ...interface{}) (err error) {
 		return rows.Err()
 	}
 
+	for _, dst := range dest {
+		if _, ok := dst.(*RawBytes); ok {
+			rows.Close()
+			return errRawBytesQueryRow
+		}
+	}
+
 	if !rows.Next() {
 		if rows.Err() == nil {
 			return ErrNoRows
@@ -83,10 +91,60 @@ func (r *connRow) Scan(dest ...interface{}) (err error) {
 	}
 
 	rows.Scan(dest...)
+
+	if rows.conn != nil && rows.conn.config.StrictQueryRow && rows.Err() == nil {
+		rowCount := 1
+		for rows.Next() {
+			rowCount++
+		}
+		if rows.Err() == nil && rowCount > 1 {
+			return &NotSingleRowError{RowCount: rowCount}
+		}
+	}
+
 	rows.Close()
 	return rows.Err()
 }
 
+// RawBytes is a scan destination that receives the raw bytes of a value without copying. The bytes are in the format
+// of the result column, text or binary, and are nil for NULL. They alias the connection's read buffer so they are only
+// valid until the next call to Next or Close. It is intended for consumers that immediately hash or forward values and
+// want to avoid an allocation per value. RawBytes cannot be used with QueryRow as the row is closed before Scan returns.
+type RawBytes []byte
+
+var errRawBytesQueryRow = errors.New("RawBytes cannot be used with QueryRow")
+
+// NotSingleRowError occurs when QueryRow returns more than one row and ConnConfig.StrictQueryRow is set.
+type NotSingleRowError struct {
+	RowCount int
+}
+
+func (e *NotSingleRowError) Error() string {
+	return fmt.Sprintf("expected 1 row, got %d", e.RowCount)
+}
+
+// ResultSizeError occurs when the values returned by a query exceed ConnConfig.MaxResultBytes or QueryMaxResultBytes.
+type ResultSizeError struct {
+	Limit      int64
+	Row        int // 0-based index of the row that exceeded the limit
+	Column     int // 0-based index of the column that exceeded the limit
+	ColumnName string
+}
+
+func (e *ResultSizeError) Error() string {
+	return fmt.Sprintf("query result exceeds %d bytes at row %d column %d (%s)", e.Limit, e.Row, e.Column, e.ColumnName)
+}
+
+// UnknownTypeError occurs when a column of a type that is not registered in the ConnInfo is scanned into a string or
+// []byte and ConnConfig.StrictUnknownTypes is set.
+type UnknownTypeError struct {
+	OID uint32
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("unknown type OID %d", e.OID)
+}
+
 type rowLog interface {
 	shouldLog(lvl LogLevel) bool
 	log(ctx context.Context, lvl LogLevel, msg string, data map[string]interface{})
@@ -106,6 +164,18 @@ type connRows struct {
 	args       []interface{}
 	closed     bool
 	conn       *Conn
+	poolWait   *PoolWait
+
+	maxResultBytes int64 // 0 if the size of the result is not limited
+	resultBytes    int64
+
+	preparedName string // name the statement was prepared as with Conn.Prepare
+
+	// The original arguments to Query. They are used to execute the query again if its prepared statement was
+	// invalidated by a schema change.
+	retrySQL  string
+	retryArgs []interface{}
+	retryable bool
 
 	resultReader      *pgconn.ResultReader
 	multiResultReader *pgconn.MultiResultReader
@@ -139,17 +209,23 @@ func (rows *connRows) Close() {
 		}
 	}
 
+	if rows.conn != nil && rows.conn.config.OnQueryStat != nil {
+		rows.conn.reportQueryStat(rows.ctx, "Query", rows.sql, rows.startTime, int64(rows.rowCount), rows.err, rows.poolWait)
+	}
+
 	if rows.logger != nil {
 		if rows.err == nil {
 			if rows.logger.shouldLog(LogLevelInfo) {
 				endTime := time.Now()
-				rows.logger.log(rows.ctx, LogLevelInfo, "Query", map[string]interface{}{"sql": rows.sql, "args": logQueryArgs(rows.args), "time": endTime.Sub(rows.startTime), "rowCount": rows.rowCount})
+				rows.logger.log(rows.ctx, LogLevelInfo, "Query", addPoolWaitLogFields(map[string]interface{}{"sql": rows.sql, "args": logQueryArgs(rows.args), "time": endTime.Sub(rows.startTime), "rowCount": rows.rowCount}, rows.poolWait))
 			}
 		} else {
 			if rows.logger.shouldLog(LogLevelError) {
-				rows.logger.log(rows.ctx, LogLevelError, "Query", map[string]interface{}{"err": rows.err, "sql": rows.sql, "args": logQueryArgs(rows.args)})
+				rows.logger.log(rows.ctx, LogLevelError, "Query", addPoolWaitLogFields(map[string]interface{}{"err": rows.err, "sql": rows.sql, "args": logQueryArgs(rows.args)}, rows.poolWait))
 			}
-			if rows.err != nil && rows.conn.stmtcache != nil {
+			if rows.err != nil && rows.preparedName != "" {
+				rows.conn.preparedStatementErrored(rows.preparedName, rows.err)
+			} else if rows.err != nil && rows.conn.stmtcache != nil {
 				rows.conn.stmtcache.StatementErrored(rows.sql, rows.err)
 			}
 		}
@@ -183,20 +259,76 @@ func (rows *connRows) Next() bool {
 	if rows.resultReader.NextRow() {
 		rows.rowCount++
 		rows.values = rows.resultReader.Values()
+		if rows.maxResultBytes > 0 {
+			if err := rows.checkResultBytes(); err != nil {
+				rows.fatal(err)
+				return false
+			}
+		}
 		return true
+	} else if rows.rowCount == 0 && rows.retryInvalidCachedPlan() {
+		return rows.Next()
 	} else {
 		rows.Close()
 		return false
 	}
 }
 
+// checkResultBytes adds the size of the current row to the size of the result and returns a *ResultSizeError if it
+// exceeds the limit.
+func (rows *connRows) checkResultBytes() error {
+	for i, v := range rows.values {
+		rows.resultBytes += int64(len(v))
+		if rows.resultBytes > rows.maxResultBytes {
+			err := &ResultSizeError{Limit: rows.maxResultBytes, Row: rows.rowCount - 1, Column: i}
+			if fds := rows.FieldDescriptions(); i < len(fds) {
+				err.ColumnName = string(fds[i].Name)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// retryInvalidCachedPlan executes the query again when it failed before returning any rows because its prepared
+// statement was invalidated by a schema change. It reports whether the query was executed again.
+func (rows *connRows) retryInvalidCachedPlan() bool {
+	if !rows.retryable {
+		return false
+	}
+	rows.retryable = false
+
+	_, err := rows.resultReader.Close()
+	if !rows.conn.canRetryInvalidCachedPlan(err) {
+		return false
+	}
+
+	if rows.preparedName != "" {
+		rows.conn.preparedStatementErrored(rows.preparedName, err)
+	} else if rows.conn.stmtcache != nil {
+		rows.conn.stmtcache.StatementErrored(rows.sql, err)
+	}
+
+	retry, _ := rows.conn.Query(rows.ctx, rows.retrySQL, rows.retryArgs...)
+	retryRows := retry.(*connRows)
+	if retryRows.err != nil {
+		rows.err = retryRows.err
+		return false
+	}
+
+	rows.resultReader = retryRows.resultReader
+	rows.multiResultReader = retryRows.multiResultReader
+	rows.sql = retryRows.sql
+	rows.scanPlans = nil
+	return true
+}
+
 func (rows *connRows) Scan(dest ...interface{}) error {
 	ci := rows.connInfo
 	fieldDescriptions := rows.FieldDescriptions()
 	values := rows.values
 
-	if len(fieldDescriptions) != len(values) {
-		err := fmt.Errorf("number of field descriptions must equal number of values, got %d and %d", len(fieldDescriptions), len(values))
+	if err := checkRowValueCount(fieldDescriptions, values); err != nil {
 		rows.fatal(err)
 		return err
 	}
@@ -207,18 +339,48 @@ func (rows *connRows) Scan(dest ...interface{}) error {
 	}
 
 	if rows.scanPlans == nil {
-		rows.scanPlans = make([]pgtype.ScanPlan, len(values))
+		rows.scanPlans = rows.conn.scanPlanBuffer(len(values))
 		for i := range dest {
 			rows.scanPlans[i] = ci.PlanScan(fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, dest[i])
 		}
 	}
 
+	var scanConversions ScanConversionPolicy
+	if rows.conn != nil {
+		scanConversions = rows.conn.config.ScanConversions
+	}
+
 	for i, dst := range dest {
 		if dst == nil {
 			continue
 		}
+		if scanFast(fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], dst) {
+			continue
+		}
+		if rb, ok := dst.(*RawBytes); ok {
+			*rb = values[i]
+			continue
+		}
+
+		if rows.conn != nil && rows.conn.config.StrictUnknownTypes {
+			if err := checkKnownType(ci, fieldDescriptions[i].DataTypeOID, dst); err != nil {
+				err = ScanArgError{ColumnIndex: i, Err: err}
+				rows.fatal(err)
+				return err
+			}
+		}
 
-		err := rows.scanPlans[i].Scan(ci, fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], dst)
+		var err error
+		if converted, convertErr := scanConversions.scanConverted(ci, fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], dst); converted {
+			err = convertErr
+		} else if target, assign := namedTypeScanTarget(dst); target != nil {
+			err = ci.Scan(fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], target)
+			if err == nil {
+				assign()
+			}
+		} else {
+			err = rows.scanPlans[i].Scan(ci, fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], dst)
+		}
 		if err != nil {
 			err = ScanArgError{ColumnIndex: i, Err: err}
 			rows.fatal(err)
@@ -229,11 +391,43 @@ func (rows *connRows) Scan(dest ...interface{}) error {
 	return nil
 }
 
+// checkKnownType returns an *UnknownTypeError if oid is not registered in ci and dst would receive the value without
+// decoding it.
+func checkKnownType(ci *pgtype.ConnInfo, oid uint32, dst interface{}) error {
+	switch dst.(type) {
+	case *string, *[]byte:
+	default:
+		return nil
+	}
+
+	if _, ok := ci.DataTypeForOID(oid); ok {
+		return nil
+	}
+	return &UnknownTypeError{OID: oid}
+}
+
+// scanPlanBuffer returns a slice of n scan plans. The slice is reused by the next query on the connection.
+func (c *Conn) scanPlanBuffer(n int) []pgtype.ScanPlan {
+	if c == nil {
+		return make([]pgtype.ScanPlan, n)
+	}
+
+	if cap(c.scanPlans) < n {
+		c.scanPlans = make([]pgtype.ScanPlan, n)
+	}
+	return c.scanPlans[:n]
+}
+
 func (rows *connRows) Values() ([]interface{}, error) {
 	if rows.closed {
 		return nil, errors.New("rows is closed")
 	}
 
+	if err := checkRowValueCount(rows.FieldDescriptions(), rows.values); err != nil {
+		rows.fatal(err)
+		return nil, err
+	}
+
 	values := make([]interface{}, 0, len(rows.FieldDescriptions()))
 
 	for i := range rows.FieldDescriptions() {
@@ -318,6 +512,17 @@ func (e ScanArgError) Unwrap() error {
 	return e.Err
 }
 
+// checkRowValueCount returns a *ProtocolError if a DataRow did not have a value for each field of the RowDescription.
+func checkRowValueCount(fieldDescriptions []pgproto3.FieldDescription, values [][]byte) error {
+	if len(fieldDescriptions) != len(values) {
+		return &ProtocolError{
+			MessageType: 'D',
+			Err:         fmt.Errorf("number of field descriptions must equal number of values, got %d and %d", len(fieldDescriptions), len(values)),
+		}
+	}
+	return nil
+}
+
 // ScanRow decodes raw row data into dest. It can be used to scan rows read from the lower level pgconn interface.
 //
 // connInfo - OID to Go type mapping.
@@ -325,8 +530,8 @@ func (e ScanArgError) Unwrap() error {
 // values - the raw data as returned from the PostgreSQL server
 // dest - the destination that values will be decoded into
 func ScanRow(connInfo *pgtype.ConnInfo, fieldDescriptions []pgproto3.FieldDescription, values [][]byte, dest ...interface{}) error {
-	if len(fieldDescriptions) != len(values) {
-		return fmt.Errorf("number of field descriptions must equal number of values, got %d and %d", len(fieldDescriptions), len(values))
+	if err := checkRowValueCount(fieldDescriptions, values); err != nil {
+		return err
 	}
 	if len(fieldDescriptions) != len(dest) {
 		return fmt.Errorf("number of field descriptions must equal number of destinations, got %d and %d", len(fieldDescriptions), len(dest))
@@ -337,7 +542,15 @@ func ScanRow(connInfo *pgtype.ConnInfo, fieldDescriptions []pgproto3.FieldDescri
 			continue
 		}
 
+		var assign func()
+		if target, a := namedTypeScanTarget(d); target != nil {
+			d, assign = target, a
+		}
+
 		err := connInfo.Scan(fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], d)
+		if err == nil && assign != nil {
+			assign()
+		}
 		if err != nil {
 			return ScanArgError{ColumnIndex: i, Err: err}
 		}
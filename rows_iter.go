@@ -0,0 +1,81 @@
+//go:build go1.23
+
+package pgx
+
+import "iter"
+
+// RowsSeq returns an iterator over rows for use with range. Each iteration yields rows positioned on the next row so
+// Scan or Values may be called. rows is closed when the iteration ends including when the loop exits early. As with a
+// Next loop, rows.Err() must be checked after the loop.
+//
+//	rows, _ := conn.Query(ctx, "select id, name from users")
+//	for row := range pgx.RowsSeq(rows) {
+//		err := row.Scan(&id, &name)
+//		...
+//	}
+//	if rows.Err() != nil {
+//		...
+//	}
+func RowsSeq(rows Rows) iter.Seq[Rows] {
+	return func(yield func(Rows) bool) {
+		defer rows.Close()
+
+		for rows.Next() {
+			if !yield(rows) {
+				return
+			}
+		}
+	}
+}
+
+// ScanSeq returns an iterator that yields the value scanned by scanFn from each row of rows. If scanFn fails or rows
+// has an error, the error is yielded with the zero value of T and the iteration ends. rows is closed when the iteration
+// ends including when the loop exits early.
+//
+//	rows, _ := conn.Query(ctx, "select id, name from users")
+//	users := pgx.ScanSeq(rows, func(row pgx.Rows) (User, error) {
+//		var u User
+//		err := row.Scan(&u.ID, &u.Name)
+//		return u, err
+//	})
+//	for user, err := range users {
+//		...
+//	}
+func ScanSeq[T any](rows Rows, scanFn func(row Rows) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+
+		for rows.Next() {
+			value, err := scanFn(rows)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if !yield(value, nil) {
+				return
+			}
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// ValueSeq returns an iterator that yields the single column of each row of rows scanned into a T. It is a shorthand
+// for ScanSeq with a scan function that scans one value.
+//
+//	rows, _ := conn.Query(ctx, "select name from users")
+//	for name, err := range pgx.ValueSeq[string](rows) {
+//		...
+//	}
+func ValueSeq[T any](rows Rows) iter.Seq2[T, error] {
+	return ScanSeq(rows, func(row Rows) (T, error) {
+		var value T
+		err := row.Scan(&value)
+		return value, err
+	})
+}
@@ -0,0 +1,97 @@
+//go:build go1.23
+
+package pgx_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowsSeq(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	rows, err := conn.Query(context.Background(), "select generate_series(1, 5)")
+	require.NoError(t, err)
+
+	var sum int32
+	for row := range pgx.RowsSeq(rows) {
+		var n int32
+		require.NoError(t, row.Scan(&n))
+		sum += n
+		if n == 3 {
+			break
+		}
+	}
+	require.NoError(t, rows.Err())
+	assert.EqualValues(t, 6, sum)
+
+	ensureConnValid(t, conn)
+}
+
+func TestScanSeq(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	type pair struct {
+		n    int32
+		name string
+	}
+
+	rows, err := conn.Query(context.Background(), "select n, 'n' || n from generate_series(1, 3) n")
+	require.NoError(t, err)
+
+	var pairs []pair
+	for p, err := range pgx.ScanSeq(rows, func(row pgx.Rows) (pair, error) {
+		var p pair
+		err := row.Scan(&p.n, &p.name)
+		return p, err
+	}) {
+		require.NoError(t, err)
+		pairs = append(pairs, p)
+	}
+	assert.Equal(t, []pair{{1, "n1"}, {2, "n2"}, {3, "n3"}}, pairs)
+
+	var names []string
+	rows, err = conn.Query(context.Background(), "select 'n' || n from generate_series(1, 3) n")
+	require.NoError(t, err)
+	for name, err := range pgx.ValueSeq[string](rows) {
+		require.NoError(t, err)
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"n1", "n2", "n3"}, names)
+
+	rows, err = conn.Query(context.Background(), "select 1 / (n - 2) from generate_series(1, 3) n")
+	require.NoError(t, err)
+	var errs []error
+	for _, err := range pgx.ValueSeq[int32](rows) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 2)
+	assert.NoError(t, errs[0])
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, errs[1], &pgErr)
+	assert.Equal(t, "22012", pgErr.Code)
+
+	rows, err = conn.Query(context.Background(), "select 1")
+	require.NoError(t, err)
+	for p, err := range pgx.ScanSeq(rows, func(row pgx.Rows) (pair, error) {
+		return pair{n: 1}, errors.New("scan failed")
+	}) {
+		require.EqualError(t, err, "scan failed")
+		assert.Equal(t, pair{}, p)
+	}
+
+	ensureConnValid(t, conn)
+}
@@ -3,6 +3,7 @@ package sanitize
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -16,6 +17,15 @@ type Part interface{}
 
 type Query struct {
 	Parts []Part
+
+	backslashEscapes bool // standard_conforming_strings is off
+}
+
+// ServerSettings are the server settings that affect how SQL text is lexed and how arguments must be quoted.
+type ServerSettings struct {
+	// StandardConformingStrings is true when standard_conforming_strings is on. When it is off backslashes are escape
+	// characters in ordinary string literals as well as in E'' strings.
+	StandardConformingStrings bool
 }
 
 func (q *Query) Sanitize(args ...interface{}) (string, error) {
@@ -43,9 +53,22 @@ func (q *Query) Sanitize(args ...interface{}) (string, error) {
 			case bool:
 				str = strconv.FormatBool(arg)
 			case []byte:
-				str = QuoteBytes(arg)
+				if q.backslashEscapes {
+					str = quoteBytesEscape(arg)
+				} else {
+					str = QuoteBytes(arg)
+				}
 			case string:
-				str = QuoteString(arg)
+				// The E'' form is only needed when backslashes are escape characters in ordinary literals.
+				var err error
+				if q.backslashEscapes {
+					str, err = EscapeLiteral(arg)
+				} else if err = checkText(arg); err == nil {
+					str = QuoteString(arg)
+				}
+				if err != nil {
+					return "", fmt.Errorf("invalid arg %d: %w", part, err)
+				}
 			case time.Time:
 				str = arg.Truncate(time.Microsecond).Format("'2006-01-02 15:04:05.999999999Z07:00:00'")
 			default:
@@ -67,16 +90,24 @@ func (q *Query) Sanitize(args ...interface{}) (string, error) {
 }
 
 func NewQuery(sql string) (*Query, error) {
+	return NewQueryForServer(sql, ServerSettings{StandardConformingStrings: true})
+}
+
+// NewQueryForServer is the same as NewQuery except that sql is lexed and arguments are quoted for a server with
+// settings. Arguments are always quoted by doubling single quotes rather than with \' so the result is valid for any
+// backslash_quote setting.
+func NewQueryForServer(sql string, settings ServerSettings) (*Query, error) {
 	l := &sqlLexer{
-		src:     sql,
-		stateFn: rawState,
+		src:              sql,
+		stateFn:          rawState,
+		backslashEscapes: !settings.StandardConformingStrings,
 	}
 
 	for l.stateFn != nil {
 		l.stateFn = l.stateFn(l)
 	}
 
-	query := &Query{Parts: l.parts}
+	query := &Query{Parts: l.parts, backslashEscapes: l.backslashEscapes}
 
 	return query, nil
 }
@@ -89,13 +120,57 @@ func QuoteBytes(buf []byte) string {
 	return `'\x` + hex.EncodeToString(buf) + "'"
 }
 
+// EscapeLiteral returns str quoted as a SQL string literal. If str contains a backslash it is quoted as an E'' string
+// with the backslashes doubled so it is interpreted the same regardless of standard_conforming_strings. Single quotes
+// are always doubled so the result is valid for any backslash_quote setting. An error is returned if str contains a
+// null byte, which PostgreSQL does not allow in text, or is not valid UTF-8.
+func EscapeLiteral(str string) (string, error) {
+	if err := checkText(str); err != nil {
+		return "", err
+	}
+	if !strings.Contains(str, `\`) {
+		return QuoteString(str), nil
+	}
+	return "E'" + strings.ReplaceAll(strings.ReplaceAll(str, `\`, `\\`), "'", "''") + "'", nil
+}
+
+// EscapeIdentifier returns str quoted as a SQL identifier such as a table or column name. The result is case-sensitive
+// and is never interpreted as a keyword. An error is returned if str is empty, contains a null byte, or is not valid
+// UTF-8. Schema-qualified names must be escaped a part at a time.
+func EscapeIdentifier(str string) (string, error) {
+	if str == "" {
+		return "", errors.New("identifier is empty")
+	}
+	if err := checkText(str); err != nil {
+		return "", err
+	}
+	return `"` + strings.ReplaceAll(str, `"`, `""`) + `"`, nil
+}
+
+// checkText returns an error if str cannot be sent to PostgreSQL as text.
+func checkText(str string) error {
+	if strings.IndexByte(str, 0) >= 0 {
+		return errors.New("contains null byte")
+	}
+	if !utf8.ValidString(str) {
+		return errors.New("invalid UTF-8")
+	}
+	return nil
+}
+
+// quoteBytesEscape quotes buf for a server where backslashes in string literals are escape characters.
+func quoteBytesEscape(buf []byte) string {
+	return `E'\\x` + hex.EncodeToString(buf) + "'"
+}
+
 type sqlLexer struct {
-	src     string
-	start   int
-	pos     int
-	nested  int // multiline comment nesting level.
-	stateFn stateFn
-	parts   []Part
+	src              string
+	start            int
+	pos              int
+	nested           int  // multiline comment nesting level.
+	backslashEscapes bool // backslashes are escape characters in ordinary string literals.
+	stateFn          stateFn
+	parts            []Part
 }
 
 type stateFn func(*sqlLexer) stateFn
@@ -148,6 +223,10 @@ func rawState(l *sqlLexer) stateFn {
 }
 
 func singleQuoteState(l *sqlLexer) stateFn {
+	if l.backslashEscapes {
+		return escapeStringState
+	}
+
 	for {
 		r, width := utf8.DecodeRuneInString(l.src[l.pos:])
 		l.pos += width
@@ -294,7 +373,7 @@ func multilineCommentState(l *sqlLexer) stateFn {
 
 // SanitizeSQL replaces placeholder values with args. It quotes and escapes args
 // as necessary. This function is only safe when standard_conforming_strings is
-// on.
+// on. Use SanitizeSQLForServer otherwise.
 func SanitizeSQL(sql string, args ...interface{}) (string, error) {
 	query, err := NewQuery(sql)
 	if err != nil {
@@ -302,3 +381,13 @@ func SanitizeSQL(sql string, args ...interface{}) (string, error) {
 	}
 	return query.Sanitize(args...)
 }
+
+// SanitizeSQLForServer is the same as SanitizeSQL but is safe for a server with
+// settings.
+func SanitizeSQLForServer(settings ServerSettings, sql string, args ...interface{}) (string, error) {
+	query, err := NewQueryForServer(sql, settings)
+	if err != nil {
+		return "", err
+	}
+	return query.Sanitize(args...)
+}
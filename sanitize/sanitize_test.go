@@ -2,6 +2,7 @@ package sanitize_test
 
 import (
 	"github.com/nappspt/schemapgx/v4/sanitize"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -202,3 +203,101 @@ func TestQuerySanitize(t *testing.T) {
 		}
 	}
 }
+
+func TestNewQueryForServerNonStandardConformingStrings(t *testing.T) {
+	settings := sanitize.ServerSettings{StandardConformingStrings: false}
+
+	query, err := sanitize.NewQueryForServer(`select 'foo\' $1', $1`, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedParts := []sanitize.Part{`select 'foo\' $1', `, 1}
+	if !reflect.DeepEqual(query.Parts, expectedParts) {
+		t.Errorf("expected parts %v, got %v", expectedParts, query.Parts)
+	}
+
+	successTests := []struct {
+		arg      interface{}
+		expected string
+	}{
+		{arg: "foo", expected: `select 'foo\' $1', 'foo'`},
+		{arg: "it's", expected: `select 'foo\' $1', 'it''s'`},
+		{arg: `\'; drop table users; --`, expected: `select 'foo\' $1', E'\\''; drop table users; --'`},
+		{arg: []byte{0, 255}, expected: `select 'foo\' $1', E'\\x00ff'`},
+	}
+
+	for i, tt := range successTests {
+		actual, err := sanitize.SanitizeSQLForServer(settings, `select 'foo\' $1', $1`, tt.arg)
+		if err != nil {
+			t.Errorf("%d. %v", i, err)
+			continue
+		}
+		if tt.expected != actual {
+			t.Errorf("%d. expected %s, but got %s", i, tt.expected, actual)
+		}
+	}
+}
+
+func TestEscapeLiteral(t *testing.T) {
+	successTests := []struct {
+		str      string
+		expected string
+	}{
+		{str: "", expected: `''`},
+		{str: "foo", expected: `'foo'`},
+		{str: "it's", expected: `'it''s'`},
+		{str: `C:\tmp`, expected: `E'C:\\tmp'`},
+		{str: `\'`, expected: `E'\\'''`},
+		{str: "日本語", expected: `'日本語'`},
+	}
+
+	for i, tt := range successTests {
+		actual, err := sanitize.EscapeLiteral(tt.str)
+		if err != nil {
+			t.Errorf("%d. %v", i, err)
+			continue
+		}
+		if tt.expected != actual {
+			t.Errorf("%d. expected %s, but got %s", i, tt.expected, actual)
+		}
+	}
+
+	for i, str := range []string{"foo\x00bar", "\xff\xfe"} {
+		if _, err := sanitize.EscapeLiteral(str); err == nil {
+			t.Errorf("%d. expected error for %q", i, str)
+		}
+	}
+
+	if _, err := sanitize.SanitizeSQL("select $1", "foo\x00"); err == nil {
+		t.Error("expected SanitizeSQL to reject a null byte")
+	}
+}
+
+func TestEscapeIdentifier(t *testing.T) {
+	successTests := []struct {
+		str      string
+		expected string
+	}{
+		{str: "foo", expected: `"foo"`},
+		{str: "Foo Bar", expected: `"Foo Bar"`},
+		{str: `a"b`, expected: `"a""b"`},
+		{str: "select", expected: `"select"`},
+	}
+
+	for i, tt := range successTests {
+		actual, err := sanitize.EscapeIdentifier(tt.str)
+		if err != nil {
+			t.Errorf("%d. %v", i, err)
+			continue
+		}
+		if tt.expected != actual {
+			t.Errorf("%d. expected %s, but got %s", i, tt.expected, actual)
+		}
+	}
+
+	for i, str := range []string{"", "foo\x00", "\xff"} {
+		if _, err := sanitize.EscapeIdentifier(str); err == nil {
+			t.Errorf("%d. expected error for %q", i, str)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package pgx
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/jackc/pgtype"
+)
+
+// ScanConversionPolicy is a set of conversions Scan performs in addition to those of pgtype when the type of a column
+// does not match the type of the destination. The zero value performs only the conversions of pgtype.
+type ScanConversionPolicy uint8
+
+const (
+	// ScanConvertNumbers scans int2, int4 and int8 columns into float32 and float64 destinations, and float4 and float8
+	// columns holding whole numbers into integer destinations. A value that cannot be represented exactly by the
+	// destination is an error unless ScanConvertLossy is also set.
+	ScanConvertNumbers ScanConversionPolicy = 1 << iota
+
+	// ScanConvertLossy allows conversions into float32 and float64 destinations that may lose precision: integers
+	// beyond the precision of the float, float8 into float32 and numeric into either. When a policy is set without
+	// ScanConvertLossy such conversions are an error, including numeric into a float which pgtype otherwise allows.
+	ScanConvertLossy
+
+	// ScanConvertText scans a column of any registered type into a *string using the text representation of the
+	// value, as the text format already does.
+	ScanConvertText
+)
+
+// scanConverted scans src into dst if policy applies to the column type oid and dst. It returns false if it did not
+// handle dst so it should be scanned normally.
+func (policy ScanConversionPolicy) scanConverted(ci *pgtype.ConnInfo, oid uint32, format int16, src []byte, dst interface{}) (bool, error) {
+	if policy == 0 {
+		return false, nil
+	}
+
+	switch dst.(type) {
+	case *float32, *float64:
+		switch oid {
+		case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID:
+			if policy&ScanConvertNumbers == 0 {
+				return false, nil
+			}
+			var n *int64
+			if err := ci.Scan(oid, format, src, &n); err != nil {
+				return true, err
+			}
+			if n == nil {
+				return true, fmt.Errorf("cannot scan NULL into %T", dst)
+			}
+			return true, assignFloatFromInt(policy, *n, dst)
+		case pgtype.Float8OID:
+			if _, ok := dst.(*float32); !ok || policy&ScanConvertLossy != 0 {
+				return false, nil
+			}
+			var f *float64
+			if err := ci.Scan(oid, format, src, &f); err != nil {
+				return true, err
+			}
+			if f == nil {
+				return true, fmt.Errorf("cannot scan NULL into %T", dst)
+			}
+			if f32 := float32(*f); float64(f32) == *f || math.IsNaN(*f) {
+				*dst.(*float32) = f32
+				return true, nil
+			}
+			return true, fmt.Errorf("cannot scan float8 %v into %T without losing precision", *f, dst)
+		case pgtype.NumericOID:
+			if policy&ScanConvertLossy != 0 {
+				return false, nil
+			}
+			return true, fmt.Errorf("cannot scan numeric into %T without ScanConvertLossy", dst)
+		}
+	case *string:
+		if policy&ScanConvertText == 0 || format != BinaryFormatCode {
+			return false, nil
+		}
+		switch oid {
+		case pgtype.TextOID, pgtype.VarcharOID, pgtype.BPCharOID, pgtype.NameOID, pgtype.UnknownOID:
+			return false, nil
+		}
+		dt, ok := ci.DataTypeForOID(oid)
+		if !ok {
+			return false, nil
+		}
+		value := pgtype.NewValue(dt.Value)
+		decoder, ok := value.(pgtype.BinaryDecoder)
+		if !ok {
+			return false, nil
+		}
+		encoder, ok := value.(pgtype.TextEncoder)
+		if !ok {
+			return false, nil
+		}
+		if src == nil {
+			return true, fmt.Errorf("cannot scan NULL into %T", dst)
+		}
+		if err := decoder.DecodeBinary(ci, src); err != nil {
+			return true, err
+		}
+		buf, err := encoder.EncodeText(ci, nil)
+		if err != nil {
+			return true, err
+		}
+		*dst.(*string) = string(buf)
+		return true, nil
+	default:
+		if policy&ScanConvertNumbers == 0 || (oid != pgtype.Float4OID && oid != pgtype.Float8OID) {
+			return false, nil
+		}
+		dstVal := reflect.ValueOf(dst)
+		if dstVal.Kind() != reflect.Ptr || dstVal.IsNil() {
+			return false, nil
+		}
+		elem := dstVal.Elem()
+		switch elem.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return false, nil
+		}
+		var f *float64
+		if err := ci.Scan(oid, format, src, &f); err != nil {
+			return true, err
+		}
+		if f == nil {
+			return true, fmt.Errorf("cannot scan NULL into %T", dst)
+		}
+		return true, assignIntFromFloat(*f, elem, dst)
+	}
+
+	return false, nil
+}
+
+// assignFloatFromInt stores n in dst, a *float32 or *float64. It is an error if n cannot be represented exactly unless
+// policy includes ScanConvertLossy.
+func assignFloatFromInt(policy ScanConversionPolicy, n int64, dst interface{}) error {
+	f := float64(n)
+	if _, ok := dst.(*float32); ok {
+		f = float64(float32(n))
+	}
+
+	// float64(math.MaxInt64) rounds up to 2^63 which does not fit in an int64.
+	if policy&ScanConvertLossy == 0 && (f >= math.MaxInt64 || int64(f) != n) {
+		return fmt.Errorf("cannot scan %d into %T without losing precision", n, dst)
+	}
+
+	switch dst := dst.(type) {
+	case *float32:
+		*dst = float32(f)
+	case *float64:
+		*dst = f
+	}
+	return nil
+}
+
+// assignIntFromFloat stores f in elem, an integer value, if f is a whole number in its range.
+func assignIntFromFloat(f float64, elem reflect.Value, dst interface{}) error {
+	if math.Trunc(f) != f || math.IsInf(f, 0) {
+		return fmt.Errorf("cannot scan %v into %T: not a whole number", f, dst)
+	}
+
+	switch elem.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f < math.MinInt64 || f >= math.MaxInt64 || elem.OverflowInt(int64(f)) {
+			return fmt.Errorf("cannot scan %v into %T: out of range", f, dst)
+		}
+		elem.SetInt(int64(f))
+	default:
+		if f < 0 || f >= math.MaxUint64 || elem.OverflowUint(uint64(f)) {
+			return fmt.Errorf("cannot scan %v into %T: out of range", f, dst)
+		}
+		elem.SetUint(uint64(f))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package pgx
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+
+	"github.com/jackc/pgtype"
+)
+
+// scanFast decodes src directly into dst for the most common combinations of binary column type and destination,
+// including the widening integer conversions such as int4 into *int that pgtype only handles through its generic path.
+// It returns false without modifying dst if it does not handle the combination, src is NULL, or src has an unexpected
+// length, so the value can be scanned normally and any error reported as usual.
+func scanFast(oid uint32, format int16, src []byte, dst interface{}) bool {
+	if format != BinaryFormatCode || src == nil {
+		return false
+	}
+
+	switch dst := dst.(type) {
+	case *int64:
+		if n, ok := decodeBinaryInt(oid, src); ok {
+			*dst = n
+			return true
+		}
+	case *int:
+		if n, ok := decodeBinaryInt(oid, src); ok && (strconv.IntSize == 64 || oid != pgtype.Int8OID) {
+			*dst = int(n)
+			return true
+		}
+	case *int32:
+		if oid != pgtype.Int8OID {
+			if n, ok := decodeBinaryInt(oid, src); ok {
+				*dst = int32(n)
+				return true
+			}
+		}
+	case *int16:
+		if oid == pgtype.Int2OID && len(src) == 2 {
+			*dst = int16(binary.BigEndian.Uint16(src))
+			return true
+		}
+	case *float64:
+		switch {
+		case oid == pgtype.Float8OID && len(src) == 8:
+			*dst = math.Float64frombits(binary.BigEndian.Uint64(src))
+			return true
+		case oid == pgtype.Float4OID && len(src) == 4:
+			*dst = float64(math.Float32frombits(binary.BigEndian.Uint32(src)))
+			return true
+		}
+	case *float32:
+		if oid == pgtype.Float4OID && len(src) == 4 {
+			*dst = math.Float32frombits(binary.BigEndian.Uint32(src))
+			return true
+		}
+	case *string:
+		switch oid {
+		case pgtype.TextOID, pgtype.VarcharOID:
+			*dst = string(src)
+			return true
+		}
+	case *bool:
+		if oid == pgtype.BoolOID && len(src) == 1 {
+			*dst = src[0] == 1
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodeBinaryInt decodes an int2, int4 or int8 in the binary format.
+func decodeBinaryInt(oid uint32, src []byte) (int64, bool) {
+	switch {
+	case oid == pgtype.Int4OID && len(src) == 4:
+		return int64(int32(binary.BigEndian.Uint32(src))), true
+	case oid == pgtype.Int8OID && len(src) == 8:
+		return int64(binary.BigEndian.Uint64(src)), true
+	case oid == pgtype.Int2OID && len(src) == 2:
+		return int64(int16(binary.BigEndian.Uint16(src))), true
+	}
+	return 0, false
+}
@@ -0,0 +1,124 @@
+package pgx
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// serverParams is a copy of the run-time parameters reported by the server. Unlike the parameters held by
+// pgconn.PgConn it can be read while the connection is in use by another goroutine.
+type serverParams struct {
+	mux    sync.RWMutex
+	params map[string]string
+}
+
+func (sp *serverParams) get(name string) string {
+	sp.mux.RLock()
+	defer sp.mux.RUnlock()
+	return sp.params[name]
+}
+
+func (sp *serverParams) set(name, value string) {
+	sp.mux.Lock()
+	sp.params[name] = value
+	sp.mux.Unlock()
+}
+
+// reset forgets the parameters reported by a previous connection attempt.
+func (sp *serverParams) reset() {
+	sp.mux.Lock()
+	sp.params = make(map[string]string)
+	sp.mux.Unlock()
+}
+
+// buildFrontend wraps buildFrontend so the ParameterStatus messages received by the frontends it builds are recorded in
+// sp.
+func (sp *serverParams) buildFrontend(buildFrontend pgconn.BuildFrontendFunc) pgconn.BuildFrontendFunc {
+	return func(r io.Reader, w io.Writer) pgconn.Frontend {
+		sp.reset()
+		return &paramStatusFrontend{Frontend: buildFrontend(r, w), params: sp}
+	}
+}
+
+// paramStatusFrontend is a pgconn.Frontend that records the ParameterStatus messages it receives.
+type paramStatusFrontend struct {
+	pgconn.Frontend
+	params *serverParams
+}
+
+func (f *paramStatusFrontend) Receive() (pgproto3.BackendMessage, error) {
+	msg, err := f.Frontend.Receive()
+	if ps, ok := msg.(*pgproto3.ParameterStatus); ok {
+		f.params.set(ps.Name, ps.Value)
+	}
+	return msg, err
+}
+
+// ServerParameter returns the value of the run-time parameter name most recently reported by the server, such as
+// server_version, TimeZone, or application_name, or an empty string if the server has not reported it. The server
+// reports a fixed set of parameters when the connection is established and reports them again when they change.
+//
+// ServerParameter may be called concurrently with other use of the connection. It is the stable way to read server
+// parameters. The RuntimeParams of the config are the parameters requested when connecting, not the values in effect.
+func (c *Conn) ServerParameter(name string) string {
+	return c.serverParams.get(name)
+}
+
+// ServerVersion returns the version of the server in the form of server_version_num, such as 140005 for 14.5 or 90624
+// for 9.6.24. It returns 0 if the version reported by the server cannot be parsed. It may be called concurrently with
+// other use of the connection.
+func (c *Conn) ServerVersion() int {
+	return parseServerVersion(c.ServerParameter("server_version"))
+}
+
+// TimeZone returns the location of the TimeZone parameter of the session. It returns an error if the time zone is not
+// known to the time package, such as a POSIX time zone specification. It may be called concurrently with other use of
+// the connection.
+func (c *Conn) TimeZone() (*time.Location, error) {
+	return time.LoadLocation(c.ServerParameter("TimeZone"))
+}
+
+// IsSuperuser reports whether the session user is a superuser. It may be called concurrently with other use of the
+// connection.
+func (c *Conn) IsSuperuser() bool {
+	return c.ServerParameter("is_superuser") == "on"
+}
+
+// parseServerVersion converts a server_version such as "14.5 (Debian 14.5-1)", "9.6.24", or "16beta1" to the form of
+// server_version_num.
+func parseServerVersion(version string) int {
+	if i := strings.IndexFunc(version, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		version = version[:i]
+	}
+
+	parts := strings.Split(version, ".")
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		nums[i] = n
+	}
+
+	switch {
+	case nums[0] >= 10:
+		if len(nums) > 1 {
+			return nums[0]*10000 + nums[1]
+		}
+		return nums[0] * 10000
+	case len(nums) >= 2:
+		n := nums[0]*10000 + nums[1]*100
+		if len(nums) > 2 {
+			n += nums[2]
+		}
+		return n
+	}
+	return 0
+}
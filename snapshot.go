@@ -0,0 +1,53 @@
+package pgx
+
+import (
+	"context"
+	"strings"
+)
+
+// BeginExportSnapshot starts a transaction with txOptions and exports its snapshot with pg_export_snapshot. The
+// returned snapshot ID can be passed to BeginTxWithSnapshot on other connections so they see the same data as the
+// returned transaction. The snapshot can only be imported while the returned transaction is open. If txOptions.IsoLevel
+// is not set repeatable read is used.
+func (c *Conn) BeginExportSnapshot(ctx context.Context, txOptions TxOptions) (Tx, string, error) {
+	if txOptions.IsoLevel == "" {
+		txOptions.IsoLevel = RepeatableRead
+	}
+
+	tx, err := c.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var snapshot string
+	err = tx.QueryRow(ctx, "select pg_export_snapshot()").Scan(&snapshot)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, "", err
+	}
+
+	return tx, snapshot, nil
+}
+
+// BeginTxWithSnapshot starts a transaction with txOptions that uses snapshot. snapshot is a snapshot ID returned by
+// BeginExportSnapshot or by creating a logical replication slot with EXPORT_SNAPSHOT. This allows multiple connections
+// to read a consistent view of the database such as for a parallel dump or the initial load of change data capture
+// that continues from a replication slot. If txOptions.IsoLevel is not set repeatable read is used.
+func (c *Conn) BeginTxWithSnapshot(ctx context.Context, txOptions TxOptions, snapshot string) (Tx, error) {
+	if txOptions.IsoLevel == "" {
+		txOptions.IsoLevel = RepeatableRead
+	}
+
+	tx, err := c.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, "set transaction snapshot '"+strings.ReplaceAll(snapshot, "'", "''")+"'")
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,73 @@
+package pgx
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SQLCommentTagsFunc returns the tags to attach to a query as an SQL comment. It is called with the context of the
+// query. Returning an empty map leaves the query unchanged.
+type SQLCommentTagsFunc func(ctx context.Context) map[string]string
+
+// FormatSQLComment formats tags as a comment following the sqlcommenter specification
+// (https://google.github.io/sqlcommenter/spec/). Keys and values are URL encoded, values are single quoted, and the
+// pairs are sorted by key. e.g. /*application='api',traceparent='00-0af7...-01'*/
+func FormatSQLComment(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sb := &strings.Builder{}
+	sb.WriteString("/*")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(sqlCommentEscape(k))
+		sb.WriteString("='")
+		sb.WriteString(sqlCommentEscape(tags[k]))
+		sb.WriteByte('\'')
+	}
+	sb.WriteString("*/")
+
+	return sb.String()
+}
+
+func sqlCommentEscape(s string) string {
+	// PathEscape encodes spaces as %20 as required by the specification. It also encodes single quotes so the value
+	// cannot terminate its quoting.
+	return url.PathEscape(s)
+}
+
+// commentSQL appends the comment built from c.config.SQLCommentTags to sql. Queries that already contain a comment are
+// not modified.
+func (c *Conn) commentSQL(ctx context.Context, sql string) string {
+	if c.config.SQLCommentTags == nil {
+		return sql
+	}
+
+	if strings.Contains(sql, "/*") || strings.Contains(sql, "--") {
+		return sql
+	}
+
+	comment := FormatSQLComment(c.config.SQLCommentTags(ctx))
+	if comment == "" {
+		return sql
+	}
+
+	// Keep a trailing semicolon at the end of the statement.
+	trimmed := strings.TrimRight(sql, " \t\r\n")
+	if strings.HasSuffix(trimmed, ";") {
+		return trimmed[:len(trimmed)-1] + comment + ";"
+	}
+
+	return sql + comment
+}
@@ -0,0 +1,65 @@
+package pgx_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatSQLComment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tags     map[string]string
+		expected string
+	}{
+		{tags: nil, expected: ""},
+		{tags: map[string]string{"application": "api"}, expected: `/*application='api'*/`},
+		{
+			tags:     map[string]string{"traceparent": "00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01", "application": "api"},
+			expected: `/*application='api',traceparent='00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01'*/`,
+		},
+		{tags: map[string]string{"route": "/users/{id}"}, expected: `/*route='%2Fusers%2F%7Bid%7D'*/`},
+		{tags: map[string]string{"name": "it's a test"}, expected: `/*name='it%27s%20a%20test'*/`},
+	}
+
+	for i, tt := range tests {
+		assert.Equalf(t, tt.expected, pgx.FormatSQLComment(tt.tags), "%d", i)
+	}
+}
+
+func TestSQLCommentTags(t *testing.T) {
+	t.Parallel()
+
+	type ctxKey struct{}
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.PreferSimpleProtocol = true
+	config.SQLCommentTags = func(ctx context.Context) map[string]string {
+		if requestID, ok := ctx.Value(ctxKey{}).(string); ok {
+			return map[string]string{"request_id": requestID}
+		}
+		return nil
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var query string
+	ctx := context.WithValue(context.Background(), ctxKey{}, "abc123")
+	err := conn.QueryRow(ctx, "select current_query()").Scan(&query)
+	require.NoError(t, err)
+	require.Equal(t, `select current_query()/*request_id='abc123'*/`, query)
+
+	err = conn.QueryRow(context.Background(), "select current_query()").Scan(&query)
+	require.NoError(t, err)
+	require.Equal(t, `select current_query()`, query)
+
+	err = conn.QueryRow(ctx, "select current_query() -- already commented").Scan(&query)
+	require.NoError(t, err)
+	require.Equal(t, `select current_query() -- already commented`, query)
+}
@@ -0,0 +1,166 @@
+package pgx
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgconn/stmtcache"
+)
+
+// StatementCacheName returns the name of the prepared statement the automatic statement cache creates for sql. The name
+// is derived from a hash of sql so the same statement has the same name on every connection and across restarts. This
+// makes the statements of an application easy to find in pg_prepared_statements.
+func StatementCacheName(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return "pgx_" + hex.EncodeToString(sum[:16])
+}
+
+// NewStatementCache returns the statement cache used by default. It is a least recently used cache like stmtcache.New
+// but names prepared statements with StatementCacheName instead of a sequence number that differs per connection. mode
+// is either stmtcache.ModePrepare or stmtcache.ModeDescribe. cap is the maximum size of the cache.
+func NewStatementCache(conn *pgconn.PgConn, mode int, cap int) stmtcache.Cache {
+	if mode != stmtcache.ModePrepare && mode != stmtcache.ModeDescribe {
+		panic("mode must be ModePrepare or ModeDescribe")
+	}
+	if cap < 1 {
+		panic("cache must have cap of >= 1")
+	}
+
+	return &statementCache{
+		conn:   conn,
+		mode:   mode,
+		cap:    cap,
+		m:      make(map[string]*list.Element),
+		l:      list.New(),
+		leaked: make(map[string]struct{}),
+	}
+}
+
+// statementCache is a least recently used stmtcache.Cache with names from StatementCacheName.
+type statementCache struct {
+	conn         *pgconn.PgConn
+	mode         int
+	cap          int
+	m            map[string]*list.Element
+	l            *list.List
+	stmtsToClear []string
+	leaked       map[string]struct{} // names that failed to deallocate
+}
+
+func (c *statementCache) Get(ctx context.Context, sql string) (*pgconn.StatementDescription, error) {
+	// Deallocate statements that errored once outside of a failed transaction.
+	txStatus := c.conn.TxStatus()
+	if (txStatus == 'I' || txStatus == 'T') && len(c.stmtsToClear) > 0 {
+		for _, stmt := range c.stmtsToClear {
+			if err := c.clearStmt(ctx, stmt); err != nil {
+				return nil, err
+			}
+		}
+		c.stmtsToClear = nil
+	}
+
+	if el, ok := c.m[sql]; ok {
+		c.l.MoveToFront(el)
+		return el.Value.(*pgconn.StatementDescription), nil
+	}
+
+	if c.l.Len() == c.cap {
+		if err := c.removeOldest(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	sd, err := c.prepare(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	c.m[sql] = c.l.PushFront(sd)
+	return sd, nil
+}
+
+func (c *statementCache) Clear(ctx context.Context) error {
+	for c.l.Len() > 0 {
+		if err := c.removeOldest(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *statementCache) StatementErrored(sql string, err error) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return
+	}
+
+	if pgErr.Severity == "ERROR" && pgErr.Code == "0A000" && pgErr.Message == "cached plan must not change result type" {
+		c.stmtsToClear = append(c.stmtsToClear, sql)
+	}
+}
+
+func (c *statementCache) Len() int { return c.l.Len() }
+
+func (c *statementCache) Cap() int { return c.cap }
+
+func (c *statementCache) Mode() int { return c.mode }
+
+func (c *statementCache) prepare(ctx context.Context, sql string) (*pgconn.StatementDescription, error) {
+	if c.mode == stmtcache.ModeDescribe {
+		return c.conn.Prepare(ctx, "", sql, nil)
+	}
+
+	name := StatementCacheName(sql)
+	if _, ok := c.leaked[name]; ok {
+		// The statement still exists on the server as it could not be deallocated when it was evicted. The same name
+		// cannot be prepared again until it is.
+		if err := c.deallocate(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	return c.conn.Prepare(ctx, name, sql, nil)
+}
+
+func (c *statementCache) clearStmt(ctx context.Context, sql string) error {
+	el, ok := c.m[sql]
+	if !ok {
+		// The statement was already evicted.
+		return nil
+	}
+
+	c.l.Remove(el)
+	sd := el.Value.(*pgconn.StatementDescription)
+	delete(c.m, sd.SQL)
+	if c.mode == stmtcache.ModePrepare {
+		return c.deallocate(ctx, sd.Name)
+	}
+	return nil
+}
+
+func (c *statementCache) removeOldest(ctx context.Context) error {
+	oldest := c.l.Back()
+	c.l.Remove(oldest)
+	sd := oldest.Value.(*pgconn.StatementDescription)
+	delete(c.m, sd.SQL)
+	if c.mode == stmtcache.ModePrepare {
+		return c.deallocate(ctx, sd.Name)
+	}
+	return nil
+}
+
+// deallocate deallocates the statement name. If that fails, such as in an aborted transaction, name is remembered so it
+// is deallocated before it is prepared again. A per connection sequence number would avoid this but not give the same
+// name on every connection.
+func (c *statementCache) deallocate(ctx context.Context, name string) error {
+	err := c.conn.Exec(ctx, "deallocate "+quoteIdentifier(name)).Close()
+	if err != nil {
+		c.leaked[name] = struct{}{}
+	} else {
+		delete(c.leaked, name)
+	}
+	return err
+}
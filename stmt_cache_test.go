@@ -0,0 +1,70 @@
+package pgx_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgconn/stmtcache"
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementCacheName(t *testing.T) {
+	t.Parallel()
+
+	name := pgx.StatementCacheName("select 1")
+	assert.Equal(t, name, pgx.StatementCacheName("select 1"))
+	assert.NotEqual(t, name, pgx.StatementCacheName("select 2"))
+	assert.Len(t, name, 36)
+}
+
+func TestStatementCacheSameNameOnEveryConn(t *testing.T) {
+	t.Parallel()
+
+	const sql = "select $1::int4 as statement_cache_same_name"
+
+	for i := 0; i < 2; i++ {
+		func() {
+			conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+			defer closeConn(t, conn)
+
+			var n int32
+			err := conn.QueryRow(context.Background(), sql, 42).Scan(&n)
+			require.NoError(t, err)
+
+			var name string
+			err = conn.QueryRow(context.Background(), "select name from pg_prepared_statements where statement = $1", sql).Scan(&name)
+			require.NoError(t, err)
+			assert.Equal(t, pgx.StatementCacheName(sql), name)
+		}()
+	}
+}
+
+func TestStatementCacheEvictionInAbortedTx(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.BuildStatementCache = func(conn *pgconn.PgConn) stmtcache.Cache {
+		return pgx.NewStatementCache(conn, stmtcache.ModePrepare, 1)
+	}
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	ctx := context.Background()
+	mustExec(t, conn, "select 1")
+
+	// Evicting "select 1" fails in the aborted transaction so it must be deallocated when it is prepared again.
+	tx, err := conn.Begin(ctx)
+	require.NoError(t, err)
+	_, err = tx.Exec(ctx, "select 1/0", pgx.QuerySimpleProtocol(true))
+	require.Error(t, err)
+	_, err = tx.Exec(ctx, "select 2")
+	require.Error(t, err)
+	require.NoError(t, tx.Rollback(ctx))
+
+	mustExec(t, conn, "select 1")
+	ensureConnValid(t, conn)
+}
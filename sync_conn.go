@@ -0,0 +1,266 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// SyncConn is a wrapper that allows a single *Conn to be shared by multiple goroutines. Each call waits for exclusive
+// use of the connection. Waiting calls are served in approximately the order they started. It is intended for tools
+// with little concurrency such as command line programs and schedulers. Applications with more concurrency should use
+// pgxpool.
+//
+// Rows returned by Query hold the connection until they are closed or fully read, a Row returned by QueryRow holds it
+// until Scan is called, and BatchResults returned by SendBatch hold it until they are closed.
+type SyncConn struct {
+	conn *Conn
+	lock chan struct{}
+}
+
+// NewSyncConn returns a SyncConn that serializes access to conn. conn must not be used directly while the SyncConn is
+// in use.
+func NewSyncConn(conn *Conn) *SyncConn {
+	return &SyncConn{conn: conn, lock: make(chan struct{}, 1)}
+}
+
+func (sc *SyncConn) acquire(ctx context.Context) error {
+	select {
+	case sc.lock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (sc *SyncConn) release() {
+	<-sc.lock
+}
+
+// Do calls f with exclusive use of the underlying connection. The connection must not be used after f returns. Do is
+// used for operations that are not provided by SyncConn such as transactions that are not run with BeginFunc.
+func (sc *SyncConn) Do(ctx context.Context, f func(*Conn) error) error {
+	if err := sc.acquire(ctx); err != nil {
+		return err
+	}
+	defer sc.release()
+
+	return f(sc.conn)
+}
+
+// Close closes the underlying connection. It waits for exclusive use of the connection.
+func (sc *SyncConn) Close(ctx context.Context) error {
+	return sc.Do(ctx, func(c *Conn) error { return c.Close(ctx) })
+}
+
+// Exec waits for exclusive use of the connection and calls Exec on it.
+func (sc *SyncConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	if err := sc.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer sc.release()
+
+	return sc.conn.Exec(ctx, sql, arguments...)
+}
+
+// Query waits for exclusive use of the connection and calls Query on it. The connection is held until the returned
+// Rows are closed.
+func (sc *SyncConn) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	if err := sc.acquire(ctx); err != nil {
+		return syncErrRows{err: err}, err
+	}
+
+	rows, err := sc.conn.Query(ctx, sql, args...)
+	if err != nil {
+		sc.release()
+		return syncErrRows{err: err}, err
+	}
+
+	return &syncRows{r: rows, sc: sc}, nil
+}
+
+// QueryRow waits for exclusive use of the connection and calls QueryRow on it. The connection is held until Scan is
+// called on the returned Row.
+func (sc *SyncConn) QueryRow(ctx context.Context, sql string, args ...interface{}) Row {
+	if err := sc.acquire(ctx); err != nil {
+		return syncErrRows{err: err}
+	}
+
+	return &syncRow{r: sc.conn.QueryRow(ctx, sql, args...), sc: sc}
+}
+
+// QueryFunc waits for exclusive use of the connection and calls QueryFunc on it.
+func (sc *SyncConn) QueryFunc(ctx context.Context, sql string, args []interface{}, scans []interface{}, f func(QueryFuncRow) error) (pgconn.CommandTag, error) {
+	if err := sc.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer sc.release()
+
+	return sc.conn.QueryFunc(ctx, sql, args, scans, f)
+}
+
+// SendBatch waits for exclusive use of the connection and calls SendBatch on it. The connection is held until the
+// returned BatchResults are closed.
+func (sc *SyncConn) SendBatch(ctx context.Context, b *Batch) BatchResults {
+	if err := sc.acquire(ctx); err != nil {
+		return &batchResults{ctx: ctx, err: err}
+	}
+
+	return &syncBatchResults{br: sc.conn.SendBatch(ctx, b), sc: sc}
+}
+
+// CopyFrom waits for exclusive use of the connection and calls CopyFrom on it.
+func (sc *SyncConn) CopyFrom(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource) (int64, error) {
+	if err := sc.acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer sc.release()
+
+	return sc.conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// BeginFunc waits for exclusive use of the connection and calls BeginFunc on it. The connection is held until the
+// transaction is committed or rolled back.
+func (sc *SyncConn) BeginFunc(ctx context.Context, f func(Tx) error) error {
+	return sc.BeginTxFunc(ctx, TxOptions{}, f)
+}
+
+// BeginTxFunc waits for exclusive use of the connection and calls BeginTxFunc on it. The connection is held until the
+// transaction is committed or rolled back.
+func (sc *SyncConn) BeginTxFunc(ctx context.Context, txOptions TxOptions, f func(Tx) error) error {
+	if err := sc.acquire(ctx); err != nil {
+		return err
+	}
+	defer sc.release()
+
+	return sc.conn.BeginTxFunc(ctx, txOptions, f)
+}
+
+// Ping waits for exclusive use of the connection and calls Ping on it.
+func (sc *SyncConn) Ping(ctx context.Context) error {
+	if err := sc.acquire(ctx); err != nil {
+		return err
+	}
+	defer sc.release()
+
+	return sc.conn.Ping(ctx)
+}
+
+type syncErrRows struct {
+	err error
+}
+
+func (syncErrRows) Close()                                         {}
+func (e syncErrRows) Err() error                                   { return e.err }
+func (syncErrRows) CommandTag() pgconn.CommandTag                  { return nil }
+func (syncErrRows) FieldDescriptions() []pgproto3.FieldDescription { return nil }
+func (syncErrRows) Next() bool                                     { return false }
+func (e syncErrRows) Scan(dest ...interface{}) error               { return e.err }
+func (e syncErrRows) Values() ([]interface{}, error)               { return nil, e.err }
+func (e syncErrRows) RawValues() [][]byte                          { return nil }
+
+// syncRows releases its SyncConn when it is closed.
+type syncRows struct {
+	r  Rows
+	sc *SyncConn
+}
+
+func (rows *syncRows) Close() {
+	rows.r.Close()
+	if rows.sc != nil {
+		rows.sc.release()
+		rows.sc = nil
+	}
+}
+
+func (rows *syncRows) Err() error {
+	return rows.r.Err()
+}
+
+func (rows *syncRows) CommandTag() pgconn.CommandTag {
+	return rows.r.CommandTag()
+}
+
+func (rows *syncRows) FieldDescriptions() []pgproto3.FieldDescription {
+	return rows.r.FieldDescriptions()
+}
+
+func (rows *syncRows) Next() bool {
+	n := rows.r.Next()
+	if !n {
+		rows.Close()
+	}
+	return n
+}
+
+func (rows *syncRows) Scan(dest ...interface{}) error {
+	err := rows.r.Scan(dest...)
+	if err != nil {
+		rows.Close()
+	}
+	return err
+}
+
+func (rows *syncRows) Values() ([]interface{}, error) {
+	values, err := rows.r.Values()
+	if err != nil {
+		rows.Close()
+	}
+	return values, err
+}
+
+func (rows *syncRows) RawValues() [][]byte {
+	return rows.r.RawValues()
+}
+
+var errSyncRowScanned = errors.New("row already scanned")
+
+// syncRow releases its SyncConn when it is scanned.
+type syncRow struct {
+	r  Row
+	sc *SyncConn
+}
+
+func (row *syncRow) Scan(dest ...interface{}) error {
+	if row.sc == nil {
+		return errSyncRowScanned
+	}
+
+	err := row.r.Scan(dest...)
+	row.sc.release()
+	row.sc = nil
+	return err
+}
+
+// syncBatchResults releases its SyncConn when it is closed.
+type syncBatchResults struct {
+	br BatchResults
+	sc *SyncConn
+}
+
+func (br *syncBatchResults) Exec() (pgconn.CommandTag, error) {
+	return br.br.Exec()
+}
+
+func (br *syncBatchResults) Query() (Rows, error) {
+	return br.br.Query()
+}
+
+func (br *syncBatchResults) QueryRow() Row {
+	return br.br.QueryRow()
+}
+
+func (br *syncBatchResults) QueryFunc(scans []interface{}, f func(QueryFuncRow) error) (pgconn.CommandTag, error) {
+	return br.br.QueryFunc(scans, f)
+}
+
+func (br *syncBatchResults) Close() error {
+	err := br.br.Close()
+	if br.sc != nil {
+		br.sc.release()
+		br.sc = nil
+	}
+	return err
+}
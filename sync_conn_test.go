@@ -0,0 +1,82 @@
+package pgx_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncConnConcurrentUse(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	sc := pgx.NewSyncConn(conn)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			var n int32
+			err := sc.QueryRow(context.Background(), "select $1::int4", i).Scan(&n)
+			assert.NoError(t, err)
+			assert.EqualValues(t, i, n)
+
+			rows, err := sc.Query(context.Background(), "select generate_series(1, $1::int4)", i+1)
+			if !assert.NoError(t, err) {
+				return
+			}
+			var sum int32
+			for rows.Next() {
+				var x int32
+				assert.NoError(t, rows.Scan(&x))
+				sum += x
+			}
+			assert.NoError(t, rows.Err())
+			assert.EqualValues(t, (i+1)*(i+2)/2, sum)
+
+			_, err = sc.Exec(context.Background(), "select pg_sleep(0.01)")
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	ensureConnValid(t, conn)
+}
+
+func TestSyncConnAcquireCanceled(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	sc := pgx.NewSyncConn(conn)
+
+	rows, err := sc.Query(context.Background(), "select generate_series(1, 3)")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = sc.Exec(ctx, "select 1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var n int32
+	err = sc.QueryRow(ctx, "select 1").Scan(&n)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	rows.Close()
+
+	_, err = sc.Exec(context.Background(), "select 1")
+	require.NoError(t, err)
+
+	ensureConnValid(t, conn)
+}
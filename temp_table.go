@@ -0,0 +1,223 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// tempTableDropTimeout is the maximum time WithTempTable waits to drop its table.
+const tempTableDropTimeout = 5 * time.Second
+
+// tempTableCount makes the names of temporary tables created by WithTempTable unique.
+var tempTableCount uint64
+
+// TempTableColumn is a column of a temporary table created by WithTempTable.
+type TempTableColumn struct {
+	Name string
+	Type string // PostgreSQL type such as "int8" or "text[]"
+}
+
+// WithTempTable creates a temporary table with columns, loads rowSrc into it with CopyFrom, and calls f with the name of
+// the table. The table is dropped when f returns. It is intended for sending large sets of values such as a list of
+// IDs to the server to join against instead of building huge IN lists or ANY arrays. The table is analyzed after it is
+// loaded so the planner knows its size.
+//
+// The table exists only for this connection. f must use c for all queries that refer to the table. The return value is
+// either an error creating or loading the table, the return value of f, or an error dropping the table. The table is
+// dropped even if ctx is done when f returns. If it cannot be dropped, such as when f leaves a failed transaction open,
+// the connection is closed.
+//
+//	columns := []pgx.TempTableColumn{{Name: "id", Type: "int8"}}
+//	err := conn.WithTempTable(ctx, columns, pgx.CopyFromRows(ids), func(table pgx.Identifier) error {
+//		_, err := conn.Exec(ctx, "delete from widgets using "+table.Sanitize()+" t where widgets.id = t.id")
+//		return err
+//	})
+func (c *Conn) WithTempTable(ctx context.Context, columns []TempTableColumn, rowSrc CopyFromSource, f func(table Identifier) error) (err error) {
+	if len(columns) == 0 {
+		return errors.New("temporary table must have at least one column")
+	}
+
+	table := Identifier{fmt.Sprintf("pgx_temp_%d", atomic.AddUint64(&tempTableCount, 1))}
+	columnNames := make([]string, len(columns))
+	columnDefs := make([]string, len(columns))
+	for i, col := range columns {
+		if col.Name == "" || col.Type == "" {
+			return fmt.Errorf("temporary table column %d must have a name and a type", i)
+		}
+		columnNames[i] = col.Name
+		columnDefs[i] = quoteIdentifier(col.Name) + " " + col.Type
+	}
+
+	_, err = c.Exec(ctx, "create temporary table "+table.Sanitize()+" ("+strings.Join(columnDefs, ", ")+")")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// ctx may be done when f returns so the table is dropped with a context of its own. A connection that still has
+		// the table is closed so a pool does not hand it out with the table and its data still present.
+		dropCtx, cancel := context.WithTimeout(context.Background(), tempTableDropTimeout)
+		defer cancel()
+		_, dropErr := c.Exec(dropCtx, "drop table if exists "+table.Sanitize())
+		if dropErr != nil {
+			c.die(fmt.Errorf("failed to drop temporary table: %w", dropErr))
+		}
+		if err == nil {
+			err = dropErr
+		}
+	}()
+
+	_, err = c.CopyFrom(ctx, table, columnNames, rowSrc)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Exec(ctx, "analyze "+table.Sanitize())
+	if err != nil {
+		return err
+	}
+
+	return f(table)
+}
+
+// TempTableColumnsOf returns the columns of a temporary table for the exported fields of the struct v or the struct v
+// points to. The column name is taken from the db tag of a field or is the lowercase field name if it has no tag. A db
+// tag of "-" skips the field. The column type is taken from the dbtype tag of a field or is derived from the Go type of
+// the field: integer, float, bool, string, []byte, time.Time, time.Duration, [16]byte (uuid), and slices of these
+// (arrays). Pointer fields have the type of the value they point to.
+//
+//	type widget struct {
+//		ID    int64  `db:"id"`
+//		Price string `db:"price" dbtype:"numeric"`
+//	}
+func TempTableColumnsOf(v interface{}) ([]TempTableColumn, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a struct", v)
+	}
+
+	var columns []TempTableColumn
+	for _, field := range tempTableFields(t) {
+		sf := t.Field(field)
+		name := sf.Tag.Get("db")
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+
+		typ := sf.Tag.Get("dbtype")
+		if typ == "" {
+			var ok bool
+			typ, ok = tempTableType(sf.Type)
+			if !ok {
+				return nil, fmt.Errorf("cannot determine PostgreSQL type of field %s of type %v: set the dbtype tag", sf.Name, sf.Type)
+			}
+		}
+
+		columns = append(columns, TempTableColumn{Name: name, Type: typ})
+	}
+
+	return columns, nil
+}
+
+// CopyFromStructs returns a CopyFromSource over rows, a slice of structs or pointers to structs. The values are the
+// fields in the order of the columns of TempTableColumnsOf.
+func CopyFromStructs(rows interface{}) (CopyFromSource, error) {
+	rv := reflect.ValueOf(rows)
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("%T is not a slice", rows)
+	}
+
+	t := rv.Type().Elem()
+	ptr := t.Kind() == reflect.Ptr
+	if ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a slice of structs", rows)
+	}
+	fields := tempTableFields(t)
+
+	return CopyFromSlice(rv.Len(), func(i int) ([]interface{}, error) {
+		elem := rv.Index(i)
+		if ptr {
+			if elem.IsNil() {
+				return nil, fmt.Errorf("row %d is nil", i)
+			}
+			elem = elem.Elem()
+		}
+
+		values := make([]interface{}, len(fields))
+		for j, field := range fields {
+			values[j] = elem.Field(field).Interface()
+		}
+		return values, nil
+	}), nil
+}
+
+// tempTableFields returns the indexes of the fields of the struct type t that are columns.
+func tempTableFields(t reflect.Type) []int {
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" || sf.Tag.Get("db") == "-" {
+			continue
+		}
+		fields = append(fields, i)
+	}
+	return fields
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+func tempTableType(t reflect.Type) (string, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return "timestamptz", true
+	case durationType:
+		return "interval", true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool", true
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "int2", true
+	case reflect.Int32, reflect.Uint16:
+		return "int4", true
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "int8", true
+	case reflect.Float32:
+		return "float4", true
+	case reflect.Float64:
+		return "float8", true
+	case reflect.String:
+		return "text", true
+	case reflect.Array:
+		if t.Len() == 16 && t.Elem().Kind() == reflect.Uint8 {
+			return "uuid", true
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytea", true
+		}
+		if elem, ok := tempTableType(t.Elem()); ok && !strings.HasSuffix(elem, "[]") && elem != "bytea" {
+			return elem + "[]", true
+		}
+	}
+
+	return "", false
+}
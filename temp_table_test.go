@@ -0,0 +1,111 @@
+package pgx_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nappspt/schemapgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tempTableRow struct {
+	ID      int64 `db:"id"`
+	Name    string
+	Price   string `db:"price" dbtype:"numeric"`
+	Tags    []string
+	Created *time.Time
+	Ignored int `db:"-"`
+	private int
+}
+
+func TestTempTableColumnsOf(t *testing.T) {
+	t.Parallel()
+
+	columns, err := pgx.TempTableColumnsOf(&tempTableRow{})
+	require.NoError(t, err)
+	assert.Equal(t, []pgx.TempTableColumn{
+		{Name: "id", Type: "int8"},
+		{Name: "name", Type: "text"},
+		{Name: "price", Type: "numeric"},
+		{Name: "tags", Type: "text[]"},
+		{Name: "created", Type: "timestamptz"},
+	}, columns)
+
+	_, err = pgx.TempTableColumnsOf(struct{ C chan int }{})
+	assert.Error(t, err)
+
+	_, err = pgx.TempTableColumnsOf(42)
+	assert.Error(t, err)
+}
+
+func TestConnWithTempTable(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	ctx := context.Background()
+	now := time.Now()
+	rows := []tempTableRow{
+		{ID: 1, Name: "a", Price: "1.50", Tags: []string{"x"}, Created: &now},
+		{ID: 2, Name: "b", Price: "2.25"},
+		{ID: 3, Name: "c", Price: "3.00", Tags: []string{}},
+	}
+
+	columns, err := pgx.TempTableColumnsOf(tempTableRow{})
+	require.NoError(t, err)
+	rowSrc, err := pgx.CopyFromStructs(rows)
+	require.NoError(t, err)
+
+	var tableName string
+	err = conn.WithTempTable(ctx, columns, rowSrc, func(table pgx.Identifier) error {
+		tableName = table[0]
+
+		var sum string
+		err := conn.QueryRow(ctx, "select sum(t.price)::text from "+table.Sanitize()+" t join generate_series(1, 2) n on n = t.id").Scan(&sum)
+		require.NoError(t, err)
+		assert.Equal(t, "3.75", sum)
+
+		var created time.Time
+		err = conn.QueryRow(ctx, "select created from "+table.Sanitize()+" where id = 1").Scan(&created)
+		require.NoError(t, err)
+		assert.True(t, now.Truncate(time.Microsecond).Equal(created))
+		return nil
+	})
+	require.NoError(t, err)
+
+	var exists bool
+	err = conn.QueryRow(ctx, "select to_regclass($1) is not null", "pg_temp."+tableName).Scan(&exists)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnWithTempTableDropsTableAfterContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	columns := []pgx.TempTableColumn{{Name: "id", Type: "int8"}}
+
+	var tableName string
+	err := conn.WithTempTable(ctx, columns, pgx.CopyFromRows([][]interface{}{{int64(1)}}), func(table pgx.Identifier) error {
+		tableName = table[0]
+		cancel()
+		return nil
+	})
+	require.NoError(t, err)
+
+	var exists bool
+	err = conn.QueryRow(context.Background(), "select to_regclass($1) is not null", "pg_temp."+tableName).Scan(&exists)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	ensureConnValid(t, conn)
+}
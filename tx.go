@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/jackc/pgconn"
 )
@@ -39,6 +40,58 @@ const (
 	NotDeferrable = TxDeferrableMode("not deferrable")
 )
 
+// ConstraintMode is when deferrable constraints are checked in a transaction (deferred or immediate)
+type ConstraintMode string
+
+// Constraint modes
+const (
+	ConstraintsDeferred  = ConstraintMode("deferred")
+	ConstraintsImmediate = ConstraintMode("immediate")
+)
+
+// setConstraintsSQL returns the SET CONSTRAINTS statement that sets names, or all constraints if names is empty, to
+// mode.
+func setConstraintsSQL(mode ConstraintMode, names []Identifier) (string, error) {
+	switch mode {
+	case ConstraintsDeferred, ConstraintsImmediate:
+	default:
+		return "", fmt.Errorf("invalid constraint mode: %s", mode)
+	}
+
+	if len(names) == 0 {
+		return "set constraints all " + string(mode), nil
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("set constraints ")
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(name.Sanitize())
+	}
+	buf.WriteString(" ")
+	buf.WriteString(string(mode))
+	return buf.String(), nil
+}
+
+// DeferredConstraintError occurs when Commit fails because a deferred constraint is violated. The violation was caused
+// by a statement executed earlier in the transaction rather than by the commit. ConstraintName identifies the
+// constraint so the statement can be found.
+type DeferredConstraintError struct {
+	ConstraintName string
+	TableName      string
+	Err            *pgconn.PgError
+}
+
+func (e *DeferredConstraintError) Error() string {
+	return fmt.Sprintf("commit failed: deferred constraint %q on table %q violated: %v", e.ConstraintName, e.TableName, e.Err)
+}
+
+func (e *DeferredConstraintError) Unwrap() error {
+	return e.Err
+}
+
 // TxOptions are transaction modes within a transaction block
 type TxOptions struct {
 	IsoLevel       TxIsoLevel
@@ -105,7 +158,15 @@ func (c *Conn) BeginFunc(ctx context.Context, f func(Tx) error) (err error) {
 // an error the transaction is committed. If f returns an error the transaction is rolled back. The context will be
 // used when executing the transaction control statements (BEGIN, ROLLBACK, and COMMIT) but does not otherwise affect
 // the execution of f.
+//
+// If c is already in a transaction, f is run in a pseudo nested transaction implemented with a savepoint instead of
+// issuing another BEGIN. In that case txOptions is ignored as the transaction mode cannot be changed mid-transaction.
 func (c *Conn) BeginTxFunc(ctx context.Context, txOptions TxOptions, f func(Tx) error) (err error) {
+	if c.pgConn.TxStatus() != 'I' {
+		outer := &dbTx{conn: c}
+		return outer.BeginFunc(ctx, f)
+	}
+
 	var tx Tx
 	tx, err = c.BeginTx(ctx, txOptions)
 	if err != nil {
@@ -140,6 +201,11 @@ type Tx interface {
 
 	// BeginFunc starts a pseudo nested transaction and executes f. If f does not return an err the pseudo nested
 	// transaction will be committed. If it does then it will be rolled back.
+	//
+	// The pseudo nested transaction is a savepoint so only the work of f is rolled back. This includes a statement in f
+	// that fails on the server, which would otherwise abort the whole transaction. A failure that is expected, such as a
+	// unique violation when probing for an existing row, can be handled by checking the error BeginFunc returns and
+	// continuing the transaction.
 	BeginFunc(ctx context.Context, f func(Tx) error) (err error)
 
 	// Commit commits the transaction if this is a real transaction or releases the savepoint if this is a pseudo nested
@@ -160,6 +226,12 @@ type Tx interface {
 
 	Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error)
 
+	// SetConstraints sets when the deferrable constraints names, or all deferrable constraints if names is empty, are
+	// checked for the rest of the transaction. Setting constraints to ConstraintsImmediate checks the changes made so
+	// far while they were deferred. A violation of a constraint that is still deferred at commit is reported by Commit
+	// as a *DeferredConstraintError.
+	SetConstraints(ctx context.Context, mode ConstraintMode, names ...Identifier) error
+
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (commandTag pgconn.CommandTag, err error)
 	Query(ctx context.Context, sql string, args ...interface{}) (Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) Row
@@ -233,6 +305,10 @@ func (tx *dbTx) Commit(ctx context.Context) error {
 		if tx.conn.PgConn().TxStatus() != 'I' {
 			_ = tx.conn.Close(ctx) // already have error to return
 		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "23") {
+			return &DeferredConstraintError{ConstraintName: pgErr.ConstraintName, TableName: pgErr.TableName, Err: pgErr}
+		}
 		return err
 	}
 	if string(commandTag) == "ROLLBACK" {
@@ -262,6 +338,20 @@ func (tx *dbTx) Rollback(ctx context.Context) error {
 	return nil
 }
 
+// SetConstraints sets when deferrable constraints are checked for the rest of the transaction.
+func (tx *dbTx) SetConstraints(ctx context.Context, mode ConstraintMode, names ...Identifier) error {
+	if tx.closed {
+		return ErrTxClosed
+	}
+
+	sql, err := setConstraintsSQL(mode, names)
+	if err != nil {
+		return err
+	}
+	_, err = tx.conn.Exec(ctx, sql)
+	return err
+}
+
 // Exec delegates to the underlying *Conn
 func (tx *dbTx) Exec(ctx context.Context, sql string, arguments ...interface{}) (commandTag pgconn.CommandTag, err error) {
 	return tx.conn.Exec(ctx, sql, arguments...)
@@ -377,6 +467,15 @@ func (sp *dbSavepoint) Rollback(ctx context.Context) error {
 	return err
 }
 
+// SetConstraints delegates to the underlying Tx
+func (sp *dbSavepoint) SetConstraints(ctx context.Context, mode ConstraintMode, names ...Identifier) error {
+	if sp.closed {
+		return ErrTxClosed
+	}
+
+	return sp.tx.SetConstraints(ctx, mode, names...)
+}
+
 // Exec delegates to the underlying Tx
 func (sp *dbSavepoint) Exec(ctx context.Context, sql string, arguments ...interface{}) (commandTag pgconn.CommandTag, err error) {
 	if sp.closed {
@@ -324,6 +324,104 @@ func TestBeginFunc(t *testing.T) {
 	require.EqualValues(t, 1, n)
 }
 
+func TestRetryTxFunc(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	_, err := conn.Exec(context.Background(), "create temporary table foo(id integer)")
+	require.NoError(t, err)
+
+	calls := 0
+	err = conn.RetryTxFunc(context.Background(), pgx.TxOptions{}, func(tx pgx.Tx) error {
+		calls++
+		_, err := tx.Exec(context.Background(), "insert into foo(id) values ($1)", calls)
+		require.NoError(t, err)
+		if calls == 1 {
+			return &pgconn.PgError{Code: "40001", Message: "restart transaction"}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+
+	var ids []int32
+	rows, err := conn.Query(context.Background(), "select id from foo")
+	require.NoError(t, err)
+	for rows.Next() {
+		var id int32
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []int32{2}, ids)
+
+	err = conn.RetryTxFunc(context.Background(), pgx.TxOptions{}, func(tx pgx.Tx) error {
+		return errors.New("not retried")
+	})
+	require.EqualError(t, err, "not retried")
+}
+
+func TestRetryTxFuncMaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.TxRetryMaxAttempts = 3
+	config.TxRetryBackoff = time.Millisecond
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	calls := 0
+	err := conn.RetryTxFunc(context.Background(), pgx.TxOptions{}, func(tx pgx.Tx) error {
+		calls++
+		return &pgconn.PgError{Code: "40001", Message: "restart transaction"}
+	})
+	require.True(t, pgx.IsSerializationFailure(err))
+	require.Equal(t, 3, calls)
+
+	ensureConnValid(t, conn)
+}
+
+func TestSnapshotExportImport(t *testing.T) {
+	t.Parallel()
+
+	conn1 := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn1)
+	conn2 := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn2)
+	conn3 := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn3)
+
+	skipCockroachDB(t, conn1, "Server does not support pg_export_snapshot")
+
+	mustExec(t, conn1, "create table if not exists snapshot_test (id int8)")
+	defer mustExec(t, conn1, "drop table snapshot_test")
+
+	tx1, snapshot, err := conn1.BeginExportSnapshot(context.Background(), pgx.TxOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, snapshot)
+	defer tx1.Rollback(context.Background())
+
+	mustExec(t, conn3, "insert into snapshot_test values (1)")
+
+	tx2, err := conn2.BeginTxWithSnapshot(context.Background(), pgx.TxOptions{AccessMode: pgx.ReadOnly}, snapshot)
+	require.NoError(t, err)
+	defer tx2.Rollback(context.Background())
+
+	var n int64
+	err = tx2.QueryRow(context.Background(), "select count(*) from snapshot_test").Scan(&n)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, n)
+
+	_, err = conn3.BeginTxWithSnapshot(context.Background(), pgx.TxOptions{}, "invalid")
+	require.Error(t, err)
+	require.EqualValues(t, 'I', conn3.PgConn().TxStatus())
+
+	require.NoError(t, tx1.Rollback(context.Background()))
+	require.NoError(t, tx2.Rollback(context.Background()))
+}
+
 func TestBeginFuncRollbackOnError(t *testing.T) {
 	t.Parallel()
 
@@ -587,6 +685,52 @@ func TestTxBeginFuncNestedTransactionRollback(t *testing.T) {
 	require.EqualValues(t, 2, n)
 }
 
+func TestConnBeginFuncInsideTransactionUsesSavepoint(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	createSql := `
+    create temporary table foo(
+      id integer,
+      unique (id)
+    );
+  `
+
+	_, err := conn.Exec(context.Background(), createSql)
+	require.NoError(t, err)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+
+	_, err = tx.Exec(context.Background(), "insert into foo(id) values (1)")
+	require.NoError(t, err)
+
+	err = conn.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		_, err := tx.Exec(context.Background(), "insert into foo(id) values (2)")
+		require.NoError(t, err)
+		return errors.New("do a rollback")
+	})
+	require.EqualError(t, err, "do a rollback")
+	require.EqualValues(t, 'T', conn.PgConn().TxStatus())
+
+	err = conn.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		_, err := tx.Exec(context.Background(), "insert into foo(id) values (3)")
+		return err
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 'T', conn.PgConn().TxStatus())
+
+	err = tx.Rollback(context.Background())
+	require.NoError(t, err)
+
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&n)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, n)
+}
+
 func TestTxSendBatchClosed(t *testing.T) {
 	t.Parallel()
 
@@ -619,3 +763,74 @@ func TestTxSendBatchClosed(t *testing.T) {
 	_, err = br.Query()
 	require.Error(t, err)
 }
+
+func TestTxSetConstraints(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+	skipCockroachDB(t, conn, "Server does not support deferrable constraints")
+
+	mustExec(t, conn, `create temporary table parent(id int primary key)`)
+	mustExec(t, conn, `create temporary table child(parent_id int constraint child_parent_fk references parent deferrable initially immediate)`)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, tx.SetConstraints(context.Background(), pgx.ConstraintsDeferred, pgx.Identifier{"child_parent_fk"}))
+	_, err = tx.Exec(context.Background(), "insert into child(parent_id) values (1)")
+	require.NoError(t, err)
+	_, err = tx.Exec(context.Background(), "insert into parent(id) values (1)")
+	require.NoError(t, err)
+	require.NoError(t, tx.SetConstraints(context.Background(), pgx.ConstraintsImmediate))
+	require.NoError(t, tx.Commit(context.Background()))
+
+	tx, err = conn.Begin(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, tx.SetConstraints(context.Background(), pgx.ConstraintsDeferred))
+	_, err = tx.Exec(context.Background(), "insert into child(parent_id) values (2)")
+	require.NoError(t, err)
+	err = tx.Commit(context.Background())
+	var constraintErr *pgx.DeferredConstraintError
+	require.True(t, errors.As(err, &constraintErr), err)
+	require.Equal(t, "child_parent_fk", constraintErr.ConstraintName)
+	require.Equal(t, "child", constraintErr.TableName)
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr))
+	require.Equal(t, "23503", pgErr.Code)
+
+	tx, err = conn.Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback(context.Background())
+	require.Error(t, tx.SetConstraints(context.Background(), pgx.ConstraintMode("later")))
+}
+
+func TestTxBeginFuncNestedTransactionToleratesServerError(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(id integer unique)`)
+
+	err := conn.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		_, err := tx.Exec(context.Background(), "insert into foo(id) values (1)")
+		require.NoError(t, err)
+
+		err = tx.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+			_, err := tx.Exec(context.Background(), "insert into foo(id) values (1)")
+			return err
+		})
+		var pgErr *pgconn.PgError
+		require.True(t, errors.As(err, &pgErr), err)
+		require.Equal(t, "23505", pgErr.Code)
+
+		_, err = tx.Exec(context.Background(), "insert into foo(id) values (2)")
+		return err
+	})
+	require.NoError(t, err)
+
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&n)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+}
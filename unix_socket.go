@@ -0,0 +1,88 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgconn"
+)
+
+// isSocketHost reports whether host is the directory of a unix domain socket or, if it begins with @, a socket in the
+// abstract namespace of Linux.
+func isSocketHost(host string) bool {
+	return strings.HasPrefix(host, "/") || strings.HasPrefix(host, "@")
+}
+
+// socketAddress returns the network and address to dial for host and port like pgconn.NetworkAddress. socketName, if
+// not empty, replaces the .s.PGSQL.<port> file name of unix domain sockets.
+func socketAddress(host string, port uint16, socketName string) (network, address string) {
+	if !isSocketHost(host) {
+		return pgconn.NetworkAddress(host, port)
+	}
+	if socketName == "" {
+		socketName = ".s.PGSQL." + strconv.FormatUint(uint64(port), 10)
+	}
+	return "unix", filepath.Join(host, socketName)
+}
+
+// disableSocketTLS removes TLS from the abstract namespace socket hosts of config as TLS is not used on unix domain
+// sockets. pgconn.ParseConfig already does this for socket directories. Fallbacks that only differed by TLS are
+// removed.
+func disableSocketTLS(config *pgconn.Config) {
+	if strings.HasPrefix(config.Host, "@") {
+		config.TLSConfig = nil
+	}
+
+	fallbacks := config.Fallbacks[:0]
+	prevHost, prevPort, prevSocket := config.Host, config.Port, strings.HasPrefix(config.Host, "@")
+	for _, fb := range config.Fallbacks {
+		socket := strings.HasPrefix(fb.Host, "@")
+		if socket {
+			fb.TLSConfig = nil
+			if prevSocket && fb.Host == prevHost && fb.Port == prevPort {
+				continue
+			}
+		}
+		fallbacks = append(fallbacks, fb)
+		prevHost, prevPort, prevSocket = fb.Host, fb.Port, socket
+	}
+	config.Fallbacks = fallbacks
+}
+
+// requirePeerDial wraps dial so unix domain socket connections fail unless the server process runs as the operating
+// system user requirePeer.
+func requirePeerDial(dial pgconn.DialFunc, requirePeer string) pgconn.DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil || network != "unix" {
+			return conn, err
+		}
+
+		if err := verifyPeer(conn, requirePeer); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+func verifyPeer(conn net.Conn, requirePeer string) error {
+	uid, err := peerUID(conn)
+	if err != nil {
+		return fmt.Errorf("could not get peer credentials: %w", err)
+	}
+
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return fmt.Errorf("could not look up local user ID %d: %w", uid, err)
+	}
+	if u.Username != requirePeer {
+		return fmt.Errorf("requirepeer specifies %q, but actual peer user name is %q", requirePeer, u.Username)
+	}
+	return nil
+}
@@ -1,10 +1,14 @@
 package pgx
 
 import (
+	"bytes"
+	"database/sql"
 	"database/sql/driver"
+	"encoding/hex"
 	"fmt"
 	"math"
 	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgio"
@@ -138,10 +142,17 @@ func convertSimpleArgument(ci *pgtype.ConnInfo, arg interface{}) (interface{}, e
 	if strippedArg, ok := stripNamedType(&refVal); ok {
 		return convertSimpleArgument(ci, strippedArg)
 	}
+
+	if refVal.Kind() == reflect.Slice || refVal.Kind() == reflect.Array {
+		return encodeSimpleArray(ci, refVal)
+	}
+
 	return nil, SerializationError(fmt.Sprintf("Cannot encode %T in simple protocol - %T must implement driver.Valuer, pgtype.TextEncoder, or be a native type", arg, arg))
 }
 
-func encodePreparedStatementArgument(ci *pgtype.ConnInfo, buf []byte, oid uint32, arg interface{}) ([]byte, error) {
+// encodePreparedStatementArgument appends arg encoded in the binary format of oid to buf. strictTime is
+// ConnConfig.StrictTimePrecision.
+func encodePreparedStatementArgument(ci *pgtype.ConnInfo, buf []byte, oid uint32, arg interface{}, strictTime bool) ([]byte, error) {
 	if arg == nil {
 		return pgio.AppendInt32(buf, -1), nil
 	}
@@ -175,6 +186,17 @@ func encodePreparedStatementArgument(ci *pgtype.ConnInfo, buf []byte, oid uint32
 		buf = pgio.AppendInt32(buf, int32(len(arg)))
 		buf = append(buf, arg...)
 		return buf, nil
+	case time.Time:
+		if isTimeParamOID(oid) {
+			sp := len(buf)
+			buf = pgio.AppendInt32(buf, -1)
+			buf, err := encodeTimeParam(buf, oid, arg, strictTime)
+			if err != nil {
+				return nil, err
+			}
+			pgio.SetInt32(buf[sp:], int32(len(buf[sp:])-4))
+			return buf, nil
+		}
 	}
 
 	refVal := reflect.ValueOf(arg)
@@ -184,7 +206,7 @@ func encodePreparedStatementArgument(ci *pgtype.ConnInfo, buf []byte, oid uint32
 			return pgio.AppendInt32(buf, -1), nil
 		}
 		arg = refVal.Elem().Interface()
-		return encodePreparedStatementArgument(ci, buf, oid, arg)
+		return encodePreparedStatementArgument(ci, buf, oid, arg, strictTime)
 	}
 
 	if dt, ok := ci.DataTypeForOID(oid); ok {
@@ -197,7 +219,7 @@ func encodePreparedStatementArgument(ci *pgtype.ConnInfo, buf []byte, oid uint32
 					if err != nil {
 						return nil, err
 					}
-					return encodePreparedStatementArgument(ci, buf, oid, v)
+					return encodePreparedStatementArgument(ci, buf, oid, v, strictTime)
 				}
 			}
 
@@ -218,11 +240,194 @@ func encodePreparedStatementArgument(ci *pgtype.ConnInfo, buf []byte, oid uint32
 	}
 
 	if strippedArg, ok := stripNamedType(&refVal); ok {
-		return encodePreparedStatementArgument(ci, buf, oid, strippedArg)
+		return encodePreparedStatementArgument(ci, buf, oid, strippedArg, strictTime)
 	}
 	return nil, SerializationError(fmt.Sprintf("Cannot encode %T into oid %v - %T must implement Encoder or be converted to a string", arg, oid, arg))
 }
 
+// timetzOID is the OID of time with time zone. pgtype does not define it.
+const timetzOID = 1266
+
+const (
+	microsecondsPerSecond = 1000000
+
+	// secondsFrom1970To2000 is the offset between the Unix epoch and the PostgreSQL epoch of 2000-01-01.
+	secondsFrom1970To2000 = 946684800
+)
+
+// isTimeParamOID reports whether a time.Time parameter of type oid is encoded by encodeTimeParam.
+func isTimeParamOID(oid uint32) bool {
+	switch oid {
+	case pgtype.DateOID, pgtype.TimestampOID, pgtype.TimestamptzOID, pgtype.TimeOID, timetzOID:
+		return true
+	}
+	return false
+}
+
+// encodeTimeParam appends t in the binary format of the date, timestamp, timestamptz, time or timetz type oid to buf. The
+// part of t that the type cannot hold is truncated as pgtype and the server do: the time of day for date and anything
+// finer than a microsecond for the others. If strict is set an error is returned instead. The wall clock of t is used
+// for the types without a time zone.
+func encodeTimeParam(buf []byte, oid uint32, t time.Time, strict bool) ([]byte, error) {
+	if oid != pgtype.DateOID && t.Nanosecond()%1000 != 0 {
+		if strict {
+			return nil, fmt.Errorf("cannot encode %v into oid %d without losing sub-microsecond precision", t, oid)
+		}
+		t = t.Truncate(time.Microsecond)
+	}
+
+	// wallClock is the wall clock of t as if it were in UTC.
+	wallClock := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+
+	switch oid {
+	case pgtype.DateOID:
+		if strict && (t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0 || t.Nanosecond() != 0) {
+			return nil, fmt.Errorf("cannot encode %v into date without losing the time of day", t)
+		}
+		// The time of day is dropped before dividing so days before 2000 are not rounded toward it.
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		days := (midnight.Unix() - secondsFrom1970To2000) / 86400
+		if days < math.MinInt32 || days > math.MaxInt32 {
+			return nil, fmt.Errorf("%v is out of range for date", t)
+		}
+		return pgio.AppendInt32(buf, int32(days)), nil
+	case pgtype.TimestampOID:
+		return pgio.AppendInt64(buf, microsecondsSince2000(wallClock)), nil
+	case pgtype.TimestamptzOID:
+		return pgio.AppendInt64(buf, microsecondsSince2000(t)), nil
+	case pgtype.TimeOID:
+		return pgio.AppendInt64(buf, microsecondsOfDay(t)), nil
+	case timetzOID:
+		_, offset := t.Zone()
+		buf = pgio.AppendInt64(buf, microsecondsOfDay(t))
+		// PostgreSQL stores the offset in seconds west of UTC.
+		return pgio.AppendInt32(buf, int32(-offset)), nil
+	}
+
+	return nil, fmt.Errorf("cannot encode %v into oid %d", t, oid)
+}
+
+// checkTimePrecision returns an error if arg is a time.Time with precision finer than a microsecond. It is used for the
+// simple protocol where the parameter type is not known.
+func checkTimePrecision(arg interface{}) error {
+	var t time.Time
+	switch arg := arg.(type) {
+	case time.Time:
+		t = arg
+	case *time.Time:
+		if arg == nil {
+			return nil
+		}
+		t = *arg
+	default:
+		return nil
+	}
+
+	if t.Nanosecond()%1000 != 0 {
+		return fmt.Errorf("cannot encode %v without losing sub-microsecond precision", t)
+	}
+	return nil
+}
+
+func microsecondsSince2000(t time.Time) int64 {
+	return (t.Unix()-secondsFrom1970To2000)*microsecondsPerSecond + int64(t.Nanosecond())/1000
+}
+
+func microsecondsOfDay(t time.Time) int64 {
+	return int64(t.Hour()*3600+t.Minute()*60+t.Second())*microsecondsPerSecond + int64(t.Nanosecond())/1000
+}
+
+// encodeSimpleArray encodes a slice or array that has no matching data type as a PostgreSQL array literal. Each element
+// is converted as if it were an argument. Nested slices and arrays become multidimensional arrays. The server casts the
+// literal to the array type of the parameter.
+func encodeSimpleArray(ci *pgtype.ConnInfo, refVal reflect.Value) (interface{}, error) {
+	if refVal.Kind() == reflect.Slice && refVal.IsNil() {
+		return nil, nil
+	}
+
+	buf := &bytes.Buffer{}
+	err := appendSimpleArray(ci, buf, refVal)
+	if err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
+
+func appendSimpleArray(ci *pgtype.ConnInfo, buf *bytes.Buffer, refVal reflect.Value) error {
+	buf.WriteByte('{')
+	for i := 0; i < refVal.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		elem := refVal.Index(i)
+		for elem.Kind() == reflect.Interface && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+
+		isUUID := elem.Kind() == reflect.Array && elem.Len() == 16 && elem.Type().Elem().Kind() == reflect.Uint8
+		isBytes := elem.Kind() == reflect.Slice && elem.Type().Elem().Kind() == reflect.Uint8
+		if (elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array) && !isUUID && !isBytes {
+			err := appendSimpleArray(ci, buf, elem)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var v interface{}
+		if isUUID {
+			// [16]byte is the conventional representation of a uuid.
+			uuid := &pgtype.UUID{Status: pgtype.Present}
+			reflect.Copy(reflect.ValueOf(uuid.Bytes[:]), elem)
+			text, err := uuid.EncodeText(ci, nil)
+			if err != nil {
+				return err
+			}
+			v = string(text)
+		} else {
+			var err error
+			v, err = convertSimpleArgument(ci, elem.Interface())
+			if err != nil {
+				return err
+			}
+		}
+
+		var s string
+		switch v := v.(type) {
+		case nil:
+			buf.WriteString("NULL")
+			continue
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			s = strconv.FormatBool(v)
+		case []byte:
+			s = `\x` + hex.EncodeToString(v)
+		case string:
+			s = v
+		case time.Time:
+			s = v.Truncate(time.Microsecond).Format("2006-01-02 15:04:05.999999999Z07:00:00")
+		default:
+			return SerializationError(fmt.Sprintf("Cannot encode %T in array in simple protocol", v))
+		}
+
+		buf.WriteByte('"')
+		for j := 0; j < len(s); j++ {
+			if s[j] == '"' || s[j] == '\\' {
+				buf.WriteByte('\\')
+			}
+			buf.WriteByte(s[j])
+		}
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('}')
+
+	return nil
+}
+
 // chooseParameterFormatCode determines the correct format code for an
 // argument to a prepared statement. It defaults to TextFormatCode if no
 // determination can be made.
@@ -234,6 +439,10 @@ func chooseParameterFormatCode(ci *pgtype.ConnInfo, oid uint32, arg interface{})
 		return BinaryFormatCode
 	case string, *string, pgtype.TextEncoder:
 		return TextFormatCode
+	case time.Time, *time.Time:
+		if isTimeParamOID(oid) {
+			return BinaryFormatCode
+		}
 	}
 
 	return ci.ParamFormatCodeForOID(oid)
@@ -271,6 +480,15 @@ func stripNamedType(val *reflect.Value) (interface{}, bool) {
 	case reflect.Uint64:
 		convVal := uint64(val.Uint())
 		return convVal, reflect.TypeOf(convVal) != val.Type()
+	case reflect.Float32:
+		convVal := float32(val.Float())
+		return convVal, reflect.TypeOf(convVal) != val.Type()
+	case reflect.Float64:
+		convVal := val.Float()
+		return convVal, reflect.TypeOf(convVal) != val.Type()
+	case reflect.Bool:
+		convVal := val.Bool()
+		return convVal, reflect.TypeOf(convVal) != val.Type()
 	case reflect.String:
 		convVal := val.String()
 		return convVal, reflect.TypeOf(convVal) != val.Type()
@@ -278,3 +496,69 @@ func stripNamedType(val *reflect.Value) (interface{}, bool) {
 
 	return nil, false
 }
+
+var basicKindTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+	reflect.String:  reflect.TypeOf(""),
+}
+
+// namedTypeScanTarget returns a scan target of the underlying core type when dst is a pointer to a named type such as
+// type UserID int64, or a slice of such types. assign converts the scanned value and stores it in dst. It returns a
+// nil target if dst is not such a type or decodes itself.
+func namedTypeScanTarget(dst interface{}) (target interface{}, assign func()) {
+	switch dst.(type) {
+	case *string, *int16, *int32, *int64, *int, *float32, *float64, *bool, *[]byte, *time.Time, *interface{}:
+		return nil, nil
+	case sql.Scanner, pgtype.TextDecoder, pgtype.BinaryDecoder:
+		return nil, nil
+	}
+
+	dstVal := reflect.ValueOf(dst)
+	if dstVal.Kind() != reflect.Ptr || dstVal.IsNil() {
+		return nil, nil
+	}
+	elem := dstVal.Elem()
+	elemType := elem.Type()
+
+	if baseType, ok := basicKindTypes[elemType.Kind()]; ok {
+		if elemType == baseType {
+			return nil, nil
+		}
+		targetVal := reflect.New(baseType)
+		return targetVal.Interface(), func() { elem.Set(targetVal.Elem().Convert(elemType)) }
+	}
+
+	if elemType.Kind() == reflect.Slice {
+		baseType, ok := basicKindTypes[elemType.Elem().Kind()]
+		if !ok || elemType.Elem() == baseType {
+			return nil, nil
+		}
+		targetVal := reflect.New(reflect.SliceOf(baseType))
+		return targetVal.Interface(), func() {
+			src := targetVal.Elem()
+			if src.IsNil() {
+				elem.Set(reflect.Zero(elemType))
+				return
+			}
+			slice := reflect.MakeSlice(elemType, src.Len(), src.Len())
+			for i := 0; i < src.Len(); i++ {
+				slice.Index(i).Set(src.Index(i).Convert(elemType.Elem()))
+			}
+			elem.Set(slice)
+		}
+	}
+
+	return nil, nil
+}
@@ -3,6 +3,7 @@ package pgx_test
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"net"
 	"os"
 	"reflect"
@@ -74,6 +75,139 @@ func TestTimestampTzTranscode(t *testing.T) {
 
 // TODO - move these tests to pgtype
 
+func TestTimeParamEncoding(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	loc := time.FixedZone("", -(5*3600 + 30*60))
+	ts := time.Date(2021, 3, 4, 5, 6, 7, 890123000, loc)
+
+	tests := []struct {
+		sql      string
+		arg      time.Time
+		expected string
+	}{
+		{"select $1::date::text", time.Date(2021, 3, 4, 0, 0, 0, 0, loc), "2021-03-04"},
+		{"select $1::timestamp::text", ts, "2021-03-04 05:06:07.890123"},
+		{"select ($1::timestamptz at time zone 'UTC')::text", ts, "2021-03-04 10:36:07.890123"},
+		{"select $1::time::text", ts, "05:06:07.890123"},
+		{"select $1::timetz::text", ts, "05:06:07.890123-05:30"},
+	}
+
+	for i, tt := range tests {
+		var s string
+		err := conn.QueryRow(context.Background(), tt.sql, tt.arg).Scan(&s)
+		if assert.NoErrorf(t, err, "%d. %s", i, tt.sql) {
+			assert.Equalf(t, tt.expected, s, "%d. %s", i, tt.sql)
+		}
+
+		err = conn.QueryRow(context.Background(), tt.sql, &tt.arg).Scan(&s)
+		if assert.NoErrorf(t, err, "%d. %s", i, tt.sql) {
+			assert.Equalf(t, tt.expected, s, "%d. %s", i, tt.sql)
+		}
+	}
+
+	// By default precision the type cannot hold is truncated.
+	now := time.Now()
+	var tz time.Time
+	err := conn.QueryRow(context.Background(), "select $1::timestamptz", now).Scan(&tz)
+	require.NoError(t, err)
+	assert.True(t, now.Truncate(time.Microsecond).Equal(tz))
+
+	var date string
+	err = conn.QueryRow(context.Background(), "select $1::date::text", ts.Add(time.Nanosecond)).Scan(&date)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-03-04", date)
+
+	before2000 := time.Date(1999, 12, 31, 12, 0, 0, 0, time.UTC)
+	err = conn.QueryRow(context.Background(), "select $1::date::text", before2000).Scan(&date)
+	require.NoError(t, err)
+	assert.Equal(t, "1999-12-31", date)
+
+	ensureConnValid(t, conn)
+}
+
+func TestTimeParamEncodingStrictTimePrecision(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE")+" strict_time_precision=true")
+	require.True(t, config.StrictTimePrecision)
+	require.Empty(t, config.RuntimeParams["strict_time_precision"])
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	loc := time.FixedZone("", -(5*3600 + 30*60))
+	ts := time.Date(2021, 3, 4, 5, 6, 7, 890123000, loc)
+
+	for _, sql := range []string{"select $1::timestamp", "select $1::timestamptz", "select $1::time", "select $1::timetz"} {
+		_, err := conn.Exec(context.Background(), sql, ts.Add(time.Nanosecond))
+		assert.Errorf(t, err, "%s did not reject sub-microsecond precision", sql)
+
+		_, err = conn.Exec(context.Background(), sql, pgx.QuerySimpleProtocol(true), ts.Add(time.Nanosecond))
+		assert.Errorf(t, err, "%s did not reject sub-microsecond precision with the simple protocol", sql)
+
+		_, err = conn.Exec(context.Background(), sql, ts)
+		assert.NoErrorf(t, err, "%s", sql)
+	}
+
+	_, err := conn.Exec(context.Background(), "select $1::date", ts)
+	assert.Error(t, err, "date did not reject a time of day")
+
+	ensureConnValid(t, conn)
+}
+
+func TestScanIntegerIntoAnyIntegerType(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		for _, typ := range []string{"int2", "int4", "int8"} {
+			var (
+				i   int
+				i8  int8
+				i16 int16
+				i32 int32
+				i64 int64
+				u   uint
+				u8  uint8
+				u16 uint16
+				u32 uint32
+				u64 uint64
+			)
+			sql := fmt.Sprintf("select 42::%[1]s, 42::%[1]s, 42::%[1]s, 42::%[1]s, 42::%[1]s, 42::%[1]s, 42::%[1]s, 42::%[1]s, 42::%[1]s, 42::%[1]s", typ)
+			err := conn.QueryRow(context.Background(), sql).Scan(&i, &i8, &i16, &i32, &i64, &u, &u8, &u16, &u32, &u64)
+			require.NoErrorf(t, err, "%s", typ)
+			for _, v := range []interface{}{i, i8, i16, i32, i64, u, u8, u16, u32, u64} {
+				assert.EqualValuesf(t, 42, v, "%s into %T", typ, v)
+			}
+		}
+
+		tests := []struct {
+			sql string
+			dst interface{}
+		}{
+			{"select 128::int2", new(int8)},
+			{"select (-129)::int2", new(int8)},
+			{"select 32768::int4", new(int16)},
+			{"select 2147483648::int8", new(int32)},
+			{"select (-1)::int2", new(uint)},
+			{"select (-1)::int8", new(uint64)},
+			{"select 256::int4", new(uint8)},
+			{"select 65536::int8", new(uint16)},
+			{"select 4294967296::int8", new(uint32)},
+		}
+
+		for _, tt := range tests {
+			err := conn.QueryRow(context.Background(), tt.sql).Scan(tt.dst)
+			var scanErr pgx.ScanArgError
+			if assert.ErrorAsf(t, err, &scanErr, "%s into %T", tt.sql, tt.dst) {
+				assert.Equal(t, 0, scanErr.ColumnIndex)
+			}
+		}
+	})
+}
+
 func TestJSONAndJSONBTranscode(t *testing.T) {
 	t.Parallel()
 
@@ -738,6 +872,40 @@ func TestEmptyArrayDecoding(t *testing.T) {
 	})
 }
 
+func TestArrayParameterEncoding(t *testing.T) {
+	t.Parallel()
+
+	type userID int32
+	type status string
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		one, two := int32(1), int32(2)
+
+		tests := []struct {
+			sql      string
+			arg      interface{}
+			expected string
+		}{
+			{"select $1::int4[]::text", []int32{1, 2, 3}, "{1,2,3}"},
+			{"select $1::int4[]::text", []userID{1, 2, 3}, "{1,2,3}"},
+			{"select $1::int4[]::text", []*int32{&one, nil, &two}, "{1,NULL,2}"},
+			{"select $1::int4[]::text", [][]int32{{1, 2}, {3, 4}}, "{{1,2},{3,4}}"},
+			{"select $1::text[]::text", []string{"a", `b "quoted" \ value`, "NULL", ""}, `{a,"b \"quoted\" \\ value","NULL",""}`},
+			{"select $1::text[]::text", []status{"active", "disabled"}, "{active,disabled}"},
+			{"select $1::uuid[]::text", [][16]byte{{1: 1}, {15: 2}}, "{00010000-0000-0000-0000-000000000000,00000000-0000-0000-0000-000000000002}"},
+			{"select $1::uuid[]::text", []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, "{6ba7b810-9dad-11d1-80b4-00c04fd430c8}"},
+		}
+
+		for i, tt := range tests {
+			var actual string
+			err := conn.QueryRow(context.Background(), tt.sql, tt.arg).Scan(&actual)
+			if assert.NoErrorf(t, err, "%d. %T", i, tt.arg) {
+				assert.Equalf(t, tt.expected, actual, "%d. %T", i, tt.arg)
+			}
+		}
+	})
+}
+
 func TestPointerPointer(t *testing.T) {
 	t.Parallel()
 
@@ -942,6 +1110,39 @@ func TestEncodeTypeRename(t *testing.T) {
 	})
 }
 
+func TestEncodeTypeRenameFloatBoolAndSlices(t *testing.T) {
+	t.Parallel()
+
+	type score float64
+	type ratio float32
+	type flag bool
+	type userID int64
+	type status string
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		var outScore score
+		var outRatio ratio
+		var outFlag flag
+		var outIDs []userID
+		var outStatuses []status
+
+		err := conn.QueryRow(context.Background(), "select $1::float8, $2::float4, $3::bool, $4::int8[], $5::text[]",
+			score(1.5), ratio(0.25), flag(true), []userID{1, 2}, []status{"active", "disabled"},
+		).Scan(&outScore, &outRatio, &outFlag, &outIDs, &outStatuses)
+		require.NoError(t, err)
+
+		assert.Equal(t, score(1.5), outScore)
+		assert.Equal(t, ratio(0.25), outRatio)
+		assert.Equal(t, flag(true), outFlag)
+		assert.Equal(t, []userID{1, 2}, outIDs)
+		assert.Equal(t, []status{"active", "disabled"}, outStatuses)
+
+		err = conn.QueryRow(context.Background(), "select null::int8[]").Scan(&outIDs)
+		require.NoError(t, err)
+		assert.Nil(t, outIDs)
+	})
+}
+
 func TestRowDecodeBinary(t *testing.T) {
 	t.Parallel()
 